@@ -0,0 +1,395 @@
+package gslang
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+// ValueKind annotation value kind
+type ValueKind int
+
+// annotation value kinds
+const (
+	ValueString ValueKind = iota
+	ValueNumeric
+	ValueBoolean
+	ValueEnum
+	ValueTable
+)
+
+var valueKindNames = map[ValueKind]string{
+	ValueString:  "string",
+	ValueNumeric: "numeric",
+	ValueBoolean: "boolean",
+	ValueEnum:    "enum",
+	ValueTable:   "table",
+}
+
+func (kind ValueKind) String() string {
+	return valueKindNames[kind]
+}
+
+// AnnotationValue evaluated annotation instance, constant references and table instances are resolved
+type AnnotationValue struct {
+	Kind      ValueKind                   // value kind
+	Node      ast.Node                    // source annotation or expr node
+	Type      ast.Type                    // table type of ValueTable, enum type of ValueEnum
+	String    string                      // ValueString value
+	Numeric   float64                     // ValueNumeric value
+	Int       int64                       // ValueNumeric exact value of integer literal
+	Integer   bool                        // ValueNumeric integer literal flag, the exact value is Int
+	Boolean   bool                        // ValueBoolean value
+	Enum      int64                       // ValueEnum value, flag constants are combined
+	Constants []*ast.EnumConstant         // ValueEnum constants
+	Fields    map[string]*AnnotationValue // ValueTable field values indexed by field name, omitted args are absent
+}
+
+// Field get table field value
+func (value *AnnotationValue) Field(name string) (*AnnotationValue, bool) {
+	field, ok := value.Fields[name]
+
+	return field, ok
+}
+
+func annotationErrorf(err error, node ast.Node, fmtstr string, args ...interface{}) *Error {
+
+	var start, end lexer.Position
+
+	if node != nil {
+		start, end = Pos(node)
+	}
+
+	return &Error{
+		Stage:   StageSemParing,
+		Orignal: err,
+		Start:   start,
+		End:     end,
+		Text:    fmt.Sprintf(fmtstr, args...),
+	}
+}
+
+// EvalAnnotation eval linked annotation into value tree, the returned error is *Error with the position
+// of the failed expr
+func EvalAnnotation(annotation *ast.Annotation) (*AnnotationValue, error) {
+
+	table, ok := annotation.Type.Ref.(*ast.Table)
+
+	if !ok {
+		return nil, annotationErrorf(ErrEval, annotation, "annotation(%s) type must be linked table", annotation)
+	}
+
+	value, err := evalTable(annotation, table, annotation.Args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func evalTable(node ast.Node, table *ast.Table, args *ast.ArgsTable) (*AnnotationValue, *Error) {
+
+	value := &AnnotationValue{
+		Kind:   ValueTable,
+		Node:   node,
+		Type:   table,
+		Fields: make(map[string]*AnnotationValue),
+	}
+
+	if args == nil {
+		return value, nil
+	}
+
+	if !args.Named && args.Count() > len(table.Fields) {
+		return nil, annotationErrorf(ErrNewObj, args, "table(%s) expect %d args but got %d", table, len(table.Fields), args.Count())
+	}
+
+	for i, arg := range args.Args() {
+
+		var name string
+
+		if !args.Named {
+			name = table.Fields[i].Name()
+		} else {
+
+			name = arg.Name()
+
+			if _, ok := table.Field(name); !ok {
+				return nil, annotationErrorf(ErrFieldName, arg, "unknown table(%s) field(%s)", table, name)
+			}
+
+			arg = arg.(*ast.NamedArg).Arg
+		}
+
+		field, err := evalExpr(arg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		value.Fields[name] = field
+	}
+
+	return value, nil
+}
+
+func evalExpr(expr ast.Expr) (*AnnotationValue, *Error) {
+
+	switch expr.(type) {
+	case *ast.String:
+		return &AnnotationValue{Kind: ValueString, Node: expr, String: expr.Name()}, nil
+	case *ast.Numeric:
+		numeric := expr.(*ast.Numeric)
+
+		return &AnnotationValue{Kind: ValueNumeric, Node: expr, Numeric: numeric.Val, Int: numeric.Int, Integer: numeric.Integer}, nil
+	case *ast.Boolean:
+		return &AnnotationValue{Kind: ValueBoolean, Node: expr, Boolean: expr.(*ast.Boolean).Val}, nil
+	case *ast.ConstantRef:
+
+		constant, ok := expr.(*ast.ConstantRef).Value.(*ast.EnumConstant)
+
+		if !ok {
+			return nil, annotationErrorf(ErrEval, expr, "constant(%s) must be linked enum constant", expr)
+		}
+
+		return &AnnotationValue{
+			Kind:      ValueEnum,
+			Node:      expr,
+			Type:      ast.EnclosingType(constant),
+			Enum:      constant.Value,
+			Constants: []*ast.EnumConstant{constant},
+		}, nil
+
+	case *ast.UnaryOp:
+
+		unary := expr.(*ast.UnaryOp)
+
+		operand, err := evalExpr(unary.Operand)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if unary.Token != lexer.OpSub || operand.Kind != ValueNumeric {
+			return nil, annotationErrorf(ErrEval, expr, "unsupport unary op(%s)", unary)
+		}
+
+		return &AnnotationValue{
+			Kind:    ValueNumeric,
+			Node:    expr,
+			Numeric: -operand.Numeric,
+			Int:     -operand.Int,
+			Integer: operand.Integer && operand.Int != math.MinInt64,
+		}, nil
+
+	case *ast.BinaryOp:
+
+		binary := expr.(*ast.BinaryOp)
+
+		lhs, err := evalExpr(binary.LHS)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rhs, err := evalExpr(binary.RHS)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if lhs.Kind != ValueEnum || rhs.Kind != ValueEnum || lhs.Type != rhs.Type {
+			return nil, annotationErrorf(ErrEval, expr, "binary op(%s) operands must be constants of the same enum", binary)
+		}
+
+		value := &AnnotationValue{
+			Kind:      ValueEnum,
+			Node:      expr,
+			Type:      lhs.Type,
+			Constants: append(append([]*ast.EnumConstant{}, lhs.Constants...), rhs.Constants...),
+		}
+
+		switch binary.Token {
+		case lexer.OpBitOr:
+			value.Enum = lhs.Enum | rhs.Enum
+		case lexer.OpBitAnd:
+			value.Enum = lhs.Enum & rhs.Enum
+		default:
+			return nil, annotationErrorf(ErrEval, expr, "unsupport binary op(%s)", binary)
+		}
+
+		return value, nil
+
+	case *ast.NewObj:
+
+		newObj := expr.(*ast.NewObj)
+
+		table, ok := newObj.Type.Ref.(*ast.Table)
+
+		if !ok {
+			return nil, annotationErrorf(ErrEval, expr, "newobj(%s) type must be linked table", newObj)
+		}
+
+		return evalTable(expr, table, newObj.Args)
+	}
+
+	return nil, annotationErrorf(ErrEval, expr, "can't eval expr(%s)", expr)
+}
+
+var annotationValueType = reflect.TypeOf(&AnnotationValue{})
+
+// DecodeAnnotation decode linked annotation into the struct val points to, the annotation table fields are
+// mapped to the struct fields by name or by the `gslang:"name"` tag, tag "-" skips the struct field. Omitted
+// args keep the struct fields unchanged. The returned error is *Error with the position of the failed arg
+func DecodeAnnotation(annotation *ast.Annotation, val interface{}) error {
+
+	target := reflect.ValueOf(val)
+
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return annotationErrorf(ErrDecode, annotation, "decode annotation(%s) target must be struct pointer", annotation)
+	}
+
+	value, err := EvalAnnotation(annotation)
+
+	if err != nil {
+		return err
+	}
+
+	if err := decodeValue(value, target.Elem()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func decodeValue(value *AnnotationValue, target reflect.Value) *Error {
+
+	if target.Type() == annotationValueType {
+		target.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	mismatch := func() *Error {
+		return annotationErrorf(ErrDecode, value.Node, "can't decode %s value(%s) as %s", value.Kind, value.Node, target.Type())
+	}
+
+	switch target.Kind() {
+	case reflect.Ptr:
+
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+
+		return decodeValue(value, target.Elem())
+
+	case reflect.String:
+
+		if value.Kind != ValueString {
+			return mismatch()
+		}
+
+		target.SetString(value.String)
+
+	case reflect.Bool:
+
+		if value.Kind != ValueBoolean {
+			return mismatch()
+		}
+
+		target.SetBool(value.Boolean)
+
+	case reflect.Float32, reflect.Float64:
+
+		if value.Kind != ValueNumeric {
+			return mismatch()
+		}
+
+		target.SetFloat(value.Numeric)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+
+		val, ok := value.integer()
+
+		if !ok || target.OverflowInt(val) {
+			return mismatch()
+		}
+
+		target.SetInt(val)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+
+		val, ok := value.integer()
+
+		if !ok || val < 0 || target.OverflowUint(uint64(val)) {
+			return mismatch()
+		}
+
+		target.SetUint(uint64(val))
+
+	case reflect.Struct:
+
+		if value.Kind != ValueTable {
+			return mismatch()
+		}
+
+		structType := target.Type()
+
+		for i := 0; i < structType.NumField(); i++ {
+
+			field := structType.Field(i)
+
+			if field.PkgPath != "" {
+				continue
+			}
+
+			name := field.Name
+
+			if tag := field.Tag.Get("gslang"); tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+
+			fieldValue, ok := value.Fields[name]
+
+			if !ok {
+				continue
+			}
+
+			if err := decodeValue(fieldValue, target.Field(i)); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return mismatch()
+	}
+
+	return nil
+}
+
+// integer get integer value of numeric or enum value
+func (value *AnnotationValue) integer() (int64, bool) {
+
+	switch value.Kind {
+	case ValueEnum:
+		return value.Enum, true
+	case ValueNumeric:
+		if value.Integer {
+			return value.Int, true
+		}
+
+		// float64(math.MaxInt64) rounds up to 2^63, which overflows int64
+		if value.Numeric != math.Trunc(value.Numeric) || math.Abs(value.Numeric) >= math.MaxInt64 {
+			return 0, false
+		}
+
+		return int64(value.Numeric), true
+	}
+
+	return 0, false
+}
@@ -5,7 +5,9 @@ using gslang.Flag;
 // attribute target flag
 @Flag
 enum Target{
-    Module(1),Script(2),Table(4),Method(8),Param(16),Enum(32)
+    Module(1),Script(2),Table(4),Method(8),Param(16),Enum(32),
+    Field(64),Contract(128),EnumConstant(256),Exception(512),Using(1024),
+    Union(2048),UnionCase(4096)
 }
 
 // attribute Usage attribute
@@ -13,3 +15,11 @@ enum Target{
 table Usage{
     Target Target;
 }
+
+// annotation can be applied more than once to the same node
+@Usage(Target.Table)
+table Repeatable{}
+
+// annotation field must be set by named args
+@Usage(Target.Field)
+table Required{}
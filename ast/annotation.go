@@ -15,5 +15,7 @@ func NewAnnotation(name string) *Annotation {
 
 	annotation._init(name)
 
+	annotation.Type.SetParent(annotation)
+
 	return annotation
 }
@@ -31,6 +31,8 @@ func NewNamedArg(name string, arg Expr) *NamedArg {
 
 	args._init(name)
 
+	setParent(arg, args)
+
 	return args
 }
 
@@ -63,6 +65,8 @@ func (args *ArgsTable) Append(expr Expr) error {
 
 	args.args = append(args.args, expr)
 
+	expr.SetParent(args)
+
 	return nil
 }
 
@@ -109,8 +113,10 @@ func NewString(val string) *String {
 
 // Numeric literal number
 type Numeric struct {
-	_Node // Mixin default node implement
-	Val   float64
+	_Node           // Mixin default node implement
+	Val     float64 // literal value
+	Int     int64   // exact value of integer literal
+	Integer bool    // integer literal flag, the exact value is Int
 }
 
 // NewNumeric .
@@ -124,6 +130,25 @@ func NewNumeric(val float64) *Numeric {
 	return lit
 }
 
+// NewInteger integer literal, the exact value is kept in Int, float64 Val may lose precision
+func NewInteger(val int64) *Numeric {
+	lit := &Numeric{
+		Val:     float64(val),
+		Int:     val,
+		Integer: true,
+	}
+
+	lit._init(fmt.Sprintf("%d", val))
+
+	return lit
+}
+
+// Negate negate the literal value
+func (numeric *Numeric) Negate() {
+	numeric.Val = -numeric.Val
+	numeric.Int = -numeric.Int
+}
+
 // Boolean literal boolean
 type Boolean struct {
 	_Node // Mixin default node implement
@@ -172,6 +197,12 @@ func NewNewObj(name string, args *ArgsTable) *NewObj {
 
 	lit._init(name)
 
+	lit.Type.SetParent(lit)
+
+	if args != nil {
+		args.SetParent(lit)
+	}
+
 	return lit
 }
 
@@ -184,6 +215,12 @@ func NewNewObj2(ref *TypeRef, args *ArgsTable) *NewObj {
 
 	lit._init(ref.Name())
 
+	ref.SetParent(lit)
+
+	if args != nil {
+		args.SetParent(lit)
+	}
+
 	return lit
 }
 
@@ -212,6 +249,8 @@ func NewUnaryOp(token lexer.TokenType, operand Expr) *UnaryOp {
 
 	lit._init(token.String())
 
+	setParent(operand, lit)
+
 	return lit
 }
 
@@ -233,5 +272,9 @@ func NewBinaryOp(token lexer.TokenType, lhs, rhs Expr) *BinaryOp {
 
 	lit._init(token.String())
 
+	setParent(lhs, lit)
+
+	setParent(rhs, lit)
+
 	return lit
 }
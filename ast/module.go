@@ -26,3 +26,15 @@ func (module *Module) Foreach(f func(script *Script) bool) {
 		}
 	}
 }
+
+// Script get script by name
+func (module *Module) Script(name string) (*Script, bool) {
+	script, ok := module.scripts[name]
+
+	return script, ok
+}
+
+// RemoveScript remove script by name
+func (module *Module) RemoveScript(name string) {
+	delete(module.scripts, name)
+}
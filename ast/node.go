@@ -53,3 +53,45 @@ func (node *_Node) GetExtra(key string) (val interface{}, ok bool) {
 
 	return
 }
+
+// copyExtra copy extra data of the source node, e.g. annotations, comment and source position
+func (node *_Node) copyExtra(from *_Node) {
+	for key, val := range from.extra {
+		node.extra[key] = val
+	}
+}
+
+// setParent set parent of the optional child node
+func setParent(node Node, parent Node) {
+	if node != nil {
+		node.SetParent(parent)
+	}
+}
+
+// EnclosingScript get the script which the node belongs to, returns nil if the node isn't in any script
+func EnclosingScript(node Node) *Script {
+
+	for ; node != nil; node = node.Parent() {
+		if script, ok := node.(*Script); ok {
+			return script
+		}
+	}
+
+	return nil
+}
+
+// EnclosingType get the nearest table, enum or contract containing the node, excluding the node itself
+func EnclosingType(node Node) TypeDecl {
+
+	if node == nil {
+		return nil
+	}
+
+	for node = node.Parent(); node != nil; node = node.Parent() {
+		if typeDecl, ok := node.(TypeDecl); ok {
+			return typeDecl
+		}
+	}
+
+	return nil
+}
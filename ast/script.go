@@ -26,6 +26,8 @@ func (module *Module) NewScript(name string) *Script {
 
 	script._init(name)
 
+	script.SetParent(module)
+
 	module.scripts[name] = script
 
 	return script
@@ -51,6 +53,8 @@ func (script *Script) Using(name string) *Using {
 
 	using._init(name)
 
+	using.SetParent(script)
+
 	script.using[name] = using
 
 	return using
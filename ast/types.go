@@ -2,6 +2,7 @@ package ast
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gsrpc/gslang/lexer"
 )
@@ -23,7 +24,8 @@ type TypeDecl interface {
 // TypeRef .
 type TypeRef struct {
 	_Node
-	Ref Type
+	Ref      Type   // referenced type
+	TypeArgs []Type // type args of generic table reference
 }
 
 // NewTypeRef .
@@ -38,10 +40,18 @@ func NewTypeRef(name string) *TypeRef {
 // FullName .
 func (ref *TypeRef) FullName() string {
 	if ref.Ref != nil {
-		return "ref :" + ref.Ref.FullName()
+		return "ref :" + ref.Ref.FullName() + typeArgsName(ref.TypeArgs)
 	}
 
-	return "unlink ref :" + ref.Name()
+	return "unlink ref :" + ref.Name() + typeArgsName(ref.TypeArgs)
+}
+
+// NewTypeArg append type arg of generic table reference
+func (ref *TypeRef) NewTypeArg(typeDecl Type) {
+
+	setParent(typeDecl, ref)
+
+	ref.TypeArgs = append(ref.TypeArgs, typeDecl)
 }
 
 // Package .
@@ -57,7 +67,8 @@ func (ref *TypeRef) Script() string {
 // BuiltinType .
 type BuiltinType struct {
 	_Node
-	Type lexer.TokenType
+	Type  lexer.TokenType
+	Bound int // string max length declared as string<=N, 0 means unbounded
 }
 
 // NewBuiltinType .
@@ -73,6 +84,11 @@ func NewBuiltinType(builtin lexer.TokenType) *BuiltinType {
 
 // FullName .
 func (builtin *BuiltinType) FullName() string {
+
+	if builtin.Bound != 0 {
+		return fmt.Sprintf("%s<=%d", builtin.Name(), builtin.Bound)
+	}
+
 	return builtin.Name()
 }
 
@@ -92,11 +108,35 @@ type Field struct {
 	Type Type // Field Type
 }
 
+// TypeParam generic table type param
+type TypeParam struct {
+	_Node
+}
+
+// FullName .
+func (param *TypeParam) FullName() string {
+	return param.Name()
+}
+
+// Package .
+func (param *TypeParam) Package() string {
+	return ""
+}
+
+// Script .
+func (param *TypeParam) Script() string {
+	return ""
+}
+
 // Table .
 type Table struct {
-	_Node           // Mixin default node implement
-	Fields []*Field // table fields
-	script *Script  // script belongs to
+	_Node                        // Mixin default node implement
+	Fields     []*Field          // table fields
+	TypeParams []*TypeParam      // generic table type params
+	Generic    *Table            // generic table of the instance, nil for declared tables
+	TypeArgs   []Type            // type args bound to the generic table type params of the instance
+	script     *Script           // script belongs to
+	instances  map[string]*Table // generic table instances indexed by name
 }
 
 // NewTable .
@@ -112,6 +152,8 @@ func (script *Script) NewTable(name string) (Type, bool) {
 
 	table._init(name)
 
+	table.SetParent(script)
+
 	script.types[name] = table
 
 	return table, true
@@ -158,16 +200,200 @@ func (table *Table) NewField(name string, typeDecl Type) (*Field, bool) {
 
 	field._init(name)
 
+	field.SetParent(table)
+
+	setParent(typeDecl, field)
+
 	table.Fields = append(table.Fields, field)
 
 	return field, true
 }
 
+// TypeParam .
+func (table *Table) TypeParam(name string) (*TypeParam, bool) {
+	for _, param := range table.TypeParams {
+		if param.Name() == name {
+			return param, true
+		}
+	}
+
+	return nil, false
+}
+
+// NewTypeParam .
+func (table *Table) NewTypeParam(name string) (*TypeParam, bool) {
+	if param, ok := table.TypeParam(name); ok {
+		return param, false
+	}
+
+	param := &TypeParam{}
+
+	param._init(name)
+
+	param.SetParent(table)
+
+	table.TypeParams = append(table.TypeParams, param)
+
+	return param, true
+}
+
+// Instantiate get the concrete table of generic table, the type params in field types are replaced by
+// the type args. Instances are cached by name, e.g. Page<gslang.test.User>, and aren't declared in the script
+func (table *Table) Instantiate(args []Type) *Table {
+
+	name := table.Name() + typeArgsName(args)
+
+	if instance, ok := table.instances[name]; ok {
+		return instance
+	}
+
+	instance := &Table{
+		script:   table.script,
+		Generic:  table,
+		TypeArgs: args,
+	}
+
+	instance._init(name)
+
+	instance.copyExtra(&table._Node)
+
+	instance.SetParent(table.script)
+
+	bindings := make(map[*TypeParam]Type)
+
+	for i, param := range table.TypeParams {
+		if i < len(args) {
+			bindings[param] = args[i]
+		}
+	}
+
+	for _, field := range table.Fields {
+
+		instanceField, _ := instance.NewField(field.Name(), instantiate(field.Type, bindings))
+
+		instanceField.copyExtra(&field._Node)
+	}
+
+	if table.instances == nil {
+		table.instances = make(map[string]*Table)
+	}
+
+	table.instances[name] = instance
+
+	return instance
+}
+
+// instantiate clone type expression with type params replaced by the bound types
+func instantiate(typeDecl Type, bindings map[*TypeParam]Type) Type {
+
+	switch typeDecl.(type) {
+	case *TypeRef:
+		ref := typeDecl.(*TypeRef)
+
+		if param, ok := ref.Ref.(*TypeParam); ok {
+			if bound, ok := bindings[param]; ok {
+				return instantiate(bound, nil)
+			}
+		}
+
+		clone := NewTypeRef(ref.Name())
+
+		clone.copyExtra(&ref._Node)
+
+		clone.Ref = ref.Ref
+
+		for _, arg := range ref.TypeArgs {
+			clone.NewTypeArg(instantiate(arg, bindings))
+		}
+
+		return clone
+
+	case *Seq:
+		seq := typeDecl.(*Seq)
+
+		clone := NewSeq(instantiate(seq.Component, bindings), seq.Size)
+
+		clone.Bound = seq.Bound
+
+		clone.copyExtra(&seq._Node)
+
+		return clone
+
+	case *Optional:
+		optional := typeDecl.(*Optional)
+
+		clone := NewOptional(instantiate(optional.Component, bindings))
+
+		clone.copyExtra(&optional._Node)
+
+		return clone
+
+	case *BuiltinType:
+		builtin := typeDecl.(*BuiltinType)
+
+		clone := NewBuiltinType(builtin.Type)
+
+		clone.Bound = builtin.Bound
+
+		clone.copyExtra(&builtin._Node)
+
+		return clone
+	}
+
+	return typeDecl
+}
+
+// TypeName get type expression name, e.g. gslang.test.Page<string>[<=10]?, references are
+// resolved to full name, so the names of equal type expressions are equal
+func TypeName(typeDecl Type) string {
+
+	switch typeDecl.(type) {
+	case *TypeRef:
+		if ref := typeDecl.(*TypeRef).Ref; ref != nil {
+			return ref.FullName() + typeArgsName(typeDecl.(*TypeRef).TypeArgs)
+		}
+
+		return typeDecl.Name() + typeArgsName(typeDecl.(*TypeRef).TypeArgs)
+	case *Seq:
+		seq := typeDecl.(*Seq)
+
+		if seq.Size > 0 {
+			return fmt.Sprintf("%s[%d]", TypeName(seq.Component), seq.Size)
+		}
+
+		if seq.Bound != 0 {
+			return fmt.Sprintf("%s[<=%d]", TypeName(seq.Component), seq.Bound)
+		}
+
+		return TypeName(seq.Component) + "[]"
+	case *Optional:
+		return TypeName(typeDecl.(*Optional).Component) + "?"
+	}
+
+	return typeDecl.FullName()
+}
+
+func typeArgsName(args []Type) string {
+
+	if len(args) == 0 {
+		return ""
+	}
+
+	var names []string
+
+	for _, arg := range args {
+		names = append(names, TypeName(arg))
+	}
+
+	return "<" + strings.Join(names, ",") + ">"
+}
+
 // Param .
 type Param struct {
 	_Node
-	ID   int
-	Type Type
+	ID     int
+	Type   Type
+	Stream bool // the param values are sent as stream
 }
 
 // Exception .
@@ -177,12 +403,35 @@ type Exception struct {
 	ID   int8
 }
 
+// StreamMode method streaming mode
+type StreamMode int
+
+// streaming modes, StreamBidi combines the client and server streams
+const (
+	StreamClient StreamMode = 1 << iota // the stream param values are sent as stream
+	StreamServer                        // the return values are received as stream
+	StreamBidi   = StreamClient | StreamServer
+)
+
+var streamModeNames = map[StreamMode]string{
+	0:            "none",
+	StreamClient: "client",
+	StreamServer: "server",
+	StreamBidi:   "bidi",
+}
+
+func (mode StreamMode) String() string {
+	return streamModeNames[mode]
+}
+
 // Method .
 type Method struct {
 	_Node
 	ID         int          /// id
-	Return     Type         // return type
+	Stream     StreamMode   // streaming mode
+	Return     Type         // return type, void if the results are declared
 	Params     []*Param     // Params type list
+	Results    []*Param     // named results list
 	Exceptions []*Exception // exception list
 }
 
@@ -203,6 +452,40 @@ func (method *Method) Param(name string) (*Param, bool) {
 	return nil, false
 }
 
+// Result .
+func (method *Method) Result(name string) (*Param, bool) {
+
+	for _, result := range method.Results {
+		if result.Name() == name {
+			return result, true
+		}
+	}
+
+	return nil, false
+}
+
+// NewResult .
+func (method *Method) NewResult(name string, typeDecl Type) (*Param, bool) {
+	if result, ok := method.Result(name); ok {
+		return result, false
+	}
+
+	result := &Param{
+		ID:   len(method.Results),
+		Type: typeDecl,
+	}
+
+	result._init(name)
+
+	result.SetParent(method)
+
+	setParent(typeDecl, result)
+
+	method.Results = append(method.Results, result)
+
+	return result, true
+}
+
 // NewException .
 func (method *Method) NewException(typeDecl Type) *Exception {
 
@@ -213,6 +496,10 @@ func (method *Method) NewException(typeDecl Type) *Exception {
 
 	exception._init(typeDecl.Name())
 
+	exception.SetParent(method)
+
+	setParent(typeDecl, exception)
+
 	method.Exceptions = append(method.Exceptions, exception)
 
 	return exception
@@ -231,6 +518,10 @@ func (method *Method) NewParam(name string, typeDecl Type) (*Param, bool) {
 
 	param._init(name)
 
+	param.SetParent(method)
+
+	setParent(typeDecl, param)
+
 	method.Params = append(method.Params, param)
 
 	return param, true
@@ -239,14 +530,16 @@ func (method *Method) NewParam(name string, typeDecl Type) (*Param, bool) {
 // EnumConstant .
 type EnumConstant struct {
 	_Node       // Mixin default node implement
-	Value int32 // constant value
+	Value int64 // constant value
+	Expr  Expr  // declared value expr evaluated after linking, nil for the previous constant value plus one
 }
 
 // Enum .
 type Enum struct {
-	_Node                     // Mixin default node implement
-	Constants []*EnumConstant // table fields
-	script    *Script
+	_Node                      // Mixin default node implement
+	Underlying Type            // declared underlying integer type, nil for the default
+	Constants  []*EnumConstant // table fields
+	script     *Script
 }
 
 // NewEnum .
@@ -261,6 +554,8 @@ func (script *Script) NewEnum(name string) (Type, bool) {
 
 	enum._init(name)
 
+	enum.SetParent(script)
+
 	script.types[name] = enum
 
 	return enum, true
@@ -307,6 +602,8 @@ func (enum *Enum) NewConstant(name string) (*EnumConstant, bool) {
 
 	constant._init(name)
 
+	constant.SetParent(enum)
+
 	if len(enum.Constants) == 0 {
 		constant.Value = 0
 	} else {
@@ -338,6 +635,8 @@ func (script *Script) NewContract(name string) (Type, bool) {
 
 	contract._init(name)
 
+	contract.SetParent(script)
+
 	script.types[name] = contract
 
 	return contract, true
@@ -386,16 +685,108 @@ func (contract *Contract) NewMethod(name string) (*Method, bool) {
 
 	method._init(name)
 
+	method.SetParent(contract)
+
 	contract.Methods = append(contract.Methods, method)
 
 	return method, true
 }
 
+// UnionCase union case
+type UnionCase struct {
+	_Node
+	Type Type // case type
+	Tag  int  // case tag, stable across case reorder
+}
+
+// Union tagged union, the value is exactly one of the cases. Union value binary layout :
+// uint16 case tag followed by the case value
+type Union struct {
+	_Node               // Mixin default node implement
+	Cases  []*UnionCase // union cases
+	script *Script
+}
+
+// NewUnion .
+func (script *Script) NewUnion(name string) (Type, bool) {
+
+	if union, ok := script.types[name]; ok {
+		return union, false
+	}
+
+	union := &Union{
+		script: script,
+	}
+
+	union._init(name)
+
+	union.SetParent(script)
+
+	script.types[name] = union
+
+	return union, true
+}
+
+// Package .
+func (union *Union) Package() string {
+	return union.script.Package
+}
+
+// Module .
+func (union *Union) Module() *Module {
+	return union.script.Module
+}
+
+// FullName .
+func (union *Union) FullName() string {
+	return union.script.Package + "." + union.Name()
+}
+
+// Script .
+func (union *Union) Script() string {
+	return union.script.String()
+}
+
+// Case .
+func (union *Union) Case(name string) (*UnionCase, bool) {
+	for _, unionCase := range union.Cases {
+		if unionCase.Name() == name {
+			return unionCase, true
+		}
+	}
+
+	return nil, false
+}
+
+// NewCase create union case, the tag is the previous case tag plus one
+func (union *Union) NewCase(name string, typeDecl Type) (*UnionCase, bool) {
+	if unionCase, ok := union.Case(name); ok {
+		return unionCase, false
+	}
+
+	unionCase := &UnionCase{Type: typeDecl}
+
+	unionCase._init(name)
+
+	unionCase.SetParent(union)
+
+	setParent(typeDecl, unionCase)
+
+	if len(union.Cases) != 0 {
+		unionCase.Tag = union.Cases[len(union.Cases)-1].Tag + 1
+	}
+
+	union.Cases = append(union.Cases, unionCase)
+
+	return unionCase, true
+}
+
 // Seq Type seq
 type Seq struct {
 	_Node
 	Component Type
-	Size      int
+	Size      int // fixed size, -1 for variable seq
+	Bound     int // variable seq max length declared as [<=N], 0 means unbounded
 }
 
 // NewSeq .
@@ -407,6 +798,8 @@ func NewSeq(component Type, size int) *Seq {
 
 	seq._init(fmt.Sprintf("%s[%d]", component, size))
 
+	setParent(component, seq)
+
 	return seq
 }
 
@@ -417,6 +810,10 @@ func (seq *Seq) FullName() string {
 		return fmt.Sprintf("%s[%d]", seq.Component.FullName(), seq.Size)
 	}
 
+	if seq.Bound != 0 {
+		return fmt.Sprintf("%s[<=%d]", seq.Component.FullName(), seq.Bound)
+	}
+
 	return fmt.Sprintf("%s[]", seq.Component.FullName())
 }
 
@@ -429,3 +826,37 @@ func (seq *Seq) Package() string {
 func (seq *Seq) Script() string {
 	return "gslang.gs"
 }
+
+// Optional optional type, the value may be absent
+type Optional struct {
+	_Node
+	Component Type
+}
+
+// NewOptional .
+func NewOptional(component Type) *Optional {
+	optional := &Optional{
+		Component: component,
+	}
+
+	optional._init(fmt.Sprintf("%s?", component))
+
+	setParent(component, optional)
+
+	return optional
+}
+
+// FullName .
+func (optional *Optional) FullName() string {
+	return fmt.Sprintf("%s?", optional.Component.FullName())
+}
+
+// Package .
+func (optional *Optional) Package() string {
+	return "gslang"
+}
+
+// Script .
+func (optional *Optional) Script() string {
+	return "gslang.gs"
+}
@@ -0,0 +1,572 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ExtraAnnotation extra data key of the node's attached annotation list
+const ExtraAnnotation = "annotation"
+
+// Visitor Walk visitor, Visit is called for each node, if the returned visitor w is not nil,
+// Walk visits each of the children of node with the visitor w, followed by a call of w.Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverse the ast in depth-first order, the types referenced by TypeRef and
+// ConstantRef links set by the linker are not children and are not followed
+func Walk(node Node, visitor Visitor) {
+
+	if visitor = visitor.Visit(node); visitor == nil {
+		return
+	}
+
+	for _, child := range children(node) {
+		Walk(child.node, visitor)
+	}
+
+	visitor.Visit(nil)
+}
+
+type _Inspector func(Node) bool
+
+func (f _Inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverse the ast in depth-first order, f is called for each node, if f returns true
+// Inspect invokes f recursively for each of the children of node, followed by a call of f(nil)
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, _Inspector(f))
+}
+
+// ApplyFunc Apply callback, see Apply
+type ApplyFunc func(cursor *Cursor) bool
+
+// Cursor describe the node encountered during Apply
+type Cursor struct {
+	parent  Node   // parent node
+	slot    *_Slot // node slot in parent
+	deleted bool   // deleted flag
+}
+
+// Node get current node
+func (cursor *Cursor) Node() Node {
+	return cursor.slot.node
+}
+
+// Parent get parent of current node
+func (cursor *Cursor) Parent() Node {
+	return cursor.parent
+}
+
+// Name get the parent's field name of current node, e.g. Fields, Type or Annotations
+func (cursor *Cursor) Name() string {
+	return cursor.slot.name
+}
+
+// Index get the index of current node in the parent's field list, -1 if the field is not a list
+func (cursor *Cursor) Index() int {
+	return cursor.slot.index
+}
+
+// Replace replace current node, the children of the new node are visited instead,
+// panic if the node type doesn't match the parent field. Replace(nil) deletes the node
+// like Delete, and panics if the parent field is a required single value field
+func (cursor *Cursor) Replace(node Node) {
+
+	if cursor.deleted {
+		panic(fmt.Sprintf("replace deleted node %s", cursor.slot.node))
+	}
+
+	cursor.slot.replace(node)
+
+	cursor.slot.node = node
+
+	if node != nil {
+		node.SetParent(cursor.parent)
+	}
+}
+
+// Delete delete current node, panic if the parent field is neither a list nor an optional field
+func (cursor *Cursor) Delete() {
+
+	if cursor.slot.delete == nil {
+		panic(fmt.Sprintf("can't delete node %s from field %s", cursor.slot.node, cursor.slot.name))
+	}
+
+	if !cursor.deleted {
+		cursor.slot.delete()
+		cursor.deleted = true
+	}
+}
+
+var errAbort = errors.New("apply abort")
+
+// Apply traverse the ast in depth-first order, the parent links of visited nodes are set.
+// pre is called for each node before the children, if pre returns false the children are
+// skipped. post is called after the children, if post returns false the traversal is
+// terminated. Apply returns the root node, which may be replaced by the callbacks
+func Apply(root Node, pre, post ApplyFunc) (result Node) {
+
+	defer func() {
+		if e := recover(); e != nil && e != errAbort {
+			panic(e)
+		}
+	}()
+
+	result = root
+
+	slot := &_Slot{
+		name:  "Root",
+		index: -1,
+		node:  root,
+		replace: func(node Node) {
+			result = node
+		},
+	}
+
+	apply(root.Parent(), slot, pre, post)
+
+	return
+}
+
+func apply(parent Node, slot *_Slot, pre, post ApplyFunc) {
+
+	if parent != nil {
+		slot.node.SetParent(parent)
+	}
+
+	cursor := &Cursor{parent: parent, slot: slot}
+
+	if pre != nil && !pre(cursor) {
+		return
+	}
+
+	if !cursor.deleted && slot.node != nil {
+		for _, child := range children(slot.node) {
+			apply(slot.node, child, pre, post)
+		}
+	}
+
+	if post != nil && !post(cursor) {
+		panic(errAbort)
+	}
+}
+
+// _Slot node position in parent
+type _Slot struct {
+	name    string     // parent field name
+	index   int        // index in parent field list, -1 for single value field
+	node    Node       // node
+	replace func(Node) // replace node in parent
+	delete  func()     // delete node from parent, nil for required field
+}
+
+// _List parent field list
+type _List interface {
+	Len() int
+	Get(i int) Node
+	Set(i int, node Node)
+	Remove(i int)
+}
+
+// listSlots create slots of list elements, the elements are located by identity,
+// so they can be replaced or deleted while visiting the siblings
+func listSlots(name string, list _List) (slots []*_Slot) {
+
+	for i := 0; i < list.Len(); i++ {
+
+		slot := &_Slot{
+			name:  name,
+			index: i,
+			node:  list.Get(i),
+		}
+
+		find := func() int {
+			for i := 0; i < list.Len(); i++ {
+				if list.Get(i) == slot.node {
+					return i
+				}
+			}
+
+			panic(fmt.Sprintf("node %s not found in %s", slot.node, name))
+		}
+
+		slot.replace = func(node Node) {
+			if node == nil {
+				list.Remove(find())
+				return
+			}
+
+			list.Set(find(), node)
+		}
+
+		slot.delete = func() {
+			list.Remove(find())
+		}
+
+		slots = append(slots, slot)
+	}
+
+	return
+}
+
+type _AnnotationList struct{ node Node }
+
+func (list _AnnotationList) anns() []*Annotation {
+
+	val, ok := list.node.GetExtra(ExtraAnnotation)
+
+	if !ok {
+		return nil
+	}
+
+	return val.([]*Annotation)
+}
+
+func (list _AnnotationList) Len() int             { return len(list.anns()) }
+func (list _AnnotationList) Get(i int) Node       { return list.anns()[i] }
+func (list _AnnotationList) Set(i int, node Node) { list.anns()[i] = node.(*Annotation) }
+func (list _AnnotationList) Remove(i int) {
+	anns := list.anns()
+	list.node.SetExtra(ExtraAnnotation, append(anns[:i:i], anns[i+1:]...))
+}
+
+type _FieldList struct{ table *Table }
+
+func (list _FieldList) Len() int             { return len(list.table.Fields) }
+func (list _FieldList) Get(i int) Node       { return list.table.Fields[i] }
+func (list _FieldList) Set(i int, node Node) { list.table.Fields[i] = node.(*Field) }
+func (list _FieldList) Remove(i int) {
+	list.table.Fields = append(list.table.Fields[:i:i], list.table.Fields[i+1:]...)
+}
+
+type _TypeParamList struct{ table *Table }
+
+func (list _TypeParamList) Len() int             { return len(list.table.TypeParams) }
+func (list _TypeParamList) Get(i int) Node       { return list.table.TypeParams[i] }
+func (list _TypeParamList) Set(i int, node Node) { list.table.TypeParams[i] = node.(*TypeParam) }
+func (list _TypeParamList) Remove(i int) {
+	list.table.TypeParams = append(list.table.TypeParams[:i:i], list.table.TypeParams[i+1:]...)
+}
+
+type _TypeArgList struct{ ref *TypeRef }
+
+func (list _TypeArgList) Len() int             { return len(list.ref.TypeArgs) }
+func (list _TypeArgList) Get(i int) Node       { return list.ref.TypeArgs[i] }
+func (list _TypeArgList) Set(i int, node Node) { list.ref.TypeArgs[i] = node.(Type) }
+func (list _TypeArgList) Remove(i int) {
+	list.ref.TypeArgs = append(list.ref.TypeArgs[:i:i], list.ref.TypeArgs[i+1:]...)
+}
+
+type _ConstantList struct{ enum *Enum }
+
+func (list _ConstantList) Len() int             { return len(list.enum.Constants) }
+func (list _ConstantList) Get(i int) Node       { return list.enum.Constants[i] }
+func (list _ConstantList) Set(i int, node Node) { list.enum.Constants[i] = node.(*EnumConstant) }
+func (list _ConstantList) Remove(i int) {
+	list.enum.Constants = append(list.enum.Constants[:i:i], list.enum.Constants[i+1:]...)
+}
+
+type _MethodList struct{ contract *Contract }
+
+func (list _MethodList) Len() int             { return len(list.contract.Methods) }
+func (list _MethodList) Get(i int) Node       { return list.contract.Methods[i] }
+func (list _MethodList) Set(i int, node Node) { list.contract.Methods[i] = node.(*Method) }
+func (list _MethodList) Remove(i int) {
+	list.contract.Methods = append(list.contract.Methods[:i:i], list.contract.Methods[i+1:]...)
+}
+
+type _CaseList struct{ union *Union }
+
+func (list _CaseList) Len() int             { return len(list.union.Cases) }
+func (list _CaseList) Get(i int) Node       { return list.union.Cases[i] }
+func (list _CaseList) Set(i int, node Node) { list.union.Cases[i] = node.(*UnionCase) }
+func (list _CaseList) Remove(i int) {
+	list.union.Cases = append(list.union.Cases[:i:i], list.union.Cases[i+1:]...)
+}
+
+type _ParamList struct{ method *Method }
+
+func (list _ParamList) Len() int             { return len(list.method.Params) }
+func (list _ParamList) Get(i int) Node       { return list.method.Params[i] }
+func (list _ParamList) Set(i int, node Node) { list.method.Params[i] = node.(*Param) }
+func (list _ParamList) Remove(i int) {
+	list.method.Params = append(list.method.Params[:i:i], list.method.Params[i+1:]...)
+}
+
+type _ResultList struct{ method *Method }
+
+func (list _ResultList) Len() int             { return len(list.method.Results) }
+func (list _ResultList) Get(i int) Node       { return list.method.Results[i] }
+func (list _ResultList) Set(i int, node Node) { list.method.Results[i] = node.(*Param) }
+func (list _ResultList) Remove(i int) {
+	list.method.Results = append(list.method.Results[:i:i], list.method.Results[i+1:]...)
+}
+
+type _ExceptionList struct{ method *Method }
+
+func (list _ExceptionList) Len() int             { return len(list.method.Exceptions) }
+func (list _ExceptionList) Get(i int) Node       { return list.method.Exceptions[i] }
+func (list _ExceptionList) Set(i int, node Node) { list.method.Exceptions[i] = node.(*Exception) }
+func (list _ExceptionList) Remove(i int) {
+	list.method.Exceptions = append(list.method.Exceptions[:i:i], list.method.Exceptions[i+1:]...)
+}
+
+type _ArgList struct{ args *ArgsTable }
+
+func (list _ArgList) Len() int             { return len(list.args.args) }
+func (list _ArgList) Get(i int) Node       { return list.args.args[i] }
+func (list _ArgList) Set(i int, node Node) { list.args.args[i] = node.(Expr) }
+func (list _ArgList) Remove(i int) {
+	list.args.args = append(list.args.args[:i:i], list.args.args[i+1:]...)
+}
+
+// single create slot of single value field, delete is nil for required field,
+// replace with nil node is forwarded to delete
+func single(name string, node Node, replace func(Node), delete func()) []*_Slot {
+
+	if node == nil {
+		return nil
+	}
+
+	return []*_Slot{{name: name, index: -1, node: node, replace: func(target Node) {
+
+		if target != nil {
+			replace(target)
+			return
+		}
+
+		if delete == nil {
+			panic(fmt.Sprintf("can't replace node %s of required field %s with nil", node, name))
+		}
+
+		delete()
+
+	}, delete: delete}}
+}
+
+// argsTable check the replacement node of Args field
+func argsTable(parent Node, node Node) *ArgsTable {
+
+	args, ok := node.(*ArgsTable)
+
+	if !ok {
+		panic(fmt.Sprintf("can't replace %s Args with %T, expect *ast.ArgsTable", parent, node))
+	}
+
+	return args
+}
+
+func sortedNames(names []string) []string {
+	sort.Strings(names)
+	return names
+}
+
+// children get the child slots of node, annotations first
+func children(node Node) (slots []*_Slot) {
+
+	if node == nil {
+		return nil
+	}
+
+	slots = listSlots("Annotations", _AnnotationList{node})
+
+	switch node := node.(type) {
+	case *Module:
+
+		var names []string
+
+		for name := range node.scripts {
+			names = append(names, name)
+		}
+
+		for i, name := range sortedNames(names) {
+
+			name := name
+
+			slots = append(slots, &_Slot{
+				name:  "Scripts",
+				index: i,
+				node:  node.scripts[name],
+				replace: func(script Node) {
+					if script == nil {
+						delete(node.scripts, name)
+						return
+					}
+
+					node.scripts[name] = script.(*Script)
+				},
+				delete: func() {
+					delete(node.scripts, name)
+				},
+			})
+		}
+
+	case *Script:
+
+		var names []string
+
+		for name := range node.using {
+			names = append(names, name)
+		}
+
+		for i, name := range sortedNames(names) {
+
+			name := name
+
+			slots = append(slots, &_Slot{
+				name:  "Using",
+				index: i,
+				node:  node.using[name],
+				replace: func(using Node) {
+					if using == nil {
+						delete(node.using, name)
+						return
+					}
+
+					node.using[name] = using.(*Using)
+				},
+				delete: func() {
+					delete(node.using, name)
+				},
+			})
+		}
+
+		names = nil
+
+		for name := range node.types {
+			names = append(names, name)
+		}
+
+		for i, name := range sortedNames(names) {
+
+			name := name
+
+			slots = append(slots, &_Slot{
+				name:  "Types",
+				index: i,
+				node:  node.types[name],
+				replace: func(typeDecl Node) {
+					if typeDecl == nil {
+						delete(node.types, name)
+						return
+					}
+
+					node.types[name] = typeDecl.(Type)
+				},
+				delete: func() {
+					delete(node.types, name)
+				},
+			})
+		}
+
+	case *Table:
+		slots = append(slots, listSlots("TypeParams", _TypeParamList{node})...)
+		slots = append(slots, listSlots("Fields", _FieldList{node})...)
+	case *TypeRef:
+		slots = append(slots, listSlots("TypeArgs", _TypeArgList{node})...)
+	case *Field:
+		slots = append(slots, single("Type", node.Type, func(typeDecl Node) {
+			node.Type = typeDecl.(Type)
+		}, nil)...)
+	case *Enum:
+		slots = append(slots, single("Underlying", node.Underlying, func(typeDecl Node) {
+			node.Underlying = typeDecl.(Type)
+		}, func() {
+			node.Underlying = nil
+		})...)
+		slots = append(slots, listSlots("Constants", _ConstantList{node})...)
+	case *EnumConstant:
+		slots = append(slots, single("Expr", node.Expr, func(expr Node) {
+			node.Expr = expr.(Expr)
+		}, func() {
+			node.Expr = nil
+		})...)
+	case *Contract:
+		slots = append(slots, listSlots("Methods", _MethodList{node})...)
+	case *Union:
+		slots = append(slots, listSlots("Cases", _CaseList{node})...)
+	case *UnionCase:
+		slots = append(slots, single("Type", node.Type, func(typeDecl Node) {
+			node.Type = typeDecl.(Type)
+		}, nil)...)
+	case *Method:
+		slots = append(slots, single("Return", node.Return, func(typeDecl Node) {
+			node.Return = typeDecl.(Type)
+		}, nil)...)
+
+		slots = append(slots, listSlots("Params", _ParamList{node})...)
+
+		slots = append(slots, listSlots("Results", _ResultList{node})...)
+
+		slots = append(slots, listSlots("Exceptions", _ExceptionList{node})...)
+	case *Param:
+		slots = append(slots, single("Type", node.Type, func(typeDecl Node) {
+			node.Type = typeDecl.(Type)
+		}, nil)...)
+	case *Exception:
+		slots = append(slots, single("Type", node.Type, func(typeDecl Node) {
+			node.Type = typeDecl.(Type)
+		}, nil)...)
+	case *Seq:
+		slots = append(slots, single("Component", node.Component, func(typeDecl Node) {
+			node.Component = typeDecl.(Type)
+		}, nil)...)
+	case *Optional:
+		slots = append(slots, single("Component", node.Component, func(typeDecl Node) {
+			node.Component = typeDecl.(Type)
+		}, nil)...)
+	case *Annotation:
+		slots = append(slots, single("Type", node.Type, func(ref Node) {
+			node.Type = ref.(*TypeRef)
+		}, nil)...)
+
+		if node.Args != nil {
+			slots = append(slots, single("Args", node.Args, func(args Node) {
+				node.Args = argsTable(node, args)
+			}, func() {
+				node.Args = nil
+			})...)
+		}
+	case *NewObj:
+		slots = append(slots, single("Type", node.Type, func(ref Node) {
+			node.Type = ref.(*TypeRef)
+		}, nil)...)
+
+		if node.Args != nil {
+			slots = append(slots, single("Args", node.Args, func(args Node) {
+				node.Args = argsTable(node, args)
+			}, func() {
+				node.Args = nil
+			})...)
+		}
+	case *ArgsTable:
+		slots = append(slots, listSlots("Args", _ArgList{node})...)
+	case *NamedArg:
+		slots = append(slots, single("Arg", node.Arg, func(arg Node) {
+			node.Arg = arg.(Expr)
+		}, nil)...)
+	case *UnaryOp:
+		slots = append(slots, single("Operand", node.Operand, func(operand Node) {
+			node.Operand = operand.(Expr)
+		}, nil)...)
+	case *BinaryOp:
+		slots = append(slots, single("LHS", node.LHS, func(lhs Node) {
+			node.LHS = lhs.(Expr)
+		}, nil)...)
+
+		slots = append(slots, single("RHS", node.RHS, func(rhs Node) {
+			node.RHS = rhs.(Expr)
+		}, nil)...)
+	}
+
+	return
+}
@@ -0,0 +1,307 @@
+// gslangc gslang compiler command line tool
+//
+//	gslangc compat [-I path]... [-strict] old/ new/
+//	gslangc go [-I path]... -package name [-o file] dir
+//	gslangc lsp
+//	gslangc --watch [-I path]... [-interval 1s] [-openapi file] [-descriptor file] dir...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/compat"
+	"github.com/gsrpc/gslang/descriptor"
+	"github.com/gsrpc/gslang/gogen"
+	"github.com/gsrpc/gslang/lsp"
+	"github.com/gsrpc/gslang/openapi"
+	"github.com/gsrpc/gslang/watch"
+)
+
+// exit codes
+const (
+	exitOK = iota
+	exitBreaking
+	exitError
+)
+
+// includes -I flag values
+type includes []string
+
+func (dirs *includes) String() string {
+	return strings.Join(*dirs, ",")
+}
+
+func (dirs *includes) Set(dir string) error {
+	*dirs = append(*dirs, dir)
+	return nil
+}
+
+var commands = map[string]func(args []string) int{
+	"compat": compatCommand,
+	"go":     goCommand,
+	"lsp":    lspCommand,
+	"watch":  watchCommand,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: gslangc <command> [arguments]\n\ncommands:\n")
+	fmt.Fprintf(os.Stderr, "\tcompat [-I path]... [-strict] old/ new/\tcheck schema compatibility between two versions\n")
+	fmt.Fprintf(os.Stderr, "\tgo [-I path]... -package name [-o file] dir\tgenerate go types and rpc stubs of package\n")
+	fmt.Fprintf(os.Stderr, "\tlsp\t\t\t\t\t\trun language server over stdio\n")
+	fmt.Fprintf(os.Stderr, "\t--watch [-I path]... [-interval 1s] [-openapi file] [-descriptor file] dir...\trecompile and regenerate on file changes\n")
+}
+
+func main() {
+
+	defer gslogger.Join()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitError)
+	}
+
+	command, ok := commands[strings.TrimLeft(os.Args[1], "-")]
+
+	if !ok {
+		usage()
+		os.Exit(exitError)
+	}
+
+	os.Exit(command(os.Args[2:]))
+}
+
+func compatCommand(args []string) int {
+
+	flagSet := flag.NewFlagSet("compat", flag.ExitOnError)
+
+	var dirs includes
+
+	flagSet.Var(&dirs, "I", "include script file or directory compiled into both versions, e.g. the gslang prelude scripts")
+
+	strict := flagSet.Bool("strict", false, "fail on source breaking changes too")
+
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 2 {
+		flagSet.Usage()
+		return exitError
+	}
+
+	old, err := load(flagSet.Arg(0), dirs)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compile %s error:\n%s\n", flagSet.Arg(0), err)
+		return exitError
+	}
+
+	current, err := load(flagSet.Arg(1), dirs)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compile %s error:\n%s\n", flagSet.Arg(1), err)
+		return exitError
+	}
+
+	report := compat.Check(old, current)
+
+	fmt.Print(report)
+
+	if report.WireBreaking() || (*strict && report.SourceBreaking()) {
+		return exitBreaking
+	}
+
+	return exitOK
+}
+
+// load compile and link all .gs and .proto scripts in directory and includes
+func load(dir string, dirs []string) (*ast.Module, error) {
+
+	compiler, err := compile(dir, dirs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return compiler.Module(), nil
+}
+
+// compile compile and link all .gs and .proto scripts in directory and includes
+func compile(dir string, dirs []string) (*gslang.Compiler, error) {
+
+	compiler := gslang.NewCompiler(dir, gslang.HandleError(func(err *gslang.Error) {
+		if err.Warning {
+			fmt.Fprintf(os.Stderr, "%s: warning: %s\n", err.Start, err.Text)
+			return
+		}
+
+		gserrors.Panicf(err.Orignal, "%s: %s", err.Start, err.Text)
+	}))
+
+	for _, root := range append([]string{dir}, dirs...) {
+
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+			if err != nil {
+				return err
+			}
+
+			switch filepath.Ext(path) {
+			case ".gs":
+				return compiler.Compile(path)
+			case ".proto":
+				return compiler.ImportProto(path, root)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := compiler.Link(); err != nil {
+		return nil, err
+	}
+
+	return compiler, nil
+}
+
+func goCommand(args []string) int {
+
+	flagSet := flag.NewFlagSet("go", flag.ExitOnError)
+
+	var dirs includes
+
+	flagSet.Var(&dirs, "I", "include script file or directory, e.g. the gslang prelude scripts")
+
+	pkg := flagSet.String("package", "", "generated gslang package name")
+
+	output := flagSet.String("o", "", "generated go file, defaults to stdout")
+
+	flagSet.Parse(args)
+
+	if flagSet.NArg() != 1 || *pkg == "" {
+		flagSet.Usage()
+		return exitError
+	}
+
+	compiler, err := compile(flagSet.Arg(0), dirs)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compile %s error:\n%s\n", flagSet.Arg(0), err)
+		return exitError
+	}
+
+	source, err := gogen.Generate(compiler, *pkg)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "generate package %s error :%s\n", *pkg, err)
+		return exitError
+	}
+
+	if *output == "" {
+		os.Stdout.Write(source)
+		return exitOK
+	}
+
+	if err := ioutil.WriteFile(*output, source, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "write %s error :%s\n", *output, err)
+		return exitError
+	}
+
+	return exitOK
+}
+
+func lspCommand(args []string) int {
+
+	flagSet := flag.NewFlagSet("lsp", flag.ExitOnError)
+
+	flagSet.Parse(args)
+
+	if err := lsp.NewServer().Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "language server error :%s\n", err)
+		return exitError
+	}
+
+	return exitOK
+}
+
+func watchCommand(args []string) int {
+
+	flagSet := flag.NewFlagSet("watch", flag.ExitOnError)
+
+	var dirs includes
+
+	flagSet.Var(&dirs, "I", "include script file or directory, e.g. the gslang prelude scripts")
+
+	interval := flagSet.Duration("interval", time.Second, "poll interval")
+
+	openapiFile := flagSet.String("openapi", "", "generate OpenAPI document file")
+
+	descriptorFile := flagSet.String("descriptor", "", "generate JSON descriptor file")
+
+	flagSet.Parse(args)
+
+	if flagSet.NArg() == 0 {
+		flagSet.Usage()
+		return exitError
+	}
+
+	watcher := watch.New("watch", watch.NewDirPoller(append(dirs, flagSet.Args()...)...), watch.SystemClock, *interval, os.Stdout)
+
+	if *openapiFile != "" {
+		watcher.Add(&watch.Generator{
+			Name: "openapi",
+			Run: func(compiler *gslang.Compiler) error {
+
+				document, err := openapi.Generate(compiler, compiler.Module().Name(), "1.0")
+
+				if err != nil {
+					return err
+				}
+
+				return writeFile(*openapiFile, document.Write)
+			},
+		})
+	}
+
+	if *descriptorFile != "" {
+		watcher.Add(&watch.Generator{
+			Name: "descriptor",
+			Run: func(compiler *gslang.Compiler) error {
+				return writeFile(*descriptorFile, descriptor.New(compiler.Module()).Write)
+			},
+		})
+	}
+
+	if err := watcher.Run(nil); err != nil {
+		fmt.Fprintf(os.Stderr, "watch error :%s\n", err)
+		return exitError
+	}
+
+	return exitOK
+}
+
+func writeFile(path string, write func(writer io.Writer) error) error {
+
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return write(file)
+}
@@ -0,0 +1,539 @@
+// Package compat check schema compatibility between two linked gslang modules
+package compat
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+)
+
+// Level compatibility level
+type Level int
+
+// compatibility levels
+const (
+	Compatible Level = iota
+	Breaking
+)
+
+func (level Level) String() string {
+	if level == Breaking {
+		return "breaking"
+	}
+
+	return "compatible"
+}
+
+// Change one schema change between two module versions
+type Change struct {
+	Path   string // changed node path, e.g. gslang.test.Duration.Unit
+	Wire   Level  // wire compatibility, whether deployed peers can still talk with each other
+	Source Level  // source compatibility, whether generated code users still compile
+	Text   string // change description
+}
+
+func (change *Change) String() string {
+	return fmt.Sprintf("%s: %s (wire %s, source %s)", change.Path, change.Text, change.Wire, change.Source)
+}
+
+// Report compatibility check report
+type Report struct {
+	Changes []*Change // change list
+}
+
+// WireBreaking check if report contains wire breaking changes
+func (report *Report) WireBreaking() bool {
+	for _, change := range report.Changes {
+		if change.Wire == Breaking {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SourceBreaking check if report contains source breaking changes
+func (report *Report) SourceBreaking() bool {
+	for _, change := range report.Changes {
+		if change.Source == Breaking {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (report *Report) String() string {
+	var buff bytes.Buffer
+
+	for _, change := range report.Changes {
+		buff.WriteString(change.String())
+		buff.WriteRune('\n')
+	}
+
+	return buff.String()
+}
+
+func (report *Report) add(path string, wire Level, source Level, fmtstring string, args ...interface{}) {
+	report.Changes = append(report.Changes, &Change{
+		Path:   path,
+		Wire:   wire,
+		Source: source,
+		Text:   fmt.Sprintf(fmtstring, args...),
+	})
+}
+
+// Check compare old and current linked modules and report the changes
+func Check(old *ast.Module, current *ast.Module) *Report {
+
+	report := &Report{}
+
+	var names []string
+
+	for name := range old.Types {
+		names = append(names, name)
+	}
+
+	for name := range current.Types {
+		if _, ok := old.Types[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		oldType, ok := old.Types[name]
+
+		if !ok {
+			report.add(name, Compatible, Compatible, "type added")
+			continue
+		}
+
+		newType, ok := current.Types[name]
+
+		if !ok {
+			report.add(name, Breaking, Breaking, "type removed")
+			continue
+		}
+
+		switch oldType.(type) {
+		case *ast.Table:
+			if newTable, ok := newType.(*ast.Table); ok {
+				report.table(oldType.(*ast.Table), newTable)
+				continue
+			}
+		case *ast.Enum:
+			if newEnum, ok := newType.(*ast.Enum); ok {
+				report.enum(oldType.(*ast.Enum), newEnum)
+				continue
+			}
+		case *ast.Contract:
+			if newContract, ok := newType.(*ast.Contract); ok {
+				report.contract(oldType.(*ast.Contract), newContract)
+				continue
+			}
+		case *ast.Union:
+			if newUnion, ok := newType.(*ast.Union); ok {
+				report.union(oldType.(*ast.Union), newUnion)
+				continue
+			}
+		}
+
+		report.add(name, Breaking, Breaking, "type kind changed from %s to %s", kind(oldType), kind(newType))
+	}
+
+	return report
+}
+
+func kind(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.Table:
+		return "table"
+	case *ast.Enum:
+		return "enum"
+	case *ast.Contract:
+		return "contract"
+	case *ast.Union:
+		return "union"
+	}
+
+	return "unknown"
+}
+
+func (report *Report) table(old *ast.Table, current *ast.Table) {
+
+	path := old.FullName()
+
+	if len(old.TypeParams) != len(current.TypeParams) {
+		report.add(path, Breaking, Breaking, "type params count changed from %d to %d", len(old.TypeParams), len(current.TypeParams))
+	}
+
+	if gslang.IsPOD(old) != gslang.IsPOD(current) {
+		report.add(path, Breaking, Compatible, "POD layout changed")
+	}
+
+	for i, oldField := range old.Fields {
+
+		fieldPath := path + "." + oldField.Name()
+
+		newField, ok := current.Field(oldField.Name())
+
+		if !ok {
+			// same position and type with different name is a rename
+			if i < len(current.Fields) && baseName(current.Fields[i].Type) == baseName(oldField.Type) {
+				if _, ok := old.Field(current.Fields[i].Name()); !ok {
+					report.add(fieldPath, Compatible, Breaking, "field renamed to %s", current.Fields[i].Name())
+					continue
+				}
+			}
+
+			report.add(fieldPath, Breaking, Breaking, "field removed")
+			continue
+		}
+
+		report.typeChanged(fieldPath, "field", oldField.Type, newField.Type)
+
+		if i >= len(current.Fields) || current.Fields[i] != newField {
+			report.add(fieldPath, Breaking, Compatible, "field position changed")
+		}
+	}
+
+	for i, newField := range current.Fields {
+
+		if _, ok := old.Field(newField.Name()); ok {
+			continue
+		}
+
+		if i < len(old.Fields) {
+			if _, ok := current.Field(old.Fields[i].Name()); !ok && baseName(old.Fields[i].Type) == baseName(newField.Type) {
+				// reported as rename
+				continue
+			}
+		}
+
+		// only appending fields to non POD tables keeps the encoding compatible
+		if i >= len(old.Fields) && !gslang.IsPOD(current) {
+			report.add(path+"."+newField.Name(), Compatible, Compatible, "field appended")
+		} else {
+			report.add(path+"."+newField.Name(), Breaking, Compatible, "field inserted")
+		}
+	}
+}
+
+func (report *Report) enum(old *ast.Enum, current *ast.Enum) {
+
+	path := old.FullName()
+
+	if oldType, newType := gslang.EnumType(old), gslang.EnumType(current); oldType != newType {
+		report.add(path, Breaking, Compatible, "enum underlying type changed from %s to %s", oldType, newType)
+	}
+
+	for _, oldConstant := range old.Constants {
+
+		constantPath := path + "." + oldConstant.Name()
+
+		newConstant, ok := current.Constant(oldConstant.Name())
+
+		if ok {
+			if oldConstant.Value != newConstant.Value {
+				report.add(constantPath, Breaking, Compatible, "enum constant value changed from %d to %d", oldConstant.Value, newConstant.Value)
+			}
+
+			continue
+		}
+
+		renamed := false
+
+		for _, constant := range current.Constants {
+			if _, ok := old.Constant(constant.Name()); !ok && constant.Value == oldConstant.Value {
+				report.add(constantPath, Compatible, Breaking, "enum constant renamed to %s", constant.Name())
+				renamed = true
+				break
+			}
+		}
+
+		if !renamed {
+			report.add(constantPath, Breaking, Breaking, "enum constant removed")
+		}
+	}
+
+	for _, newConstant := range current.Constants {
+
+		if _, ok := old.Constant(newConstant.Name()); ok {
+			continue
+		}
+
+		renamed := false
+
+		for _, constant := range old.Constants {
+			if _, ok := current.Constant(constant.Name()); !ok && constant.Value == newConstant.Value {
+				renamed = true
+				break
+			}
+		}
+
+		if !renamed {
+			report.add(path+"."+newConstant.Name(), Compatible, Compatible, "enum constant added")
+		}
+	}
+}
+
+func (report *Report) union(old *ast.Union, current *ast.Union) {
+
+	path := old.FullName()
+
+	// renamed case keeps the tag and the type
+	renamed := func(unionCase *ast.UnionCase, cases *ast.Union, names *ast.Union) (*ast.UnionCase, bool) {
+		for _, target := range cases.Cases {
+			if _, ok := names.Case(target.Name()); !ok && target.Tag == unionCase.Tag && baseName(target.Type) == baseName(unionCase.Type) {
+				return target, true
+			}
+		}
+
+		return nil, false
+	}
+
+	for _, oldCase := range old.Cases {
+
+		casePath := path + "." + oldCase.Name()
+
+		newCase, ok := current.Case(oldCase.Name())
+
+		if !ok {
+			if target, ok := renamed(oldCase, current, old); ok {
+				report.add(casePath, Compatible, Breaking, "union case renamed to %s", target.Name())
+			} else {
+				report.add(casePath, Breaking, Breaking, "union case removed")
+			}
+
+			continue
+		}
+
+		if oldCase.Tag != newCase.Tag {
+			report.add(casePath, Breaking, Compatible, "union case tag changed from %d to %d", oldCase.Tag, newCase.Tag)
+		}
+
+		report.typeChanged(casePath, "union case", oldCase.Type, newCase.Type)
+	}
+
+	for _, newCase := range current.Cases {
+
+		if _, ok := old.Case(newCase.Name()); ok {
+			continue
+		}
+
+		if _, ok := renamed(newCase, old, current); !ok {
+			report.add(path+"."+newCase.Name(), Compatible, Compatible, "union case added")
+		}
+	}
+}
+
+func (report *Report) contract(old *ast.Contract, current *ast.Contract) {
+
+	path := old.FullName()
+
+	// the service name and version route the calls
+	if oldName, newName := gslang.ServiceName(old), gslang.ServiceName(current); oldName != newName {
+		report.add(path, Breaking, Compatible, "service name changed from %s to %s", oldName, newName)
+	}
+
+	if oldVersion, newVersion := gslang.ServiceVersion(old), gslang.ServiceVersion(current); oldVersion != newVersion {
+		report.add(path, Breaking, Compatible, "service version changed from %d to %d", oldVersion, newVersion)
+	}
+
+	for _, oldMethod := range old.Methods {
+
+		methodPath := path + "." + oldMethod.Name()
+
+		newMethod, ok := current.Method(oldMethod.Name())
+
+		if !ok {
+			report.add(methodPath, Breaking, Breaking, "method removed")
+			continue
+		}
+
+		report.method(methodPath, oldMethod, newMethod)
+	}
+
+	for _, newMethod := range current.Methods {
+		if _, ok := old.Method(newMethod.Name()); !ok {
+			report.add(path+"."+newMethod.Name(), Compatible, Compatible, "method added")
+		}
+	}
+}
+
+func (report *Report) method(path string, old *ast.Method, current *ast.Method) {
+
+	if oldID, newID := gslang.MethodID(old), gslang.MethodID(current); oldID != newID {
+		report.add(path, Breaking, Compatible, "method id changed from %d to %d", oldID, newID)
+	}
+
+	if gslang.IsAsync(old) != gslang.IsAsync(current) {
+		report.add(path, Breaking, Breaking, "method async mode changed")
+	}
+
+	if old.Stream != current.Stream {
+		report.add(path, Breaking, Breaking, "method stream mode changed from %s to %s", old.Stream, current.Stream)
+	}
+
+	report.typeChanged(path, "method return", old.Return, current.Return)
+
+	report.params(path, "param", old.Params, current.Params)
+
+	report.params(path, "result", old.Results, current.Results)
+
+	for _, oldException := range old.Exceptions {
+
+		newException, ok := findException(current, ast.TypeName(oldException.Type))
+
+		if !ok {
+			// callers never receive the removed exception
+			report.add(path, Compatible, Compatible, "exception %s removed", ast.TypeName(oldException.Type))
+			continue
+		}
+
+		if oldException.ID != newException.ID {
+			report.add(path, Breaking, Compatible, "exception %s id changed from %d to %d", ast.TypeName(oldException.Type), oldException.ID, newException.ID)
+		}
+	}
+
+	for _, newException := range current.Exceptions {
+		if _, ok := findException(old, ast.TypeName(newException.Type)); !ok {
+			report.add(path, Breaking, Compatible, "exception %s added", ast.TypeName(newException.Type))
+		}
+	}
+}
+
+func (report *Report) params(path string, kind string, old []*ast.Param, current []*ast.Param) {
+
+	if len(old) != len(current) {
+		report.add(path, Breaking, Breaking, "method %ss count changed from %d to %d", kind, len(old), len(current))
+		return
+	}
+
+	for i, oldParam := range old {
+
+		newParam := current[i]
+
+		paramPath := path + "." + oldParam.Name()
+
+		report.typeChanged(paramPath, kind, oldParam.Type, newParam.Type)
+
+		if oldParam.Name() != newParam.Name() {
+			report.add(paramPath, Compatible, Breaking, "%s renamed to %s", kind, newParam.Name())
+		}
+
+		if oldParam.Stream != newParam.Stream {
+			report.add(paramPath, Breaking, Breaking, "%s stream mode changed", kind)
+		}
+	}
+}
+
+func findException(method *ast.Method, name string) (*ast.Exception, bool) {
+	for _, exception := range method.Exceptions {
+		if ast.TypeName(exception.Type) == name {
+			return exception, true
+		}
+	}
+
+	return nil, false
+}
+
+// typeChanged compare the base types and the bounds separately,
+// raising or removing a bound keeps the old values valid, lowering or adding one doesn't
+func (report *Report) typeChanged(path string, kind string, old ast.Type, current ast.Type) {
+
+	if baseName(old) != baseName(current) {
+		report.add(path, Breaking, Breaking, "%s type changed from %s to %s", kind, ast.TypeName(old), ast.TypeName(current))
+		return
+	}
+
+	oldBounds, newBounds := bounds(old, nil), bounds(current, nil)
+
+	for i, oldBound := range oldBounds {
+
+		newBound := newBounds[i]
+
+		if oldBound == newBound {
+			continue
+		}
+
+		if newBound == 0 || (oldBound != 0 && newBound > oldBound) {
+			report.add(path, Compatible, Compatible, "%s type bound raised from %s to %s", kind, ast.TypeName(old), ast.TypeName(current))
+		} else {
+			report.add(path, Breaking, Compatible, "%s type bound lowered from %s to %s", kind, ast.TypeName(old), ast.TypeName(current))
+		}
+
+		return
+	}
+}
+
+// baseName get the ast.TypeName without the string and seq bounds
+func baseName(typeDecl ast.Type) string {
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		return typeDecl.Name()
+	case *ast.TypeRef:
+		ref := typeDecl.(*ast.TypeRef)
+
+		name := ref.Name()
+
+		if ref.Ref != nil {
+			name = ref.Ref.FullName()
+		}
+
+		if len(ref.TypeArgs) == 0 {
+			return name
+		}
+
+		var args []string
+
+		for _, arg := range ref.TypeArgs {
+			args = append(args, baseName(arg))
+		}
+
+		return name + "<" + strings.Join(args, ",") + ">"
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if seq.Size > 0 {
+			return fmt.Sprintf("%s[%d]", baseName(seq.Component), seq.Size)
+		}
+
+		return baseName(seq.Component) + "[]"
+	case *ast.Optional:
+		return baseName(typeDecl.(*ast.Optional).Component) + "?"
+	}
+
+	return typeDecl.FullName()
+}
+
+// bounds collect the string and seq bounds in the baseName order, 0 means unbounded
+func bounds(typeDecl ast.Type, collected []int) []int {
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		return append(collected, typeDecl.(*ast.BuiltinType).Bound)
+	case *ast.TypeRef:
+		for _, arg := range typeDecl.(*ast.TypeRef).TypeArgs {
+			collected = bounds(arg, collected)
+		}
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		collected = append(collected, seq.Bound)
+
+		return bounds(seq.Component, collected)
+	case *ast.Optional:
+		return bounds(typeDecl.(*ast.Optional).Component, collected)
+	}
+
+	return collected
+}
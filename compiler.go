@@ -2,7 +2,9 @@ package gslang
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
+	"sort"
 	"strings"
 
 	"github.com/gsdocker/gserrors"
@@ -28,6 +30,12 @@ type Error struct {
 	Start   lexer.Position // error location start
 	End     lexer.Position // error location end
 	Text    string         // error description
+	Warning bool           // warning flag, warnings don't stop compiling
+}
+
+// Error implement error interface
+func (err *Error) Error() string {
+	return fmt.Sprintf("%s: %s", err.Start, err.Text)
 }
 
 // ErrorHandler .
@@ -45,10 +53,17 @@ func (handle HandleError) HandleError(err *Error) {
 
 // Compiler gslang compiler
 type Compiler struct {
-	gslogger.Log              // Mixin log
-	module       *ast.Module  // compiled scripts
-	errorHandler ErrorHandler // error handler
-	eval         Eval         //eval site
+	gslogger.Log                            // Mixin log
+	module       *ast.Module                // compiled scripts
+	errorHandler ErrorHandler               // error handler, record diagnostics then call handler
+	handler      ErrorHandler               // user error handler
+	eval         Eval                       //eval site
+	protoFiles   map[string]bool            // imported proto files
+	protoTypes   map[string]string          // imported proto types' gslang full name
+	diagnostics  map[string][]*Error        // reported errors indexed by script name
+	deps         map[string]map[string]bool // scripts dependencies resolved by linker
+	linked       bool                       // module linked flag
+	current      string                     // current compiling or linking script name
 }
 
 // NewCompiler .
@@ -56,13 +71,59 @@ func NewCompiler(name string, errorHandler ErrorHandler) *Compiler {
 
 	module := ast.NewModule(name)
 
-	return &Compiler{
-		Log:          gslogger.Get("compiler"),
-		module:       module,
-		errorHandler: errorHandler,
-		eval:         newEval(errorHandler, module),
+	compiler := &Compiler{
+		Log:         gslogger.Get("compiler"),
+		module:      module,
+		handler:     errorHandler,
+		protoFiles:  make(map[string]bool),
+		protoTypes:  make(map[string]string),
+		diagnostics: make(map[string][]*Error),
+		deps:        make(map[string]map[string]bool),
+	}
+
+	compiler.errorHandler = HandleError(compiler.report)
+
+	compiler.eval = NewEval(compiler.errorHandler, module)
+
+	return compiler
+}
+
+// report record error as diagnostic of the script, then call user error handler
+func (compiler *Compiler) report(err *Error) {
+
+	name := err.Start.FileName
+
+	if name == "" {
+		name = compiler.current
 	}
 
+	compiler.diagnostics[name] = append(compiler.diagnostics[name], err)
+
+	compiler.handler.HandleError(err)
+}
+
+// invalidate drop script diagnostics of stage
+func (compiler *Compiler) invalidate(name string, stage Stage) {
+
+	var diagnostics []*Error
+
+	for _, err := range compiler.diagnostics[name] {
+		if err.Stage != stage {
+			diagnostics = append(diagnostics, err)
+		}
+	}
+
+	if diagnostics == nil {
+		delete(compiler.diagnostics, name)
+		return
+	}
+
+	compiler.diagnostics[name] = diagnostics
+}
+
+// Diagnostics get errors and warnings reported for script by the last compile and link
+func (compiler *Compiler) Diagnostics(name string) []*Error {
+	return append([]*Error(nil), compiler.diagnostics[name]...)
 }
 
 // Eval .
@@ -70,31 +131,158 @@ func (compiler *Compiler) Eval() Eval {
 	return compiler.eval
 }
 
+// Module get compiled module
+func (compiler *Compiler) Module() *ast.Module {
+	return compiler.module
+}
+
 // Compile .
 func (compiler *Compiler) Compile(filepath string) (err error) {
+
+	content, err := ioutil.ReadFile(filepath)
+
+	if err != nil {
+		return err
+	}
+
+	return compiler.CompileSource(filepath, content)
+}
+
+// CompileSource compile script source content, the name is used as script name
+func (compiler *Compiler) CompileSource(name string, content []byte) (err error) {
 	defer func() {
 		if e := recover(); e != nil {
 			err = e.(error)
+
+			// aborted without reporting, e.g. by the user error handler
+			if len(compiler.diagnostics[name]) == 0 {
+				compiler.diagnostics[name] = []*Error{{Stage: StageParing, Orignal: err, Text: err.Error()}}
+			}
 		}
 	}()
 
-	content, err := ioutil.ReadFile(filepath)
+	compiler.current = name
 
-	if err != nil {
+	delete(compiler.diagnostics, name)
+
+	compiler.parse(lexer.NewLexer(name, bytes.NewBuffer(content)), compiler.errorHandler)
+
+	return
+}
+
+// Update replace script source or add new script,
+// if the module has been linked the script and its dependents are relinked
+func (compiler *Compiler) Update(name string, content []byte) error {
+
+	affected := compiler.affected(name)
+
+	compiler.removeScript(name)
+
+	err := compiler.CompileSource(name, content)
+
+	if !compiler.linked {
 		return err
 	}
 
-	compiler.parse(lexer.NewLexer(filepath, bytes.NewBuffer(content)), compiler.errorHandler)
+	// relink the script even if the parsing failed, the parsed types are still usable
+	affected[name] = true
+
+	if linkErr := compiler.link(affected); err == nil {
+		err = linkErr
+	}
+
+	return err
+}
+
+// Remove remove script, if the module has been linked the script's dependents are relinked
+func (compiler *Compiler) Remove(name string) error {
+
+	if _, ok := compiler.module.Script(name); !ok {
+		return nil
+	}
+
+	affected := compiler.affected(name)
+
+	compiler.removeScript(name)
+
+	if !compiler.linked {
+		return nil
+	}
+
+	return compiler.link(affected)
+}
+
+// Dependents get the scripts which reference types defined by script, sorted by name
+func (compiler *Compiler) Dependents(name string) (dependents []string) {
+
+	for script, deps := range compiler.deps {
+		if deps[name] {
+			dependents = append(dependents, script)
+		}
+	}
+
+	sort.Strings(dependents)
 
 	return
 }
 
+// affected get the scripts to relink after script changed : the dependents and the scripts
+// which have semantic errors, those may reference types defined by the new script version
+func (compiler *Compiler) affected(name string) map[string]bool {
+
+	affected := make(map[string]bool)
+
+	for _, script := range compiler.Dependents(name) {
+		affected[script] = true
+	}
+
+	for script, diagnostics := range compiler.diagnostics {
+		for _, err := range diagnostics {
+			if err.Stage == StageSemParing {
+				affected[script] = true
+				break
+			}
+		}
+	}
+
+	delete(affected, name)
+
+	return affected
+}
+
+func (compiler *Compiler) removeScript(name string) {
+
+	compiler.module.RemoveScript(name)
+
+	for fullname, typeDecl := range compiler.module.Types {
+		if typeDecl.Script() == name {
+			delete(compiler.module.Types, fullname)
+		}
+	}
+
+	// module annotations moved from the script
+	var anns []*ast.Annotation
+
+	for _, annotation := range Annotations(compiler.module) {
+		if start, _ := Pos(annotation); start.FileName != name {
+			anns = append(anns, annotation)
+		}
+	}
+
+	compiler.module.SetExtra(ExtraAnnotation, anns)
+
+	delete(compiler.diagnostics, name)
+
+	delete(compiler.deps, name)
+}
+
 // Visitor gslang CodeGen
 type Visitor interface {
 	BeginScript(compiler *Compiler, script *ast.Script) bool
 	// get using template
 	Using(compiler *Compiler, using *ast.Using)
 
+	// generic tables are visited as declarations, the concrete instances are got by Instantiate
 	Table(compiler *Compiler, tableType *ast.Table)
 
 	Annotation(compiler *Compiler, annotation *ast.Table)
@@ -102,6 +290,8 @@ type Visitor interface {
 	Enum(compiler *Compiler, enum *ast.Enum)
 
 	Contract(compiler *Compiler, contract *ast.Contract)
+
+	Union(compiler *Compiler, union *ast.Union)
 	//
 	EndScript(compiler *Compiler)
 }
@@ -174,6 +364,8 @@ func (codeGen *_Visitor) visit() {
 				codeGen.codeGen.Enum(codeGen.compiler, typeDecl.(*ast.Enum))
 			case *ast.Contract:
 				codeGen.codeGen.Contract(codeGen.compiler, typeDecl.(*ast.Contract))
+			case *ast.Union:
+				codeGen.codeGen.Union(codeGen.compiler, typeDecl.(*ast.Union))
 			}
 		})
 
@@ -0,0 +1,212 @@
+package gslang
+
+import (
+	"math"
+	"regexp"
+
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+// ConstraintSet normalized validation constraints of table field or method param, which are declared by
+// gslang.Range, gslang.Pattern, gslang.Length and gslang.NotEmpty
+type ConstraintSet struct {
+	Min       *float64 // inclusive min value, nil means unbounded
+	Max       *float64 // inclusive max value, nil means unbounded
+	Pattern   string   // regular expression the string value must match, "" means any
+	MinLength int      // min length of string or seq
+	MaxLength int      // max length of string or seq, the type bound is merged, 0 means unbounded
+}
+
+// Empty check if no constraint is set
+func (set *ConstraintSet) Empty() bool {
+	return set.Min == nil && set.Max == nil && set.Pattern == "" && set.MinLength == 0 && set.MaxLength == 0
+}
+
+// constraint kinds, which are the value types the constraint annotations can be applied to
+const (
+	constraintNumeric = iota
+	constraintString
+	constraintLength
+)
+
+var constraints = map[string]int{
+	"gslang.Range":    constraintNumeric,
+	"gslang.Pattern":  constraintString,
+	"gslang.Length":   constraintLength,
+	"gslang.NotEmpty": constraintLength,
+}
+
+// constraintType get the constrained type of field or param, the optional type is unwrapped
+func constraintType(node ast.Node) ast.Type {
+
+	var typeDecl ast.Type
+
+	switch node.(type) {
+	case *ast.Field:
+		typeDecl = node.(*ast.Field).Type
+	case *ast.Param:
+		typeDecl = node.(*ast.Param).Type
+	default:
+		return nil
+	}
+
+	if optional, ok := typeDecl.(*ast.Optional); ok {
+		return optional.Component
+	}
+
+	return typeDecl
+}
+
+// constraintApplicable check if the constraint kind can be applied to typeDecl
+func constraintApplicable(kind int, typeDecl ast.Type) bool {
+
+	builtin, ok := typeDecl.(*ast.BuiltinType)
+
+	switch kind {
+	case constraintNumeric:
+		return ok && numerics[builtin.Type]
+	case constraintString:
+		return ok && builtin.Type == lexer.KeyString
+	case constraintLength:
+		if _, ok := typeDecl.(*ast.Seq); ok {
+			return true
+		}
+
+		return ok && builtin.Type == lexer.KeyString
+	}
+
+	return false
+}
+
+var numerics = map[lexer.TokenType]bool{
+	lexer.KeySByte: true, lexer.KeyByte: true, lexer.KeyInt16: true, lexer.KeyUInt16: true,
+	lexer.KeyInt32: true, lexer.KeyUInt32: true, lexer.KeyInt64: true, lexer.KeyUInt64: true,
+	lexer.KeyFloat32: true, lexer.KeyFloat64: true,
+}
+
+// integerRanges the value ranges of integer types, which the range constraints must be in
+var integerRanges = map[lexer.TokenType][2]float64{
+	lexer.KeySByte: {math.MinInt8, math.MaxInt8}, lexer.KeyByte: {0, math.MaxUint8},
+	lexer.KeyInt16: {math.MinInt16, math.MaxInt16}, lexer.KeyUInt16: {0, math.MaxUint16},
+	lexer.KeyInt32: {math.MinInt32, math.MaxInt32}, lexer.KeyUInt32: {0, math.MaxUint32},
+	lexer.KeyInt64: {math.MinInt64, math.MaxInt64}, lexer.KeyUInt64: {0, math.MaxUint64},
+}
+
+// Constraints get the normalized validation constraints of table field or method param, the returned error
+// is *Error with the position of the illegal constraint annotation
+func Constraints(node ast.Node) (*ConstraintSet, error) {
+
+	set := &ConstraintSet{}
+
+	typeDecl := constraintType(node)
+
+	if max, ok := MaxLength(typeDecl); ok {
+		set.MaxLength = max
+
+		if seq, ok := typeDecl.(*ast.Seq); ok && seq.Size > 0 {
+			set.MinLength = seq.Size
+		}
+	}
+
+	for _, annotation := range Annotations(node) {
+
+		if annotation.Type.Ref == nil {
+			continue
+		}
+
+		name := annotation.Type.Ref.FullName()
+
+		kind, ok := constraints[name]
+
+		if !ok {
+			continue
+		}
+
+		if !constraintApplicable(kind, typeDecl) {
+			return nil, annotationErrorf(ErrConstraint, annotation, "constraint(%s) can't be applied to %s type(%s)", name, node, typeDecl)
+		}
+
+		switch name {
+		case "gslang.Range":
+
+			var val struct {
+				Min *float64
+				Max *float64
+			}
+
+			if err := DecodeAnnotation(annotation, &val); err != nil {
+				return nil, err
+			}
+
+			if val.Min != nil && val.Max != nil && *val.Min > *val.Max {
+				return nil, annotationErrorf(ErrConstraint, annotation, "constraint(%s) min(%v) greater than max(%v)", name, *val.Min, *val.Max)
+			}
+
+			if bounds, ok := integerRanges[typeDecl.(*ast.BuiltinType).Type]; ok {
+				for _, bound := range []*float64{val.Min, val.Max} {
+					if bound != nil && (*bound < bounds[0] || *bound > bounds[1]) {
+						return nil, annotationErrorf(ErrConstraint, annotation, "constraint(%s) bound(%v) out of %s type(%s) range [%v,%v]", name, *bound, node, typeDecl, bounds[0], bounds[1])
+					}
+				}
+			}
+
+			set.Min, set.Max = val.Min, val.Max
+
+		case "gslang.Pattern":
+
+			var val struct {
+				Regexp string
+			}
+
+			if err := DecodeAnnotation(annotation, &val); err != nil {
+				return nil, err
+			}
+
+			if _, err := regexp.Compile(val.Regexp); err != nil {
+				return nil, annotationErrorf(ErrConstraint, annotation, "constraint(%s) illegal regexp(%s) :%s", name, val.Regexp, err)
+			}
+
+			set.Pattern = val.Regexp
+
+		case "gslang.Length":
+
+			var val struct {
+				Min *uint32
+				Max *uint32
+			}
+
+			if err := DecodeAnnotation(annotation, &val); err != nil {
+				return nil, err
+			}
+
+			if val.Min != nil && val.Max != nil && *val.Min > *val.Max {
+				return nil, annotationErrorf(ErrConstraint, annotation, "constraint(%s) min(%d) greater than max(%d)", name, *val.Min, *val.Max)
+			}
+
+			if val.Max != nil && *val.Max == 0 {
+				return nil, annotationErrorf(ErrConstraint, annotation, "constraint(%s) max must be greater than 0", name)
+			}
+
+			if val.Min != nil && int(*val.Min) > set.MinLength {
+				set.MinLength = int(*val.Min)
+			}
+
+			if val.Max != nil && (set.MaxLength == 0 || int(*val.Max) < set.MaxLength) {
+				set.MaxLength = int(*val.Max)
+			}
+
+		case "gslang.NotEmpty":
+
+			if set.MinLength == 0 {
+				set.MinLength = 1
+			}
+		}
+	}
+
+	if set.MaxLength != 0 && set.MinLength > set.MaxLength {
+		return nil, annotationErrorf(ErrConstraint, node, "%s min length(%d) greater than max length(%d)", node, set.MinLength, set.MaxLength)
+	}
+
+	return set, nil
+}
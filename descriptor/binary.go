@@ -0,0 +1,712 @@
+package descriptor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsrpc/gslang/ast"
+)
+
+// binary descriptor set magic header
+var magic = []byte("GSDS")
+
+// errors
+var (
+	ErrMagic = errors.New("illegal binary descriptor magic header")
+	ErrHash  = errors.New("type content hash mismatch")
+)
+
+// Binary descriptor set layout, all integers are varint encoded:
+//
+//	magic "GSDS" | version | string table | module
+//
+// a type is encoded as kind, names, then the type params and fields lists, the enum underlying type, then the
+// constants, methods and union cases lists,
+// union case is name | tag | type | annotations | comment | span, method is name | id | return | stream mode
+// followed by the params, results and exceptions lists, exception is id | type | annotations | comment | span.
+//
+// the string table is a count followed by length prefixed utf8 strings, every string
+// in module is encoded as the index into the string table. optional values are
+// prefixed with one presence byte and lists are prefixed with the element count.
+
+type _Writer struct {
+	buff    bytes.Buffer   // output buffer
+	strings map[string]int // string table index, nil for inline strings
+	table   []string       // string table
+	source  bool           // write source position and comment
+}
+
+func (writer *_Writer) uvarint(val uint64) {
+	var buff [binary.MaxVarintLen64]byte
+
+	writer.buff.Write(buff[:binary.PutUvarint(buff[:], val)])
+}
+
+func (writer *_Writer) varint(val int64) {
+	var buff [binary.MaxVarintLen64]byte
+
+	writer.buff.Write(buff[:binary.PutVarint(buff[:], val)])
+}
+
+func (writer *_Writer) boolean(val bool) {
+	if val {
+		writer.buff.WriteByte(1)
+	} else {
+		writer.buff.WriteByte(0)
+	}
+}
+
+func (writer *_Writer) float(val float64) {
+	writer.uvarint(math.Float64bits(val))
+}
+
+func (writer *_Writer) string(val string) {
+
+	if writer.strings == nil {
+		writer.uvarint(uint64(len(val)))
+		writer.buff.WriteString(val)
+		return
+	}
+
+	index, ok := writer.strings[val]
+
+	if !ok {
+		index = len(writer.table)
+		writer.strings[val] = index
+		writer.table = append(writer.table, val)
+	}
+
+	writer.uvarint(uint64(index))
+}
+
+func (writer *_Writer) present(ok bool) bool {
+	writer.boolean(ok)
+
+	return ok
+}
+
+func (writer *_Writer) comment(comment string) {
+	if writer.source {
+		writer.string(comment)
+	}
+}
+
+func (writer *_Writer) span(span *Span) {
+
+	if !writer.source || !writer.present(span != nil) {
+		return
+	}
+
+	for _, pos := range []Position{span.Start, span.End} {
+		writer.string(pos.File)
+		writer.uvarint(uint64(pos.Line))
+		writer.uvarint(uint64(pos.Column))
+	}
+}
+
+func (writer *_Writer) module(module *Module) {
+
+	writer.string(module.Name)
+
+	writer.annotations(module.Annotations)
+
+	writer.uvarint(uint64(len(module.Scripts)))
+
+	for _, script := range module.Scripts {
+		writer.string(script.Name)
+		writer.string(script.Package)
+
+		writer.uvarint(uint64(len(script.Using)))
+
+		for _, using := range script.Using {
+			writer.string(using.Name)
+			writer.string(using.Ref)
+			writer.comment(using.Comment)
+			writer.span(using.Span)
+		}
+
+		writer.uvarint(uint64(len(script.Types)))
+
+		for _, typeDesc := range script.Types {
+			writer.typeDecl(typeDesc)
+			writer.string(typeDesc.Hash)
+		}
+
+		writer.annotations(script.Annotations)
+		writer.comment(script.Comment)
+		writer.span(script.Span)
+	}
+}
+
+func (writer *_Writer) typeDecl(desc *Type) {
+
+	writer.string(desc.Kind)
+	writer.string(desc.Name)
+	writer.string(desc.FullName)
+
+	writer.uvarint(uint64(len(desc.TypeParams)))
+
+	for _, param := range desc.TypeParams {
+		writer.string(param)
+	}
+
+	writer.uvarint(uint64(len(desc.Fields)))
+
+	for _, field := range desc.Fields {
+		writer.string(field.Name)
+		writer.typeRef(field.Type)
+		writer.annotations(field.Annotations)
+		writer.comment(field.Comment)
+		writer.span(field.Span)
+	}
+
+	writer.typeRef(desc.Underlying)
+
+	writer.uvarint(uint64(len(desc.Constants)))
+
+	for _, constant := range desc.Constants {
+		writer.string(constant.Name)
+		writer.varint(constant.Value)
+		writer.annotations(constant.Annotations)
+		writer.comment(constant.Comment)
+		writer.span(constant.Span)
+	}
+
+	writer.uvarint(uint64(len(desc.Methods)))
+
+	for _, method := range desc.Methods {
+		writer.string(method.Name)
+		writer.varint(int64(method.ID))
+		writer.typeRef(method.Return)
+		writer.string(method.Stream)
+
+		writer.params(method.Params)
+
+		writer.params(method.Results)
+
+		writer.uvarint(uint64(len(method.Exceptions)))
+
+		for _, exception := range method.Exceptions {
+			writer.varint(int64(exception.ID))
+			writer.typeRef(exception.Type)
+			writer.annotations(exception.Annotations)
+			writer.comment(exception.Comment)
+			writer.span(exception.Span)
+		}
+
+		writer.annotations(method.Annotations)
+		writer.comment(method.Comment)
+		writer.span(method.Span)
+	}
+
+	writer.uvarint(uint64(len(desc.Cases)))
+
+	for _, unionCase := range desc.Cases {
+		writer.string(unionCase.Name)
+		writer.uvarint(uint64(unionCase.Tag))
+		writer.typeRef(unionCase.Type)
+		writer.annotations(unionCase.Annotations)
+		writer.comment(unionCase.Comment)
+		writer.span(unionCase.Span)
+	}
+
+	writer.annotations(desc.Annotations)
+	writer.comment(desc.Comment)
+	writer.span(desc.Span)
+}
+
+func (writer *_Writer) params(params []*Param) {
+
+	writer.uvarint(uint64(len(params)))
+
+	for _, param := range params {
+		writer.string(param.Name)
+		writer.varint(int64(param.ID))
+		writer.typeRef(param.Type)
+		writer.boolean(param.Stream)
+		writer.annotations(param.Annotations)
+		writer.comment(param.Comment)
+		writer.span(param.Span)
+	}
+}
+
+func (writer *_Writer) typeRef(desc *TypeRef) {
+
+	if !writer.present(desc != nil) {
+		return
+	}
+
+	writer.string(desc.Kind)
+	writer.string(desc.Name)
+	writer.string(desc.Ref)
+	writer.typeRef(desc.Component)
+	writer.varint(int64(desc.Size))
+	writer.varint(int64(desc.Bound))
+
+	writer.uvarint(uint64(len(desc.TypeArgs)))
+
+	for _, arg := range desc.TypeArgs {
+		writer.typeRef(arg)
+	}
+
+	writer.span(desc.Span)
+}
+
+func (writer *_Writer) annotations(descs []*Annotation) {
+
+	writer.uvarint(uint64(len(descs)))
+
+	for _, desc := range descs {
+		writer.typeRef(desc.Type)
+		writer.expr(desc.Args)
+		writer.span(desc.Span)
+	}
+}
+
+func (writer *_Writer) expr(desc *Expr) {
+
+	if !writer.present(desc != nil) {
+		return
+	}
+
+	writer.string(desc.Kind)
+	writer.string(desc.String)
+	writer.float(desc.Numeric)
+	writer.boolean(desc.Boolean)
+	writer.string(desc.Name)
+	writer.string(desc.Ref)
+	writer.typeRef(desc.Type)
+	writer.boolean(desc.Named)
+
+	writer.uvarint(uint64(len(desc.Args)))
+
+	for _, arg := range desc.Args {
+		writer.expr(arg)
+	}
+
+	writer.string(desc.Op)
+	writer.expr(desc.Operand)
+	writer.expr(desc.LHS)
+	writer.expr(desc.RHS)
+
+	if writer.present(desc.Value != nil) {
+		writer.varint(*desc.Value)
+	}
+
+	writer.span(desc.Span)
+}
+
+type _Reader struct {
+	reader *bytes.Reader // input reader
+	table  []string      // string table
+}
+
+func (reader *_Reader) errorf(fmtstring string, args ...interface{}) {
+	gserrors.Panicf(ErrDescriptor, fmtstring, args...)
+}
+
+func (reader *_Reader) uvarint() uint64 {
+	val, err := binary.ReadUvarint(reader.reader)
+
+	if err != nil {
+		reader.errorf("read uvarint error :%s", err)
+	}
+
+	return val
+}
+
+func (reader *_Reader) varint() int64 {
+	val, err := binary.ReadVarint(reader.reader)
+
+	if err != nil {
+		reader.errorf("read varint error :%s", err)
+	}
+
+	return val
+}
+
+func (reader *_Reader) boolean() bool {
+	val, err := reader.reader.ReadByte()
+
+	if err != nil {
+		reader.errorf("read bool error :%s", err)
+	}
+
+	return val != 0
+}
+
+func (reader *_Reader) float() float64 {
+	return math.Float64frombits(reader.uvarint())
+}
+
+func (reader *_Reader) count() int {
+	count := reader.uvarint()
+
+	// every element takes one byte at least
+	if count > uint64(reader.reader.Len()) {
+		reader.errorf("illegal list length %d", count)
+	}
+
+	return int(count)
+}
+
+func (reader *_Reader) string() string {
+
+	index := reader.uvarint()
+
+	if index >= uint64(len(reader.table)) {
+		reader.errorf("string index(%d) out of range", index)
+	}
+
+	return reader.table[index]
+}
+
+func (reader *_Reader) span() *Span {
+
+	if !reader.boolean() {
+		return nil
+	}
+
+	var positions [2]Position
+
+	for i := range positions {
+		positions[i].File = reader.string()
+		positions[i].Line = int(reader.uvarint())
+		positions[i].Column = int(reader.uvarint())
+	}
+
+	return &Span{Start: positions[0], End: positions[1]}
+}
+
+func (reader *_Reader) module() *Module {
+
+	module := &Module{
+		Version:     Version,
+		Name:        reader.string(),
+		Annotations: reader.annotations(),
+	}
+
+	for i, count := 0, reader.count(); i < count; i++ {
+
+		script := &Script{
+			Name:    reader.string(),
+			Package: reader.string(),
+		}
+
+		for j, count := 0, reader.count(); j < count; j++ {
+			script.Using = append(script.Using, &Using{
+				Name:    reader.string(),
+				Ref:     reader.string(),
+				Comment: reader.string(),
+				Span:    reader.span(),
+			})
+		}
+
+		for j, count := 0, reader.count(); j < count; j++ {
+
+			typeDesc := reader.typeDecl()
+
+			typeDesc.Hash = reader.string()
+
+			if hash := Hash(typeDesc); hash != typeDesc.Hash {
+				gserrors.Panicf(ErrHash, "type(%s) content hash mismatch, expect %s got %s", typeDesc.FullName, typeDesc.Hash, hash)
+			}
+
+			script.Types = append(script.Types, typeDesc)
+		}
+
+		script.Annotations = reader.annotations()
+		script.Comment = reader.string()
+		script.Span = reader.span()
+
+		module.Scripts = append(module.Scripts, script)
+	}
+
+	return module
+}
+
+func (reader *_Reader) typeDecl() *Type {
+
+	desc := &Type{
+		Kind:     reader.string(),
+		Name:     reader.string(),
+		FullName: reader.string(),
+	}
+
+	for i, count := 0, reader.count(); i < count; i++ {
+		desc.TypeParams = append(desc.TypeParams, reader.string())
+	}
+
+	for i, count := 0, reader.count(); i < count; i++ {
+		desc.Fields = append(desc.Fields, &Field{
+			Name:        reader.string(),
+			Type:        reader.typeRef(),
+			Annotations: reader.annotations(),
+			Comment:     reader.string(),
+			Span:        reader.span(),
+		})
+	}
+
+	desc.Underlying = reader.typeRef()
+
+	for i, count := 0, reader.count(); i < count; i++ {
+		desc.Constants = append(desc.Constants, &EnumConstant{
+			Name:        reader.string(),
+			Value:       reader.varint(),
+			Annotations: reader.annotations(),
+			Comment:     reader.string(),
+			Span:        reader.span(),
+		})
+	}
+
+	for i, count := 0, reader.count(); i < count; i++ {
+
+		method := &Method{
+			Name:   reader.string(),
+			ID:     int(reader.varint()),
+			Return: reader.typeRef(),
+			Stream: reader.string(),
+		}
+
+		method.Params = reader.params()
+
+		method.Results = reader.params()
+
+		for j, count := 0, reader.count(); j < count; j++ {
+			method.Exceptions = append(method.Exceptions, &Exception{
+				ID:          int8(reader.varint()),
+				Type:        reader.typeRef(),
+				Annotations: reader.annotations(),
+				Comment:     reader.string(),
+				Span:        reader.span(),
+			})
+		}
+
+		method.Annotations = reader.annotations()
+		method.Comment = reader.string()
+		method.Span = reader.span()
+
+		desc.Methods = append(desc.Methods, method)
+	}
+
+	for i, count := 0, reader.count(); i < count; i++ {
+		desc.Cases = append(desc.Cases, &UnionCase{
+			Name:        reader.string(),
+			Tag:         int(reader.uvarint()),
+			Type:        reader.typeRef(),
+			Annotations: reader.annotations(),
+			Comment:     reader.string(),
+			Span:        reader.span(),
+		})
+	}
+
+	desc.Annotations = reader.annotations()
+	desc.Comment = reader.string()
+	desc.Span = reader.span()
+
+	return desc
+}
+
+func (reader *_Reader) params() (params []*Param) {
+
+	for i, count := 0, reader.count(); i < count; i++ {
+		params = append(params, &Param{
+			Name:        reader.string(),
+			ID:          int(reader.varint()),
+			Type:        reader.typeRef(),
+			Stream:      reader.boolean(),
+			Annotations: reader.annotations(),
+			Comment:     reader.string(),
+			Span:        reader.span(),
+		})
+	}
+
+	return
+}
+
+func (reader *_Reader) typeRef() *TypeRef {
+
+	if !reader.boolean() {
+		return nil
+	}
+
+	desc := &TypeRef{
+		Kind:      reader.string(),
+		Name:      reader.string(),
+		Ref:       reader.string(),
+		Component: reader.typeRef(),
+		Size:      int(reader.varint()),
+		Bound:     int(reader.varint()),
+	}
+
+	for i, count := 0, reader.count(); i < count; i++ {
+		desc.TypeArgs = append(desc.TypeArgs, reader.typeRef())
+	}
+
+	desc.Span = reader.span()
+
+	return desc
+}
+
+func (reader *_Reader) annotations() (descs []*Annotation) {
+
+	for i, count := 0, reader.count(); i < count; i++ {
+		descs = append(descs, &Annotation{
+			Type: reader.typeRef(),
+			Args: reader.expr(),
+			Span: reader.span(),
+		})
+	}
+
+	return
+}
+
+func (reader *_Reader) expr() *Expr {
+
+	if !reader.boolean() {
+		return nil
+	}
+
+	desc := &Expr{
+		Kind:    reader.string(),
+		String:  reader.string(),
+		Numeric: reader.float(),
+		Boolean: reader.boolean(),
+		Name:    reader.string(),
+		Ref:     reader.string(),
+		Type:    reader.typeRef(),
+		Named:   reader.boolean(),
+	}
+
+	for i, count := 0, reader.count(); i < count; i++ {
+		desc.Args = append(desc.Args, reader.expr())
+	}
+
+	desc.Op = reader.string()
+	desc.Operand = reader.expr()
+	desc.LHS = reader.expr()
+	desc.RHS = reader.expr()
+
+	if reader.boolean() {
+		val := reader.varint()
+		desc.Value = &val
+	}
+
+	desc.Span = reader.span()
+
+	return desc
+}
+
+// Hash compute type content hash, which excludes source positions and comments,
+// two types with the same hash have identical schema
+func Hash(desc *Type) string {
+
+	writer := &_Writer{}
+
+	writer.typeDecl(desc)
+
+	sum := sha256.Sum256(writer.buff.Bytes())
+
+	return hex.EncodeToString(sum[:])
+}
+
+// MarshalBinary implement encoding.BinaryMarshaler
+func (module *Module) MarshalBinary() ([]byte, error) {
+
+	writer := &_Writer{
+		strings: make(map[string]int),
+		source:  true,
+	}
+
+	writer.module(module)
+
+	body := writer.buff.Bytes()
+
+	header := &_Writer{}
+
+	header.buff.Write(magic)
+
+	header.uvarint(Version)
+
+	header.uvarint(uint64(len(writer.table)))
+
+	for _, val := range writer.table {
+		header.string(val)
+	}
+
+	header.buff.Write(body)
+
+	return header.buff.Bytes(), nil
+}
+
+// UnmarshalBinary implement encoding.BinaryUnmarshaler
+func (module *Module) UnmarshalBinary(data []byte) (err error) {
+
+	defer func() {
+		if e := recover(); e != nil {
+			gserr, ok := e.(gserrors.GSError)
+
+			if !ok {
+				panic(e)
+			}
+
+			err = gserr
+		}
+	}()
+
+	if !bytes.HasPrefix(data, magic) {
+		return ErrMagic
+	}
+
+	reader := &_Reader{
+		reader: bytes.NewReader(data[len(magic):]),
+	}
+
+	if version := reader.uvarint(); version != Version {
+		return gserrors.Newf(ErrVersion, "unsupport descriptor version(%d), expect %d", version, Version)
+	}
+
+	for i, count := 0, reader.count(); i < count; i++ {
+
+		length := reader.count()
+
+		buff := make([]byte, length)
+
+		if _, err := io.ReadFull(reader.reader, buff); err != nil {
+			reader.errorf("read string table error :%s", err)
+		}
+
+		reader.table = append(reader.table, string(buff))
+	}
+
+	*module = *reader.module()
+
+	if reader.reader.Len() != 0 {
+		reader.errorf("unexpect %d trailing bytes", reader.reader.Len())
+	}
+
+	return nil
+}
+
+// Encode encode linked module as binary descriptor set
+func Encode(module *ast.Module) ([]byte, error) {
+	return New(module).MarshalBinary()
+}
+
+// Decode decode binary descriptor set and reconstruct linked module
+func Decode(data []byte) (*ast.Module, error) {
+
+	module := &Module{}
+
+	if err := module.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return module.Module()
+}
@@ -0,0 +1,546 @@
+package descriptor
+
+import (
+	"github.com/gsdocker/gserrors"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+var builtinTypes = make(map[string]lexer.TokenType)
+
+var ops = make(map[string]lexer.TokenType)
+
+var streamModes = make(map[string]ast.StreamMode)
+
+func init() {
+	for _, token := range []lexer.TokenType{
+		lexer.KeyByte, lexer.KeySByte, lexer.KeyInt16, lexer.KeyUInt16,
+		lexer.KeyInt32, lexer.KeyUInt32, lexer.KeyInt64, lexer.KeyUInt64,
+		lexer.KeyFloat32, lexer.KeyFloat64, lexer.KeyString, lexer.KeyBool, lexer.KeyVoid,
+	} {
+		builtinTypes[token.String()] = token
+	}
+
+	for _, token := range []lexer.TokenType{lexer.OpBitOr, lexer.OpBitAnd, lexer.OpPlus, lexer.OpSub} {
+		ops[token.String()] = token
+	}
+
+	for _, mode := range []ast.StreamMode{ast.StreamClient, ast.StreamServer, ast.StreamBidi} {
+		streamModes[mode.String()] = mode
+	}
+}
+
+type _Decoder struct {
+	module      *ast.Module // reconstructed module
+	annotations []func()    // deferred annotations decoding
+	generic     *ast.Table  // decoding table, the field types may reference its type params
+}
+
+// Module reconstruct linked ast module from descriptor
+func (module *Module) Module() (target *ast.Module, err error) {
+
+	defer func() {
+		if e := recover(); e != nil {
+			gserr, ok := e.(gserrors.GSError)
+
+			if ok {
+				err = gserr
+			} else {
+				err = gserrors.Newf(ErrDescriptor, "illegal descriptor :%v", e)
+			}
+		}
+	}()
+
+	decoder := &_Decoder{
+		module: ast.NewModule(module.Name),
+	}
+
+	decoder.module.Types = make(map[string]ast.Type)
+
+	scripts := make(map[*Script]*ast.Script)
+
+	// create type declarations first, so the references can be resolved
+	for _, desc := range module.Scripts {
+		scripts[desc] = decoder.declare(desc)
+	}
+
+	for _, desc := range module.Scripts {
+		decoder.script(scripts[desc], desc)
+	}
+
+	decoder.attach(decoder.module, module.Annotations)
+
+	// annotation args may reference enum constants of any script
+	for _, f := range decoder.annotations {
+		f()
+	}
+
+	return decoder.module, nil
+}
+
+func (decoder *_Decoder) errorf(fmtstring string, args ...interface{}) {
+	gserrors.Panicf(ErrDescriptor, fmtstring, args...)
+}
+
+func (decoder *_Decoder) declare(desc *Script) *ast.Script {
+
+	script := decoder.module.NewScript(desc.Name)
+
+	script.Package = desc.Package
+
+	for _, typeDesc := range desc.Types {
+
+		var typeDecl ast.Type
+
+		switch typeDesc.Kind {
+		case KindTable:
+			typeDecl, _ = script.NewTable(typeDesc.Name)
+		case KindEnum:
+			typeDecl, _ = script.NewEnum(typeDesc.Name)
+		case KindContract:
+			typeDecl, _ = script.NewContract(typeDesc.Name)
+		case KindUnion:
+			typeDecl, _ = script.NewUnion(typeDesc.Name)
+		default:
+			decoder.errorf("unknown type(%s) kind(%s)", typeDesc.Name, typeDesc.Kind)
+		}
+
+		decoder.module.Types[typeDecl.FullName()] = typeDecl
+	}
+
+	return script
+}
+
+func (decoder *_Decoder) script(script *ast.Script, desc *Script) {
+
+	decorate(script, desc.Comment, desc.Span)
+
+	decoder.attach(script, desc.Annotations)
+
+	for _, usingDesc := range desc.Using {
+
+		using := script.Using(usingDesc.Name)
+
+		if usingDesc.Ref != "" {
+			using.Ref = decoder.lookup(usingDesc.Ref)
+		}
+
+		decorate(using, usingDesc.Comment, usingDesc.Span)
+	}
+
+	for _, typeDesc := range desc.Types {
+
+		typeDecl, _ := script.Type(typeDesc.Name)
+
+		decorate(typeDecl, typeDesc.Comment, typeDesc.Span)
+
+		decoder.attach(typeDecl, typeDesc.Annotations)
+
+		switch typeDecl.(type) {
+		case *ast.Table:
+			decoder.table(typeDecl.(*ast.Table), typeDesc)
+		case *ast.Enum:
+			decoder.enum(typeDecl.(*ast.Enum), typeDesc)
+		case *ast.Contract:
+			decoder.contract(typeDecl.(*ast.Contract), typeDesc)
+		case *ast.Union:
+			decoder.union(typeDecl.(*ast.Union), typeDesc)
+		}
+	}
+}
+
+func (decoder *_Decoder) table(table *ast.Table, desc *Type) {
+
+	for _, name := range desc.TypeParams {
+		if _, ok := table.NewTypeParam(name); !ok {
+			decoder.errorf("duplicate table(%s) type param(%s)", table, name)
+		}
+	}
+
+	decoder.generic = table
+
+	defer func() {
+		decoder.generic = nil
+	}()
+
+	for _, fieldDesc := range desc.Fields {
+
+		field, ok := table.NewField(fieldDesc.Name, decoder.typeRef(fieldDesc.Type))
+
+		if !ok {
+			decoder.errorf("duplicate table(%s) field(%s)", table, fieldDesc.Name)
+		}
+
+		decorate(field, fieldDesc.Comment, fieldDesc.Span)
+
+		decoder.attach(field, fieldDesc.Annotations)
+	}
+}
+
+func (decoder *_Decoder) enum(enum *ast.Enum, desc *Type) {
+
+	if desc.Underlying != nil {
+		enum.Underlying = decoder.typeRef(desc.Underlying)
+
+		enum.Underlying.SetParent(enum)
+	}
+
+	for _, constantDesc := range desc.Constants {
+
+		constant, ok := enum.NewConstant(constantDesc.Name)
+
+		if !ok {
+			decoder.errorf("duplicate enum(%s) constant(%s)", enum, constantDesc.Name)
+		}
+
+		constant.Value = constantDesc.Value
+
+		decorate(constant, constantDesc.Comment, constantDesc.Span)
+
+		decoder.attach(constant, constantDesc.Annotations)
+	}
+}
+
+func (decoder *_Decoder) union(union *ast.Union, desc *Type) {
+	for _, caseDesc := range desc.Cases {
+
+		unionCase, ok := union.NewCase(caseDesc.Name, decoder.typeRef(caseDesc.Type))
+
+		if !ok {
+			decoder.errorf("duplicate union(%s) case(%s)", union, caseDesc.Name)
+		}
+
+		unionCase.Tag = caseDesc.Tag
+
+		decorate(unionCase, caseDesc.Comment, caseDesc.Span)
+
+		decoder.attach(unionCase, caseDesc.Annotations)
+	}
+}
+
+func (decoder *_Decoder) contract(contract *ast.Contract, desc *Type) {
+	for _, methodDesc := range desc.Methods {
+
+		method, ok := contract.NewMethod(methodDesc.Name)
+
+		if !ok {
+			decoder.errorf("duplicate contract(%s) method(%s)", contract, methodDesc.Name)
+		}
+
+		method.ID = methodDesc.ID
+
+		if methodDesc.Return == nil {
+			decoder.errorf("method(%s) return type missing", method)
+		}
+
+		method.Return = decoder.typeRef(methodDesc.Return)
+
+		method.Return.SetParent(method)
+
+		if methodDesc.Stream != "" {
+			mode, ok := streamModes[methodDesc.Stream]
+
+			if !ok {
+				decoder.errorf("unknown method(%s) stream mode(%s)", method, methodDesc.Stream)
+			}
+
+			method.Stream = mode
+		}
+
+		for _, paramDesc := range methodDesc.Params {
+
+			param, ok := method.NewParam(paramDesc.Name, decoder.typeRef(paramDesc.Type))
+
+			if !ok {
+				decoder.errorf("duplicate method(%s) param(%s)", method, paramDesc.Name)
+			}
+
+			param.ID = paramDesc.ID
+
+			param.Stream = paramDesc.Stream
+
+			decorate(param, paramDesc.Comment, paramDesc.Span)
+
+			decoder.attach(param, paramDesc.Annotations)
+		}
+
+		for _, resultDesc := range methodDesc.Results {
+
+			result, ok := method.NewResult(resultDesc.Name, decoder.typeRef(resultDesc.Type))
+
+			if !ok {
+				decoder.errorf("duplicate method(%s) result(%s)", method, resultDesc.Name)
+			}
+
+			result.ID = resultDesc.ID
+
+			decorate(result, resultDesc.Comment, resultDesc.Span)
+
+			decoder.attach(result, resultDesc.Annotations)
+		}
+
+		for _, exceptionDesc := range methodDesc.Exceptions {
+
+			exception := method.NewException(decoder.typeRef(exceptionDesc.Type))
+
+			exception.ID = exceptionDesc.ID
+
+			decorate(exception, exceptionDesc.Comment, exceptionDesc.Span)
+
+			decoder.attach(exception, exceptionDesc.Annotations)
+		}
+
+		decorate(method, methodDesc.Comment, methodDesc.Span)
+
+		decoder.attach(method, methodDesc.Annotations)
+	}
+}
+
+func (decoder *_Decoder) lookup(fullname string) ast.Type {
+
+	typeDecl, ok := decoder.module.Types[fullname]
+
+	if !ok {
+		decoder.errorf("unknown type reference :%s", fullname)
+	}
+
+	return typeDecl
+}
+
+func (decoder *_Decoder) typeRef(desc *TypeRef) (typeDecl ast.Type) {
+
+	if desc == nil {
+		decoder.errorf("missing type reference")
+	}
+
+	switch desc.Kind {
+	case KindBuiltin:
+		builtin, ok := builtinTypes[desc.Name]
+
+		if !ok {
+			decoder.errorf("unknown builtin type :%s", desc.Name)
+		}
+
+		builtinType := ast.NewBuiltinType(builtin)
+
+		builtinType.Bound = desc.Bound
+
+		typeDecl = builtinType
+	case KindSeq:
+		seq := ast.NewSeq(decoder.typeRef(desc.Component), desc.Size)
+
+		seq.Bound = desc.Bound
+
+		typeDecl = seq
+	case KindOptional:
+		typeDecl = ast.NewOptional(decoder.typeRef(desc.Component))
+	case KindRef:
+		typeRef := ast.NewTypeRef(desc.Name)
+
+		if desc.Ref != "" {
+			typeRef.Ref = decoder.lookup(desc.Ref)
+		}
+
+		for _, arg := range desc.TypeArgs {
+			typeRef.NewTypeArg(decoder.typeRef(arg))
+		}
+
+		typeDecl = typeRef
+	case KindParam:
+		if decoder.generic == nil {
+			decoder.errorf("type param(%s) reference out of generic table", desc.Name)
+		}
+
+		param, ok := decoder.generic.TypeParam(desc.Name)
+
+		if !ok {
+			decoder.errorf("unknown table(%s) type param :%s", decoder.generic, desc.Name)
+		}
+
+		typeRef := ast.NewTypeRef(desc.Name)
+
+		typeRef.Ref = param
+
+		typeDecl = typeRef
+	default:
+		decoder.errorf("unknown type reference kind :%s", desc.Kind)
+	}
+
+	decorate(typeDecl, "", desc.Span)
+
+	return
+}
+
+func (decoder *_Decoder) attach(node ast.Node, descs []*Annotation) {
+
+	if len(descs) == 0 {
+		return
+	}
+
+	decoder.annotations = append(decoder.annotations, func() {
+
+		annotations := decoder.annotation(descs)
+
+		for _, annotation := range annotations {
+			annotation.SetParent(node)
+		}
+
+		node.SetExtra(gslang.ExtraAnnotation, annotations)
+	})
+}
+
+func (decoder *_Decoder) annotation(descs []*Annotation) []*ast.Annotation {
+
+	var annotations []*ast.Annotation
+
+	for _, desc := range descs {
+
+		if desc.Type == nil {
+			decoder.errorf("annotation type missing")
+		}
+
+		annotation := ast.NewAnnotation(desc.Type.Name)
+
+		typeRef, ok := decoder.typeRef(desc.Type).(*ast.TypeRef)
+
+		if !ok {
+			decoder.errorf("annotation(%s) type must be type reference", desc.Type.Name)
+		}
+
+		annotation.Type = typeRef
+
+		annotation.Type.SetParent(annotation)
+
+		if desc.Args != nil {
+			args, ok := decoder.expr(desc.Args).(*ast.ArgsTable)
+
+			if !ok {
+				decoder.errorf("annotation(%s) args must be args table", desc.Type.Name)
+			}
+
+			annotation.Args = args
+
+			args.SetParent(annotation)
+		}
+
+		decorate(annotation, "", desc.Span)
+
+		annotations = append(annotations, annotation)
+	}
+
+	return annotations
+}
+
+func (decoder *_Decoder) op(name string) lexer.TokenType {
+	token, ok := ops[name]
+
+	if !ok {
+		decoder.errorf("unknown op :%s", name)
+	}
+
+	return token
+}
+
+func (decoder *_Decoder) expr(desc *Expr) (expr ast.Expr) {
+
+	if desc == nil {
+		decoder.errorf("missing expr")
+	}
+
+	switch desc.Kind {
+	case KindString:
+		expr = ast.NewString(desc.String)
+	case KindNumeric:
+		if desc.Value != nil {
+			expr = ast.NewInteger(*desc.Value)
+		} else {
+			expr = ast.NewNumeric(desc.Numeric)
+		}
+	case KindBoolean:
+		expr = ast.NewBoolean(desc.Boolean)
+	case KindConstant:
+		constantRef := ast.NewConstantRef(desc.Name)
+
+		if desc.Ref != "" {
+			enumName, constantName := enumConstantName(desc.Ref)
+
+			enum, ok := decoder.lookup(enumName).(*ast.Enum)
+
+			if !ok {
+				decoder.errorf("constant(%s) reference type is not enum", desc.Ref)
+			}
+
+			constant, ok := enum.Constant(constantName)
+
+			if !ok {
+				decoder.errorf("unknown enum(%s) constant :%s", enumName, constantName)
+			}
+
+			constantRef.Value = constant
+		}
+
+		expr = constantRef
+	case KindNewObj:
+		var args *ast.ArgsTable
+
+		if len(desc.Args) > 0 {
+			var ok bool
+
+			args, ok = decoder.expr(desc.Args[0]).(*ast.ArgsTable)
+
+			if !ok {
+				decoder.errorf("newobj args must be args table")
+			}
+		}
+
+		typeRef, ok := decoder.typeRef(desc.Type).(*ast.TypeRef)
+
+		if !ok {
+			decoder.errorf("newobj type must be type reference")
+		}
+
+		expr = ast.NewNewObj2(typeRef, args)
+	case KindArgs:
+		args := ast.NewArgsTable(desc.Named)
+
+		for _, argDesc := range desc.Args {
+			if err := args.Append(decoder.expr(argDesc)); err != nil {
+				decoder.errorf("args table arg type error :%s", err)
+			}
+		}
+
+		expr = args
+	case KindNamed:
+		if len(desc.Args) != 1 {
+			decoder.errorf("named arg(%s) expect one value", desc.Name)
+		}
+
+		expr = ast.NewNamedArg(desc.Name, decoder.expr(desc.Args[0]))
+	case KindUnary:
+		expr = ast.NewUnaryOp(decoder.op(desc.Op), decoder.expr(desc.Operand))
+	case KindBinary:
+		expr = ast.NewBinaryOp(decoder.op(desc.Op), decoder.expr(desc.LHS), decoder.expr(desc.RHS))
+	default:
+		decoder.errorf("unknown expr kind :%s", desc.Kind)
+	}
+
+	decorate(expr, "", desc.Span)
+
+	return
+}
+
+// decorate restore node comment and source position
+func decorate(node ast.Node, text string, span *Span) {
+
+	if text != "" {
+		comment := ast.NewComment()
+		comment.Append(text)
+		node.SetExtra(gslang.ExtraComment, comment)
+	}
+
+	if span != nil {
+		node.SetExtra(gslang.ExtraStartPos, lexer.Position{FileName: span.Start.File, Lines: span.Start.Line, Column: span.Start.Column})
+		node.SetExtra(gslang.ExtraEndPos, lexer.Position{FileName: span.End.File, Lines: span.End.Line, Column: span.End.Column})
+	}
+}
@@ -0,0 +1,247 @@
+// Package descriptor define a stable, versioned schema descriptor of linked gslang module,
+// which can be consumed by generators not written in golang
+package descriptor
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsrpc/gslang/ast"
+)
+
+// Version descriptor format version, version 2 adds union types, version 3 adds generic tables,
+// version 4 adds method results, version 5 adds streaming methods, version 6 adds string and seq bounds,
+// version 7 adds enum underlying types, version 8 adds exception comments
+const Version = 8
+
+// errors
+var (
+	ErrVersion    = errors.New("unsupport descriptor version")
+	ErrDescriptor = errors.New("illegal descriptor")
+)
+
+// Type kinds
+const (
+	KindTable    = "table"
+	KindEnum     = "enum"
+	KindContract = "contract"
+	KindUnion    = "union"
+)
+
+// TypeRef kinds
+const (
+	KindBuiltin  = "builtin"
+	KindRef      = "ref"
+	KindSeq      = "seq"
+	KindOptional = "optional"
+	KindParam    = "param" // generic table type param
+)
+
+// Expr kinds
+const (
+	KindString   = "string"
+	KindNumeric  = "numeric"
+	KindBoolean  = "bool"
+	KindConstant = "constant"
+	KindNewObj   = "newobj"
+	KindArgs     = "args"
+	KindNamed    = "named"
+	KindUnary    = "unary"
+	KindBinary   = "binary"
+)
+
+// Position source code position
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Span source code range
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Module linked module descriptor
+type Module struct {
+	Version     int           `json:"version"`
+	Name        string        `json:"name"`
+	Scripts     []*Script     `json:"scripts"`
+	Annotations []*Annotation `json:"annotations,omitempty"`
+}
+
+// Script script descriptor
+type Script struct {
+	Name        string        `json:"name"`
+	Package     string        `json:"package"`
+	Using       []*Using      `json:"using,omitempty"`
+	Types       []*Type       `json:"types,omitempty"`
+	Annotations []*Annotation `json:"annotations,omitempty"`
+	Comment     string        `json:"comment,omitempty"`
+	Span        *Span         `json:"span,omitempty"`
+}
+
+// Using using instruction descriptor
+type Using struct {
+	Name    string `json:"name"`
+	Ref     string `json:"ref,omitempty"` // referenced type full name
+	Comment string `json:"comment,omitempty"`
+	Span    *Span  `json:"span,omitempty"`
+}
+
+// Type table/enum/contract/union descriptor
+type Type struct {
+	Kind        string          `json:"kind"`
+	Name        string          `json:"name"`
+	FullName    string          `json:"fullName"`
+	Hash        string          `json:"hash"` // content hash, see Hash
+	TypeParams  []string        `json:"typeParams,omitempty"`
+	Fields      []*Field        `json:"fields,omitempty"`
+	Underlying  *TypeRef        `json:"underlying,omitempty"` // enum declared underlying type
+	Constants   []*EnumConstant `json:"constants,omitempty"`
+	Methods     []*Method       `json:"methods,omitempty"`
+	Cases       []*UnionCase    `json:"cases,omitempty"`
+	Annotations []*Annotation   `json:"annotations,omitempty"`
+	Comment     string          `json:"comment,omitempty"`
+	Span        *Span           `json:"span,omitempty"`
+}
+
+// TypeRef type expression descriptor
+type TypeRef struct {
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name,omitempty"`      // builtin type name or reference name as written
+	Ref       string     `json:"ref,omitempty"`       // referenced type full name
+	Component *TypeRef   `json:"component,omitempty"` // seq or optional component type
+	Size      int        `json:"size,omitempty"`      // seq size
+	Bound     int        `json:"bound,omitempty"`     // string or variable seq max length
+	TypeArgs  []*TypeRef `json:"typeArgs,omitempty"`  // generic table reference type args
+	Span      *Span      `json:"span,omitempty"`
+}
+
+// Field table field descriptor
+type Field struct {
+	Name        string        `json:"name"`
+	Type        *TypeRef      `json:"type"`
+	Annotations []*Annotation `json:"annotations,omitempty"`
+	Comment     string        `json:"comment,omitempty"`
+	Span        *Span         `json:"span,omitempty"`
+}
+
+// EnumConstant enum constant descriptor
+type EnumConstant struct {
+	Name        string        `json:"name"`
+	Value       int64         `json:"value"`
+	Annotations []*Annotation `json:"annotations,omitempty"`
+	Comment     string        `json:"comment,omitempty"`
+	Span        *Span         `json:"span,omitempty"`
+}
+
+// UnionCase union case descriptor
+type UnionCase struct {
+	Name        string        `json:"name"`
+	Tag         int           `json:"tag"`
+	Type        *TypeRef      `json:"type"`
+	Annotations []*Annotation `json:"annotations,omitempty"`
+	Comment     string        `json:"comment,omitempty"`
+	Span        *Span         `json:"span,omitempty"`
+}
+
+// Method contract method descriptor
+type Method struct {
+	Name        string        `json:"name"`
+	ID          int           `json:"id"`
+	Return      *TypeRef      `json:"return"`
+	Stream      string        `json:"stream,omitempty"` // streaming mode : client, server or bidi
+	Params      []*Param      `json:"params,omitempty"`
+	Results     []*Param      `json:"results,omitempty"`
+	Exceptions  []*Exception  `json:"exceptions,omitempty"`
+	Annotations []*Annotation `json:"annotations,omitempty"`
+	Comment     string        `json:"comment,omitempty"`
+	Span        *Span         `json:"span,omitempty"`
+}
+
+// Param method param or result descriptor
+type Param struct {
+	Name        string        `json:"name"`
+	ID          int           `json:"id"`
+	Type        *TypeRef      `json:"type"`
+	Stream      bool          `json:"stream,omitempty"`
+	Annotations []*Annotation `json:"annotations,omitempty"`
+	Comment     string        `json:"comment,omitempty"`
+	Span        *Span         `json:"span,omitempty"`
+}
+
+// Exception method exception descriptor
+type Exception struct {
+	ID          int8          `json:"id"`
+	Type        *TypeRef      `json:"type"`
+	Annotations []*Annotation `json:"annotations,omitempty"`
+	Comment     string        `json:"comment,omitempty"`
+	Span        *Span         `json:"span,omitempty"`
+}
+
+// Annotation annotation descriptor
+type Annotation struct {
+	Type *TypeRef `json:"type"`
+	Args *Expr    `json:"args,omitempty"`
+	Span *Span    `json:"span,omitempty"`
+}
+
+// Expr compile time expression descriptor
+type Expr struct {
+	Kind    string   `json:"kind"`
+	String  string   `json:"string,omitempty"`  // string literal
+	Numeric float64  `json:"numeric,omitempty"` // numeric literal
+	Boolean bool     `json:"bool,omitempty"`    // boolean literal
+	Name    string   `json:"name,omitempty"`    // constant reference or named arg name
+	Ref     string   `json:"ref,omitempty"`     // referenced enum constant full name
+	Type    *TypeRef `json:"type,omitempty"`    // newobj type
+	Named   bool     `json:"named,omitempty"`   // named args table flag
+	Args    []*Expr  `json:"args,omitempty"`    // args table args or named arg value
+	Op      string   `json:"op,omitempty"`      // unary/binary op
+	Operand *Expr    `json:"operand,omitempty"` // unary op operand
+	LHS     *Expr    `json:"lhs,omitempty"`     // binary op lhs
+	RHS     *Expr    `json:"rhs,omitempty"`     // binary op rhs
+	Value   *int64   `json:"value,omitempty"`   // exact value of integer literal, or evaluated integer value of constant/unary/binary expr
+	Span    *Span    `json:"span,omitempty"`
+}
+
+// Write write descriptor as indented json
+func (module *Module) Write(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(module)
+}
+
+// Read read json descriptor
+func Read(reader io.Reader) (*Module, error) {
+
+	module := &Module{}
+
+	if err := json.NewDecoder(reader).Decode(module); err != nil {
+		return nil, err
+	}
+
+	if module.Version != Version {
+		return nil, gserrors.Newf(ErrVersion, "unsupport descriptor version(%d), expect %d", module.Version, Version)
+	}
+
+	return module, nil
+}
+
+// Load load json descriptor and reconstruct linked ast module
+func Load(reader io.Reader) (*ast.Module, error) {
+
+	module, err := Read(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return module.Module()
+}
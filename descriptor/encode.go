@@ -0,0 +1,391 @@
+package descriptor
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+)
+
+type _Encoder struct {
+	enums  map[*ast.EnumConstant]*ast.Enum // enum constant owner
+	eval   gslang.Eval                     // compile time eval of integer exprs
+	failed bool                            // last eval failed flag
+}
+
+// New create descriptor of linked module
+func New(module *ast.Module) *Module {
+
+	encoder := &_Encoder{
+		enums: make(map[*ast.EnumConstant]*ast.Enum),
+	}
+
+	encoder.eval = gslang.NewEval(gslang.HandleError(func(err *gslang.Error) {
+		encoder.failed = true
+	}), module)
+
+	for _, typeDecl := range module.Types {
+		if enum, ok := typeDecl.(*ast.Enum); ok {
+			for _, constant := range enum.Constants {
+				encoder.enums[constant] = enum
+			}
+		}
+	}
+
+	desc := &Module{
+		Version:     Version,
+		Name:        module.Name(),
+		Annotations: encoder.annotations(module),
+	}
+
+	module.Foreach(func(script *ast.Script) bool {
+		desc.Scripts = append(desc.Scripts, encoder.script(script))
+		return true
+	})
+
+	sort.Slice(desc.Scripts, func(i, j int) bool {
+		return desc.Scripts[i].Name < desc.Scripts[j].Name
+	})
+
+	return desc
+}
+
+func span(node ast.Node) *Span {
+
+	start, end := gslang.Pos(node)
+
+	if !start.Valid() {
+		return nil
+	}
+
+	return &Span{
+		Start: Position{File: start.FileName, Line: start.Lines, Column: start.Column},
+		End:   Position{File: end.FileName, Line: end.Lines, Column: end.Column},
+	}
+}
+
+func comment(node ast.Node) string {
+
+	val, ok := node.GetExtra(gslang.ExtraComment)
+
+	if !ok {
+		return ""
+	}
+
+	return val.(*ast.Comment).String()
+}
+
+func (encoder *_Encoder) script(script *ast.Script) *Script {
+
+	desc := &Script{
+		Name:        script.Name(),
+		Package:     script.Package,
+		Annotations: encoder.annotations(script),
+		Comment:     comment(script),
+		Span:        span(script),
+	}
+
+	script.UsingForeach(func(using *ast.Using) {
+
+		usingDesc := &Using{
+			Name:    using.Name(),
+			Comment: comment(using),
+			Span:    span(using),
+		}
+
+		if using.Ref != nil {
+			usingDesc.Ref = using.Ref.FullName()
+		}
+
+		desc.Using = append(desc.Using, usingDesc)
+	})
+
+	sort.Slice(desc.Using, func(i, j int) bool {
+		return desc.Using[i].Name < desc.Using[j].Name
+	})
+
+	script.TypeForeach(func(typeDecl ast.Type) {
+		desc.Types = append(desc.Types, encoder.typeDecl(typeDecl))
+	})
+
+	sort.Slice(desc.Types, func(i, j int) bool {
+		return desc.Types[i].Name < desc.Types[j].Name
+	})
+
+	return desc
+}
+
+func (encoder *_Encoder) typeDecl(typeDecl ast.Type) *Type {
+
+	desc := &Type{
+		Name:        typeDecl.Name(),
+		FullName:    typeDecl.FullName(),
+		Annotations: encoder.annotations(typeDecl),
+		Comment:     comment(typeDecl),
+		Span:        span(typeDecl),
+	}
+
+	switch typeDecl.(type) {
+	case *ast.Table:
+		desc.Kind = KindTable
+
+		for _, param := range typeDecl.(*ast.Table).TypeParams {
+			desc.TypeParams = append(desc.TypeParams, param.Name())
+		}
+
+		for _, field := range typeDecl.(*ast.Table).Fields {
+			desc.Fields = append(desc.Fields, &Field{
+				Name:        field.Name(),
+				Type:        encoder.typeRef(field.Type),
+				Annotations: encoder.annotations(field),
+				Comment:     comment(field),
+				Span:        span(field),
+			})
+		}
+
+	case *ast.Enum:
+		desc.Kind = KindEnum
+		desc.Underlying = encoder.typeRef(typeDecl.(*ast.Enum).Underlying)
+
+		for _, constant := range typeDecl.(*ast.Enum).Constants {
+			desc.Constants = append(desc.Constants, &EnumConstant{
+				Name:        constant.Name(),
+				Value:       constant.Value,
+				Annotations: encoder.annotations(constant),
+				Comment:     comment(constant),
+				Span:        span(constant),
+			})
+		}
+
+	case *ast.Contract:
+		desc.Kind = KindContract
+
+		for _, method := range typeDecl.(*ast.Contract).Methods {
+			desc.Methods = append(desc.Methods, encoder.method(method))
+		}
+
+	case *ast.Union:
+		desc.Kind = KindUnion
+
+		for _, unionCase := range typeDecl.(*ast.Union).Cases {
+			desc.Cases = append(desc.Cases, &UnionCase{
+				Name:        unionCase.Name(),
+				Tag:         unionCase.Tag,
+				Type:        encoder.typeRef(unionCase.Type),
+				Annotations: encoder.annotations(unionCase),
+				Comment:     comment(unionCase),
+				Span:        span(unionCase),
+			})
+		}
+	}
+
+	desc.Hash = Hash(desc)
+
+	return desc
+}
+
+func (encoder *_Encoder) method(method *ast.Method) *Method {
+
+	desc := &Method{
+		Name:        method.Name(),
+		ID:          method.ID,
+		Return:      encoder.typeRef(method.Return),
+		Annotations: encoder.annotations(method),
+		Comment:     comment(method),
+		Span:        span(method),
+	}
+
+	if method.Stream != 0 {
+		desc.Stream = method.Stream.String()
+	}
+
+	for _, param := range method.Params {
+		desc.Params = append(desc.Params, encoder.param(param))
+	}
+
+	for _, result := range method.Results {
+		desc.Results = append(desc.Results, encoder.param(result))
+	}
+
+	for _, exception := range method.Exceptions {
+		desc.Exceptions = append(desc.Exceptions, &Exception{
+			ID:          exception.ID,
+			Type:        encoder.typeRef(exception.Type),
+			Annotations: encoder.annotations(exception),
+			Comment:     comment(exception),
+			Span:        span(exception),
+		})
+	}
+
+	return desc
+}
+
+func (encoder *_Encoder) param(param *ast.Param) *Param {
+	return &Param{
+		Name:        param.Name(),
+		ID:          param.ID,
+		Type:        encoder.typeRef(param.Type),
+		Stream:      param.Stream,
+		Annotations: encoder.annotations(param),
+		Comment:     comment(param),
+		Span:        span(param),
+	}
+}
+
+func (encoder *_Encoder) typeRef(typeDecl ast.Type) *TypeRef {
+
+	if typeDecl == nil {
+		return nil
+	}
+
+	desc := &TypeRef{
+		Span: span(typeDecl),
+	}
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		desc.Kind = KindBuiltin
+		desc.Name = typeDecl.Name()
+		desc.Bound = typeDecl.(*ast.BuiltinType).Bound
+	case *ast.Seq:
+		desc.Kind = KindSeq
+		desc.Component = encoder.typeRef(typeDecl.(*ast.Seq).Component)
+		desc.Size = typeDecl.(*ast.Seq).Size
+		desc.Bound = typeDecl.(*ast.Seq).Bound
+	case *ast.Optional:
+		desc.Kind = KindOptional
+		desc.Component = encoder.typeRef(typeDecl.(*ast.Optional).Component)
+	case *ast.TypeRef:
+		desc.Kind = KindRef
+		desc.Name = typeDecl.Name()
+
+		switch ref := typeDecl.(*ast.TypeRef).Ref; ref.(type) {
+		case nil:
+		case *ast.TypeParam:
+			desc.Kind = KindParam
+		default:
+			desc.Ref = ref.FullName()
+		}
+
+		for _, arg := range typeDecl.(*ast.TypeRef).TypeArgs {
+			desc.TypeArgs = append(desc.TypeArgs, encoder.typeRef(arg))
+		}
+	default:
+		// type declaration referenced directly
+		desc.Kind = KindRef
+		desc.Name = typeDecl.FullName()
+		desc.Ref = typeDecl.FullName()
+	}
+
+	return desc
+}
+
+func (encoder *_Encoder) annotations(node ast.Node) (descs []*Annotation) {
+
+	for _, annotation := range gslang.Annotations(node) {
+
+		desc := &Annotation{
+			Type: encoder.typeRef(annotation.Type),
+			Span: span(annotation),
+		}
+
+		if annotation.Args != nil {
+			desc.Args = encoder.expr(annotation.Args)
+		}
+
+		descs = append(descs, desc)
+	}
+
+	return
+}
+
+func (encoder *_Encoder) expr(expr ast.Expr) *Expr {
+
+	desc := &Expr{
+		Span: span(expr),
+	}
+
+	switch expr.(type) {
+	case *ast.String:
+		desc.Kind = KindString
+		desc.String = expr.Name()
+	case *ast.Numeric:
+		desc.Kind = KindNumeric
+		desc.Numeric = expr.(*ast.Numeric).Val
+
+		if numeric := expr.(*ast.Numeric); numeric.Integer {
+			desc.Value = &numeric.Int
+		}
+	case *ast.Boolean:
+		desc.Kind = KindBoolean
+		desc.Boolean = expr.(*ast.Boolean).Val
+	case *ast.ConstantRef:
+		desc.Kind = KindConstant
+		desc.Name = expr.Name()
+
+		if constant, ok := expr.(*ast.ConstantRef).Value.(*ast.EnumConstant); ok {
+			if enum, ok := encoder.enums[constant]; ok {
+				desc.Ref = enum.FullName() + "." + constant.Name()
+			}
+		}
+	case *ast.NewObj:
+		desc.Kind = KindNewObj
+		desc.Type = encoder.typeRef(expr.(*ast.NewObj).Type)
+
+		if args := expr.(*ast.NewObj).Args; args != nil {
+			desc.Args = []*Expr{encoder.expr(args)}
+		}
+	case *ast.ArgsTable:
+		desc.Kind = KindArgs
+		desc.Named = expr.(*ast.ArgsTable).Named
+
+		for _, arg := range expr.(*ast.ArgsTable).Args() {
+			desc.Args = append(desc.Args, encoder.expr(arg))
+		}
+	case *ast.NamedArg:
+		desc.Kind = KindNamed
+		desc.Name = expr.Name()
+		desc.Args = []*Expr{encoder.expr(expr.(*ast.NamedArg).Arg)}
+	case *ast.UnaryOp:
+		desc.Kind = KindUnary
+		desc.Op = expr.(*ast.UnaryOp).Token.String()
+		desc.Operand = encoder.expr(expr.(*ast.UnaryOp).Operand)
+	case *ast.BinaryOp:
+		desc.Kind = KindBinary
+		desc.Op = expr.(*ast.BinaryOp).Token.String()
+		desc.LHS = encoder.expr(expr.(*ast.BinaryOp).LHS)
+		desc.RHS = encoder.expr(expr.(*ast.BinaryOp).RHS)
+	}
+
+	switch expr.(type) {
+	case *ast.ConstantRef, *ast.UnaryOp, *ast.BinaryOp:
+		if val, ok := encoder.evalInt(expr); ok {
+			desc.Value = &val
+		}
+	}
+
+	return desc
+}
+
+// evalInt eval integer value of linked constant expression, the eval errors are not reported
+func (encoder *_Encoder) evalInt(expr ast.Expr) (int64, bool) {
+
+	encoder.failed = false
+
+	val := encoder.eval.EvalInt(expr)
+
+	return val, !encoder.failed
+}
+
+// enumConstantName split enum constant full name into enum full name and constant name
+func enumConstantName(fullname string) (string, string) {
+
+	index := strings.LastIndex(fullname, ".")
+
+	if index == -1 {
+		return "", fullname
+	}
+
+	return fullname[:index], fullname[index+1:]
+}
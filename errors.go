@@ -21,4 +21,10 @@ var (
 	ErrType = errors.New("illegal type")
 
 	ErrEval = errors.New("compile time eval error")
+
+	ErrDecode = errors.New("annotation decode error")
+
+	ErrDeprecated = errors.New("deprecated reference")
+
+	ErrConstraint = errors.New("illegal validation constraint")
 )
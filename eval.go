@@ -2,6 +2,7 @@ package gslang
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/gsrpc/gslang/ast"
 	"github.com/gsrpc/gslang/lexer"
@@ -12,7 +13,7 @@ import (
 type Eval interface {
 	EvalInt(expr ast.Expr) int64
 	EvalString(expr ast.Expr) string
-	EvalEnumConstant(name string, constant string) int32
+	EvalEnumConstant(name string, constant string) int64
 	GetType(name string) (ast.Type, bool)
 }
 
@@ -22,7 +23,8 @@ type _Eval struct {
 	errorHandler ErrorHandler // error handlers
 }
 
-func newEval(errorHandler ErrorHandler, module *ast.Module) Eval {
+// NewEval create compile time eval of linked module
+func NewEval(errorHandler ErrorHandler, module *ast.Module) Eval {
 	return &_Eval{
 		Log:          gslogger.Get("eval"),
 		module:       module,
@@ -60,7 +62,7 @@ func (eval *_Eval) EvalString(expr ast.Expr) string {
 	return stringConstant.Name()
 }
 
-func (eval *_Eval) EvalEnumConstant(name string, constantName string) int32 {
+func (eval *_Eval) EvalEnumConstant(name string, constantName string) int64 {
 	target, ok := eval.GetType(name)
 
 	if !ok {
@@ -91,7 +93,21 @@ func (eval *_Eval) EvalInt(expr ast.Expr) int64 {
 	case *ast.ConstantRef:
 		return eval.EvalInt(expr.(*ast.ConstantRef).Value)
 	case *ast.EnumConstant:
-		return int64(expr.(*ast.EnumConstant).Value)
+		return expr.(*ast.EnumConstant).Value
+	case *ast.Numeric:
+		if numeric := expr.(*ast.Numeric); numeric.Integer {
+			return numeric.Int
+		}
+
+		val := expr.(*ast.Numeric).Val
+
+		// float64(math.MaxInt64) is 2^63, which overflows int64
+		if val != math.Trunc(val) || val < math.MinInt64 || val >= math.MaxInt64 {
+			eval.errorf(ErrEval, expr, "can't eval expr(%s) as int64 : not integer", expr)
+			return 0
+		}
+
+		return int64(val)
 	case *ast.BinaryOp:
 		binary := expr.(*ast.BinaryOp)
 
@@ -10,7 +10,7 @@ const (
 	ExtraStartPos   = "start"
 	ExtraEndPos     = "end"
 	ExtraComment    = "comment"
-	ExtraAnnotation = "annotation"
+	ExtraAnnotation = ast.ExtraAnnotation
 )
 
 func _setNodePos(node ast.Node, start lexer.Position, end lexer.Position) {
@@ -39,7 +39,24 @@ func _AttachAnnotation(node ast.Node, annotations ...*ast.Annotation) {
 
 	anns := Annotations(node)
 
-	anns = append(anns, annotations...)
+	// relinking moves the same script and module annotations again
+	for _, annotation := range annotations {
+
+		attached := false
+
+		for _, ann := range anns {
+			if ann == annotation {
+				attached = true
+				break
+			}
+		}
+
+		if !attached {
+			anns = append(anns, annotation)
+		}
+
+		annotation.SetParent(node)
+	}
 
 	node.SetExtra(ExtraAnnotation, anns)
 }
@@ -69,7 +86,7 @@ func _RemoveAnnotation(node ast.Node, annotation *ast.Annotation) {
 		}
 		newanns = append(newanns, ann)
 	}
-	node.SetExtra(ExtraAnnotation, anns)
+	node.SetExtra(ExtraAnnotation, newanns)
 }
 
 // FindAnnotation .
@@ -137,26 +154,42 @@ func Pos(node ast.Node) (start lexer.Position, end lexer.Position) {
 
 // EnumSize get enum binary size
 func EnumSize(typeDecl ast.Type) int {
-	_, ok := FindAnnotation(typeDecl, "gslang.Flag")
-
-	if ok {
+	switch EnumType(typeDecl) {
+	case lexer.KeyInt16, lexer.KeyUInt16:
+		return 2
+	case lexer.KeyInt32, lexer.KeyUInt32:
 		return 4
+	case lexer.KeyInt64, lexer.KeyUInt64:
+		return 8
 	}
 
 	return 1
 }
 
-// EnumType get enum token type
+// EnumType get enum token type, which is the declared underlying type, the flag enum defaults to uint32 and
+// the others default to byte
 func EnumType(typeDecl ast.Type) lexer.TokenType {
-	_, ok := FindAnnotation(typeDecl, "gslang.Flag")
 
-	if ok {
+	if enum, ok := typeDecl.(*ast.Enum); ok && enum.Underlying != nil {
+		if builtin, ok := enum.Underlying.(*ast.BuiltinType); ok {
+			return builtin.Type
+		}
+	}
+
+	if IsFlag(typeDecl) {
 		return lexer.KeyUInt32
 	}
 
 	return lexer.KeyByte
 }
 
+// IsFlag check if target enum is marked by gslang.Flag, which values are bit combinations
+func IsFlag(typeDecl ast.Type) bool {
+	_, ok := FindAnnotation(typeDecl, "gslang.Flag")
+
+	return ok
+}
+
 // IsBuiltin check if target type is builtin type
 func IsBuiltin(typeDecl ast.Type) bool {
 	_, ok := typeDecl.(*ast.BuiltinType)
@@ -202,6 +235,142 @@ func IsAsync(method *ast.Method) bool {
 	return false
 }
 
+// ServiceName get contract service name declared by gslang.Service, defaults to the contract full name
+func ServiceName(contract *ast.Contract) string {
+
+	if service, ok := serviceInfo(contract); ok && service.Name != nil {
+		return *service.Name
+	}
+
+	return contract.FullName()
+}
+
+// ServiceVersion get contract version declared by gslang.Service, defaults to 1
+func ServiceVersion(contract *ast.Contract) uint32 {
+
+	if service, ok := serviceInfo(contract); ok && service.Version != nil {
+		return *service.Version
+	}
+
+	return 1
+}
+
+type _ServiceInfo struct {
+	Name    *string
+	Version *uint32
+}
+
+func serviceInfo(contract *ast.Contract) (service _ServiceInfo, ok bool) {
+
+	annotation, ok := FindAnnotation(contract, "gslang.Service")
+
+	// the illegal args are reported by linker
+	if !ok || DecodeAnnotation(annotation, &service) != nil {
+		return service, false
+	}
+
+	return service, true
+}
+
+// MethodID get method id declared by gslang.MethodID, defaults to the method declaration order id
+func MethodID(method *ast.Method) int {
+
+	annotation, ok := FindAnnotation(method, "gslang.MethodID")
+
+	if !ok {
+		return method.ID
+	}
+
+	var val struct {
+		ID *uint16
+	}
+
+	if DecodeAnnotation(annotation, &val) != nil || val.ID == nil {
+		return method.ID
+	}
+
+	return int(*val.ID)
+}
+
+// IsStream check if target method is streaming method
+func IsStream(method *ast.Method) bool {
+	return method.Stream != 0
+}
+
+// IsOptional check if target type is optional type
+func IsOptional(typeDecl ast.Type) bool {
+	_, ok := typeDecl.(*ast.Optional)
+
+	return ok
+}
+
+// MaxLength get the max length of bounded string or seq type, the optional type is unwrapped,
+// fixed-size seq's max length is its size
+func MaxLength(typeDecl ast.Type) (int, bool) {
+
+	if optional, ok := typeDecl.(*ast.Optional); ok {
+		typeDecl = optional.Component
+	}
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtin := typeDecl.(*ast.BuiltinType)
+
+		return builtin.Bound, builtin.Bound > 0
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if seq.Size > 0 {
+			return seq.Size, true
+		}
+
+		return seq.Bound, seq.Bound > 0
+	}
+
+	return 0, false
+}
+
+// IsGeneric check if target type is generic table declaration
+func IsGeneric(typeDecl ast.Type) bool {
+	table, ok := typeDecl.(*ast.Table)
+
+	return ok && len(table.TypeParams) != 0
+}
+
+// Instantiate get the concrete table of linked generic table reference, e.g. Page<User>,
+// returns false if the reference isn't generic table instantiation
+func Instantiate(typeRef *ast.TypeRef) (*ast.Table, bool) {
+
+	table, ok := typeRef.Ref.(*ast.Table)
+
+	if !ok || len(table.TypeParams) == 0 || len(table.TypeParams) != len(typeRef.TypeArgs) {
+		return nil, false
+	}
+
+	return table.Instantiate(typeRef.TypeArgs), true
+}
+
+// IsDeprecated check if target node is marked by gslang.Deprecated
+func IsDeprecated(node ast.Node) bool {
+	_, ok := FindAnnotation(node, "gslang.Deprecated")
+
+	return ok
+}
+
+// IsRepeatable check if target annotation type can be applied more than once to the same node
+func IsRepeatable(typeDecl ast.Type) bool {
+	_, ok := FindAnnotation(typeDecl, "gslang.annotations.Repeatable")
+
+	return ok
+}
+
+// IsRequired check if target table field must be set by the table instances
+func IsRequired(field *ast.Field) bool {
+	_, ok := FindAnnotation(field, "gslang.annotations.Required")
+
+	return ok
+}
+
 // IsException check if target type is exception table
 func IsException(typeDecl ast.Type) bool {
 	_, ok := FindAnnotation(typeDecl, "gslang.Exception")
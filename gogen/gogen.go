@@ -0,0 +1,701 @@
+// Package gogen generate go types and rpc stubs of gslang package, the stubs run over package rpc.
+//
+// Tables and unions are generated as structs, enums as named integer types. Each contract is
+// generated as a server interface, a Register function binding the implementation to rpc.Server
+// and a client stub over rpc.Conn. Streaming methods expose channels and iterators:
+//
+//	stream Event Watch(string topic, stream Filter filters);
+//
+// is generated as server method
+//
+//	Watch(topic string, filters <-chan *Filter, rpcOut chan<- *Event) error
+//
+// and client method
+//
+//	Watch(topic string, filters <-chan *Filter) (*ServiceWatchIterator, error)
+//
+// the client closes the filters channel to close the send side of stream.
+//
+// The methods are routed by the service name, the service version and the method id, e.g. auth/v2/3,
+// so renaming a method keeps the wire compatibility, see package compat. The exception tables implement
+// error, the methods return the thrown exceptions as typed errors. The generic table instances are
+// generated as concrete structs, e.g. Page<User> is generated as PageUser.
+package gogen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+// ErrUnsupport the gslang type can't be generated as go type
+var ErrUnsupport = errors.New("unsupport go codegen type")
+
+var builtins = map[lexer.TokenType]string{
+	lexer.KeyByte: "byte", lexer.KeySByte: "int8", lexer.KeyInt16: "int16", lexer.KeyUInt16: "uint16",
+	lexer.KeyInt32: "int32", lexer.KeyUInt32: "uint32", lexer.KeyInt64: "int64", lexer.KeyUInt64: "uint64",
+	lexer.KeyFloat32: "float32", lexer.KeyFloat64: "float64", lexer.KeyString: "string", lexer.KeyBool: "bool",
+}
+
+// Generator go codegen backend, implement gslang.Visitor
+type Generator struct {
+	gslogger.Log                   // Mixin Log
+	pkg          string            // generated gslang package
+	decls        map[string]string // generated declarations indexed by type name
+	rpc          bool              // rpc package imported flag
+}
+
+// NewGenerator create new go backend of gslang package
+func NewGenerator(pkg string) *Generator {
+	return &Generator{
+		Log:   gslogger.Get("gogen"),
+		pkg:   pkg,
+		decls: make(map[string]string),
+	}
+}
+
+// Generate generate go source of the package in compiler's linked module, the go package name
+// is the last segment of the gslang package name
+func Generate(compiler *gslang.Compiler, pkg string) ([]byte, error) {
+
+	gen := NewGenerator(pkg)
+
+	if err := compiler.Visit(gen); err != nil {
+		return nil, err
+	}
+
+	return gen.Source()
+}
+
+// Source get formatted go source
+func (gen *Generator) Source() ([]byte, error) {
+
+	var buff bytes.Buffer
+
+	fmt.Fprintf(&buff, "// Code generated by gslangc go. DO NOT EDIT.\n\npackage %s\n\n", gen.pkg[strings.LastIndex(gen.pkg, ".")+1:])
+
+	if gen.rpc {
+		buff.WriteString("import \"github.com/gsrpc/gslang/rpc\"\n\n")
+	}
+
+	var names []string
+
+	for name := range gen.decls {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		buff.WriteString(gen.decls[name])
+		buff.WriteString("\n")
+	}
+
+	return format.Source(buff.Bytes())
+}
+
+// BeginScript implement gslang.Visitor, only the scripts of generated package are visited
+func (gen *Generator) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+	return script.Package == gen.pkg
+}
+
+// Using implement gslang.Visitor
+func (gen *Generator) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+// Annotation implement gslang.Visitor, annotations have no go type
+func (gen *Generator) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+// EndScript implement gslang.Visitor
+func (gen *Generator) EndScript(compiler *gslang.Compiler) {
+}
+
+// Table implement gslang.Visitor
+func (gen *Generator) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	// the generic tables are generated by the referenced instances, see instance
+	if gslang.IsGeneric(tableType) {
+		return
+	}
+
+	gen.D("generate table(%s)", tableType.FullName())
+
+	var buff bytes.Buffer
+
+	comment(&buff, tableType, "table")
+
+	gen.decls[tableType.Name()] = gen.table(&buff, tableType, tableType.Name())
+}
+
+// instance generate the concrete struct of generic table instance, returns the go struct name
+func (gen *Generator) instance(instance *ast.Table) string {
+
+	name := instance.Generic.Name()
+
+	for _, arg := range instance.TypeArgs {
+		name += gen.typeArgName(arg)
+	}
+
+	if _, ok := gen.decls[name]; ok {
+		return name
+	}
+
+	gen.D("generate table(%s) instance %s", instance.Generic.FullName(), name)
+
+	// the instance can reference itself by the field types
+	gen.decls[name] = ""
+
+	var buff bytes.Buffer
+
+	fmt.Fprintf(&buff, "// %s instance %s of generic table %s\n", name, instance.Name(), instance.Generic.FullName())
+
+	gen.decls[name] = gen.table(&buff, instance, name)
+
+	return name
+}
+
+// table generate the struct of table, the exception tables implement error
+func (gen *Generator) table(buff *bytes.Buffer, tableType *ast.Table, name string) string {
+
+	fmt.Fprintf(buff, "type %s struct {\n", name)
+
+	for _, field := range tableType.Fields {
+
+		tag := field.Name()
+
+		if gslang.IsOptional(field.Type) {
+			tag += ",omitempty"
+		}
+
+		fmt.Fprintf(buff, "%s %s `json:\"%s\"`\n", exported(field.Name()), gen.typeName(field.Type), tag)
+	}
+
+	buff.WriteString("}\n")
+
+	if gslang.IsException(tableType) {
+		fmt.Fprintf(buff, "\n// Error implement error interface\n")
+		fmt.Fprintf(buff, "func (err *%s) Error() string {\nreturn %q\n}\n", name, tableType.FullName())
+	}
+
+	return buff.String()
+}
+
+// Enum implement gslang.Visitor
+func (gen *Generator) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+
+	gen.D("generate enum(%s)", enum.FullName())
+
+	var buff bytes.Buffer
+
+	comment(&buff, enum, "enum")
+
+	fmt.Fprintf(&buff, "type %s %s\n", enum.Name(), builtins[gslang.EnumType(enum)])
+
+	if len(enum.Constants) > 0 {
+
+		fmt.Fprintf(&buff, "\n// %s constants\nconst (\n", enum.Name())
+
+		for _, constant := range enum.Constants {
+			fmt.Fprintf(&buff, "%s%s %s = %d\n", enum.Name(), exported(constant.Name()), enum.Name(), constant.Value)
+		}
+
+		buff.WriteString(")\n")
+	}
+
+	gen.decls[enum.Name()] = buff.String()
+}
+
+// Union implement gslang.Visitor, the union is generated as struct with one optional field per case
+func (gen *Generator) Union(compiler *gslang.Compiler, union *ast.Union) {
+
+	gen.D("generate union(%s)", union.FullName())
+
+	var buff bytes.Buffer
+
+	comment(&buff, union, "union")
+
+	fmt.Fprintf(&buff, "type %s struct {\n", union.Name())
+
+	for _, unionCase := range union.Cases {
+		fmt.Fprintf(&buff, "%s %s `json:\"%s,omitempty\"`\n", exported(unionCase.Name()), pointer(gen.typeName(unionCase.Type)), unionCase.Name())
+	}
+
+	buff.WriteString("}\n")
+
+	gen.decls[union.Name()] = buff.String()
+}
+
+// _Method go signature of contract method
+type _Method struct {
+	method     *ast.Method // method
+	route      string      // rpc method route
+	exceptions string      // go name of the method exceptions table, empty if the method throws nothing
+	params     []string    // go names of the unary params
+	types      []string    // go types of the unary params
+	stream     string      // go name of the stream param
+	input      string      // go type of the stream param values
+	results    []string    // go types of the unary results
+	output     string      // go type of the server stream values
+}
+
+// Contract implement gslang.Visitor
+func (gen *Generator) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+
+	gen.D("generate contract(%s)", contract.FullName())
+
+	gen.rpc = true
+
+	name := contract.Name()
+
+	var methods []*_Method
+
+	var buff bytes.Buffer
+
+	for _, method := range contract.Methods {
+		methods = append(methods, gen.method(contract, method, &buff))
+	}
+
+	var decls bytes.Buffer
+
+	comment(&decls, contract, "contract")
+
+	fmt.Fprintf(&decls, "type %s interface {\n", name)
+
+	for _, method := range methods {
+		fmt.Fprintf(&decls, "%s%s\n", method.method.Name(), method.serverSignature())
+	}
+
+	decls.WriteString("}\n\n")
+
+	fmt.Fprintf(&decls, "// Register%s register %s implementation as the method handlers of service %s\n", name, name, gslang.ServiceName(contract))
+	fmt.Fprintf(&decls, "func Register%s(rpcServer *rpc.Server, rpcImpl %s) {\n", name, name)
+
+	for _, method := range methods {
+		method.handler(&decls)
+	}
+
+	decls.WriteString("}\n\n")
+
+	fmt.Fprintf(&decls, "// %sClient client stub of contract %s\n", name, contract.FullName())
+	fmt.Fprintf(&decls, "type %sClient struct {\nconn *rpc.Conn\n}\n\n", name)
+	fmt.Fprintf(&decls, "// New%sClient create client stub over rpc connection\n", name)
+	fmt.Fprintf(&decls, "func New%sClient(conn *rpc.Conn) *%sClient {\nreturn &%sClient{conn: conn}\n}\n", name, name, name)
+
+	for _, method := range methods {
+		decls.WriteString("\n")
+		method.client(&decls, name)
+	}
+
+	decls.Write(buff.Bytes())
+
+	gen.decls[name] = decls.String()
+}
+
+// method create method signature, the server stream value types are generated into buff
+func (gen *Generator) method(contract *ast.Contract, method *ast.Method, buff *bytes.Buffer) *_Method {
+
+	signature := &_Method{
+		method: method,
+		route:  fmt.Sprintf("%s/v%d/%d", gslang.ServiceName(contract), gslang.ServiceVersion(contract), gslang.MethodID(method)),
+	}
+
+	prefix := contract.Name() + method.Name()
+
+	if len(method.Exceptions) > 0 {
+
+		signature.exceptions = unexported(prefix) + "Exceptions"
+
+		fmt.Fprintf(buff, "\n// %s exceptions thrown by contract %s method %s\n", signature.exceptions, contract.FullName(), method.Name())
+		fmt.Fprintf(buff, "var %s = rpc.Exceptions{\n", signature.exceptions)
+
+		for _, exception := range method.Exceptions {
+			fmt.Fprintf(buff, "%d: func() error { return &%s{} },\n", exception.ID, strings.TrimPrefix(gen.typeName(exception.Type), "*"))
+		}
+
+		buff.WriteString("}\n")
+	}
+
+	for _, param := range method.Params {
+
+		if param.Stream {
+			signature.stream = goName(param.Name())
+			signature.input = gen.typeName(param.Type)
+			continue
+		}
+
+		signature.params = append(signature.params, goName(param.Name()))
+		signature.types = append(signature.types, gen.typeName(param.Type))
+	}
+
+	var results []string
+
+	if len(method.Results) > 0 {
+		for _, result := range method.Results {
+			results = append(results, gen.typeName(result.Type))
+		}
+	} else if gslang.NotVoid(method.Return) {
+		results = append(results, gen.typeName(method.Return))
+	}
+
+	if method.Stream&ast.StreamServer == 0 {
+		signature.results = results
+		return signature
+	}
+
+	signature.output = results[0]
+
+	// the streamed results are sent as struct values
+	if len(method.Results) > 0 {
+
+		signature.output = "*" + prefix + "Result"
+
+		fmt.Fprintf(buff, "\n// %sResult stream value of contract %s method %s results\n", prefix, contract.FullName(), method.Name())
+		fmt.Fprintf(buff, "type %sResult struct {\n", prefix)
+
+		for i, result := range method.Results {
+			fmt.Fprintf(buff, "%s %s `json:\"%s\"`\n", exported(result.Name()), results[i], result.Name())
+		}
+
+		buff.WriteString("}\n")
+	}
+
+	fmt.Fprintf(buff, "\n// %sIterator iterator of contract %s method %s stream values\n", prefix, contract.FullName(), method.Name())
+	fmt.Fprintf(buff, "type %sIterator struct {\n*rpc.Iterator\nvalue %s\n}\n\n", prefix, signature.output)
+	fmt.Fprintf(buff, "// Next receive next value, returns false if the stream is closed or failed, see Err\n")
+	fmt.Fprintf(buff, "func (iter *%sIterator) Next() bool {\nvar value %s\n\nif !iter.Iterator.Next(&value) {\nreturn false\n}\n\niter.value = value\n\nreturn true\n}\n\n", prefix, signature.output)
+	fmt.Fprintf(buff, "// Value get current value\n")
+	fmt.Fprintf(buff, "func (iter *%sIterator) Value() %s {\nreturn iter.value\n}\n", prefix, signature.output)
+
+	if signature.exceptions != "" {
+		fmt.Fprintf(buff, "\n// Err get iteration error, the thrown exceptions are got as typed errors\n")
+		fmt.Fprintf(buff, "func (iter *%sIterator) Err() error {\nreturn %s\n}\n", prefix, signature.catch("iter.Iterator.Err()"))
+	}
+
+	return signature
+}
+
+// args get go params list, declare adds the param types
+func (method *_Method) args(declare bool) string {
+
+	var args []string
+
+	for i, name := range method.params {
+		if declare {
+			args = append(args, name+" "+method.types[i])
+		} else {
+			args = append(args, name)
+		}
+	}
+
+	if method.stream != "" {
+		if declare {
+			args = append(args, method.stream+" <-chan "+method.input)
+		} else {
+			args = append(args, method.stream)
+		}
+	}
+
+	return strings.Join(args, ", ")
+}
+
+// throw wrap the handler error expr, the thrown exceptions are sent as typed exceptions
+func (method *_Method) throw(err string) string {
+
+	if method.exceptions == "" {
+		return err
+	}
+
+	return method.exceptions + ".Throw(" + err + ")"
+}
+
+// catch wrap the received error expr, the typed exceptions are converted to the exception values
+func (method *_Method) catch(err string) string {
+
+	if method.exceptions == "" {
+		return err
+	}
+
+	return method.exceptions + ".Catch(" + err + ")"
+}
+
+func (method *_Method) serverSignature() string {
+
+	args := method.args(true)
+
+	if method.output != "" {
+
+		if args != "" {
+			args += ", "
+		}
+
+		return "(" + args + "rpcOut chan<- " + method.output + ") error"
+	}
+
+	return "(" + args + ") " + returns(method.results, "", "error")
+}
+
+// returns get go results list, names are prefixed by prefix if it's not empty
+func returns(types []string, prefix string, last string) string {
+
+	if len(types) == 0 && prefix == "" {
+		return last
+	}
+
+	var results []string
+
+	for i, typeName := range types {
+		if prefix != "" {
+			results = append(results, fmt.Sprintf("%s%d %s", prefix, i, typeName))
+		} else {
+			results = append(results, typeName)
+		}
+	}
+
+	if prefix != "" {
+		last = "rpcErr " + last
+	}
+
+	return "(" + strings.Join(append(results, last), ", ") + ")"
+}
+
+func (method *_Method) handler(buff *bytes.Buffer) {
+
+	fmt.Fprintf(buff, "rpcServer.Handle(%q, func(rpcStream *rpc.Stream) error {\n", method.route)
+
+	var ptrs []string
+
+	for i, name := range method.params {
+		fmt.Fprintf(buff, "var %s %s\n", name, method.types[i])
+		ptrs = append(ptrs, "&"+name)
+	}
+
+	fmt.Fprintf(buff, "\nif rpcErr := rpcStream.RecvArgs(%s); rpcErr != nil {\nreturn rpcErr\n}\n\n", strings.Join(ptrs, ", "))
+
+	if method.stream != "" {
+		fmt.Fprintf(buff, "%s := make(chan %s)\n\n", method.stream, method.input)
+		fmt.Fprintf(buff, "go func() {\ndefer close(%s)\n\nfor {\nvar rpcVal %s\n\n", method.stream, method.input)
+		fmt.Fprintf(buff, "if rpcStream.Recv(&rpcVal) != nil {\nreturn\n}\n\n")
+		fmt.Fprintf(buff, "select {\ncase %s <- rpcVal:\ncase <-rpcStream.Done():\nreturn\n}\n}\n}()\n\n", method.stream)
+	}
+
+	args := method.args(false)
+
+	if method.output != "" {
+
+		if args != "" {
+			args += ", "
+		}
+
+		fmt.Fprintf(buff, "rpcOut := make(chan %s)\n\nrpcResult := make(chan error, 1)\n\n", method.output)
+		fmt.Fprintf(buff, "go func() {\nrpcResult <- rpcImpl.%s(%srpcOut)\nclose(rpcOut)\n}()\n\n", method.method.Name(), args)
+		fmt.Fprintf(buff, "var rpcErr error\n\nfor rpcVal := range rpcOut {\nif rpcErr == nil {\nrpcErr = rpcStream.Send(rpcVal)\n}\n}\n\n")
+		fmt.Fprintf(buff, "if rpcImplErr := <-rpcResult; rpcImplErr != nil {\nreturn %s\n}\n\nreturn rpcErr\n", method.throw("rpcImplErr"))
+		buff.WriteString("})\n\n")
+		return
+	}
+
+	if len(method.results) == 0 {
+		fmt.Fprintf(buff, "if rpcErr := rpcImpl.%s(%s); rpcErr != nil {\nreturn %s\n}\n\nreturn rpcStream.SendArgs()\n", method.method.Name(), args, method.throw("rpcErr"))
+		buff.WriteString("})\n\n")
+		return
+	}
+
+	var rets []string
+
+	for i := range method.results {
+		rets = append(rets, fmt.Sprintf("rpcRet%d", i))
+	}
+
+	fmt.Fprintf(buff, "%s, rpcErr := rpcImpl.%s(%s)\n\n", strings.Join(rets, ", "), method.method.Name(), args)
+	fmt.Fprintf(buff, "if rpcErr != nil {\nreturn %s\n}\n\nreturn rpcStream.SendArgs(%s)\n", method.throw("rpcErr"), strings.Join(rets, ", "))
+	buff.WriteString("})\n\n")
+}
+
+func (method *_Method) client(buff *bytes.Buffer, contract string) {
+
+	name := method.method.Name()
+
+	if method.output != "" {
+		fmt.Fprintf(buff, "// %s open stream of method %s, the values are got by the returned iterator\n", name, name)
+		fmt.Fprintf(buff, "func (rpcClient *%sClient) %s(%s) (rpcIter *%s%sIterator, rpcErr error) {\n", contract, name, method.args(true), contract, name)
+	} else {
+		fmt.Fprintf(buff, "// %s call method %s\n", name, name)
+		fmt.Fprintf(buff, "func (rpcClient *%sClient) %s(%s) %s {\n", contract, name, method.args(true), returns(method.results, "rpcRet", "error"))
+	}
+
+	var ptrs []string
+
+	for i := range method.results {
+		ptrs = append(ptrs, fmt.Sprintf("&rpcRet%d", i))
+	}
+
+	// unary call
+	if method.stream == "" && method.output == "" {
+
+		args := append([]string{fmt.Sprintf("%q", method.route), "[]interface{}{" + method.args(false) + "}"}, ptrs...)
+
+		fmt.Fprintf(buff, "rpcErr = %s\n\nreturn\n}\n", method.catch("rpcClient.conn.Call("+strings.Join(args, ", ")+")"))
+
+		return
+	}
+
+	fmt.Fprintf(buff, "rpcStream, rpcErr := rpcClient.conn.Open(%s)\n\n", strings.Join(append([]string{fmt.Sprintf("%q", method.route)}, method.params...), ", "))
+	fmt.Fprintf(buff, "if rpcErr != nil {\nreturn\n}\n\n")
+
+	if method.stream != "" {
+		fmt.Fprintf(buff, "go func() {\nfor rpcVal := range %s {\nif rpcStream.Send(rpcVal) != nil {\nreturn\n}\n}\n\nrpcStream.CloseSend()\n}()\n\n", method.stream)
+	} else {
+		fmt.Fprintf(buff, "if rpcErr = rpcStream.CloseSend(); rpcErr != nil {\nreturn\n}\n\n")
+	}
+
+	if method.output != "" {
+		fmt.Fprintf(buff, "rpcIter = &%s%sIterator{Iterator: rpc.NewIterator(rpcStream)}\n\nreturn\n}\n", contract, name)
+		return
+	}
+
+	fmt.Fprintf(buff, "rpcErr = %s\n\nreturn\n}\n", method.catch("rpcStream.RecvArgs("+strings.Join(ptrs, ", ")+")"))
+}
+
+// typeName get go type name of gslang type
+func (gen *Generator) typeName(typeDecl ast.Type) string {
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		if name, ok := builtins[typeDecl.(*ast.BuiltinType).Type]; ok {
+			return name
+		}
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if seq.Size > 0 {
+			return fmt.Sprintf("[%d]%s", seq.Size, gen.typeName(seq.Component))
+		}
+
+		return "[]" + gen.typeName(seq.Component)
+	case *ast.Optional:
+		return pointer(gen.typeName(typeDecl.(*ast.Optional).Component))
+	case *ast.TypeRef:
+		ref := typeDecl.(*ast.TypeRef)
+
+		if instance, ok := gslang.Instantiate(ref); ok && gen.local(instance.Generic) {
+			return "*" + gen.instance(instance)
+		}
+
+		if ref.Ref != nil && len(ref.TypeArgs) == 0 {
+			return gen.typeName(ref.Ref)
+		}
+	case *ast.Table:
+		if gen.local(typeDecl) && !gslang.IsGeneric(typeDecl) {
+			return "*" + typeDecl.Name()
+		}
+	case *ast.Enum:
+		if gen.local(typeDecl) {
+			return typeDecl.Name()
+		}
+	case *ast.Union:
+		if gen.local(typeDecl) {
+			return "*" + typeDecl.Name()
+		}
+	}
+
+	gserrors.Panicf(ErrUnsupport, "type(%s) can't be generated as go type of package %s", ast.TypeName(typeDecl), gen.pkg)
+
+	return ""
+}
+
+// typeArgName get the go name segment of generic table instance type arg, e.g. EntrySeq of Entry[]
+func (gen *Generator) typeArgName(typeDecl ast.Type) string {
+
+	switch typeDecl.(type) {
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if seq.Size > 0 {
+			return fmt.Sprintf("%sArray%d", gen.typeArgName(seq.Component), seq.Size)
+		}
+
+		return gen.typeArgName(seq.Component) + "Seq"
+	case *ast.Optional:
+		return "Optional" + gen.typeArgName(typeDecl.(*ast.Optional).Component)
+	}
+
+	return exported(strings.TrimPrefix(gen.typeName(typeDecl), "*"))
+}
+
+// local check if the type is declared in generated package
+func (gen *Generator) local(typeDecl ast.Type) bool {
+	return typeDecl.FullName() == gen.pkg+"."+typeDecl.Name()
+}
+
+// pointer get pointer type of go type, pointer types are unchanged
+func pointer(typeName string) string {
+
+	if strings.HasPrefix(typeName, "*") {
+		return typeName
+	}
+
+	return "*" + typeName
+}
+
+// exported get exported go name
+func exported(name string) string {
+
+	r, size := utf8.DecodeRuneInString(name)
+
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+// unexported get unexported go name
+func unexported(name string) string {
+
+	r, size := utf8.DecodeRuneInString(name)
+
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// goName get go param name, the go keywords and the generated local names are suffixed with _
+func goName(name string) string {
+
+	if token.IsKeyword(name) || strings.HasPrefix(name, "rpc") {
+		return name + "_"
+	}
+
+	return name
+}
+
+// comment write doc comment of gslang type
+func comment(buff *bytes.Buffer, node ast.Node, kind string) {
+
+	text := ""
+
+	if val, ok := node.GetExtra(gslang.ExtraComment); ok {
+		text = strings.TrimSpace(val.(*ast.Comment).String())
+	}
+
+	if text == "" {
+		fmt.Fprintf(buff, "// %s %s %s\n", node.Name(), kind, node.(ast.Type).FullName())
+		return
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+
+		if i == 0 {
+			line = node.Name() + " " + line
+		}
+
+		fmt.Fprintf(buff, "// %s\n", strings.TrimSpace(line))
+	}
+}
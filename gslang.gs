@@ -25,3 +25,48 @@ table Package{
 // indicate this method don't expect call response
 @Usage(Target.Method)
 table Async {}
+
+// contract routing metadata, the service names are unique in module : @Service(Name:"auth", Version:2)
+@Usage(Target.Contract)
+table Service {
+    string Name; // service name, defaults to the contract full name
+    uint32 Version; // contract version, starts from 1
+}
+
+// stable method id for routing, defaults to the method declaration order : @MethodID(3)
+@Usage(Target.Method)
+table MethodID {
+    uint16 ID;
+}
+
+// mark retired types, fields, methods, params and enum constants, references are reported as warnings
+@Usage(Target.Table|Target.Enum|Target.Contract|Target.Field|Target.Method|Target.Param|Target.EnumConstant|
+    Target.Union|Target.UnionCase)
+table Deprecated {
+    string Reason; // retire reason and replacement
+    string Since; // retired version
+}
+
+// inclusive value range of numeric fields and params : @Range(Min:0, Max:100)
+@Usage(Target.Field|Target.Param)
+table Range {
+    float64 Min;
+    float64 Max;
+}
+
+// regular expression the string fields and params must match : @Pattern("^[a-z]+$")
+@Usage(Target.Field|Target.Param)
+table Pattern {
+    string Regexp;
+}
+
+// length range of string and seq fields and params : @Length(Max:64)
+@Usage(Target.Field|Target.Param)
+table Length {
+    uint32 Min;
+    uint32 Max;
+}
+
+// string and seq fields and params can't be empty
+@Usage(Target.Field|Target.Param)
+table NotEmpty {}
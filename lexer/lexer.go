@@ -198,7 +198,7 @@ type Lexer struct {
 	gslogger.Log                   //Mixin log interface
 	reader       *bufio.Reader     //input reader
 	position     Position          //curr coursor position
-	token        *Token            //curr parsed token
+	tokens       []*Token          //peeked tokens
 	buff         [utf8.UTFMax]byte //buffer length
 	buffPos      int               //buff write position
 	offset       int               //reader stream offset by byte
@@ -225,6 +225,11 @@ func (lexer *Lexer) String() string {
 	return lexer.position.FileName
 }
 
+//Position get current cursor position
+func (lexer *Lexer) Position() Position {
+	return lexer.position
+}
+
 func (lexer *Lexer) newerror(fmtstring string, args ...interface{}) error {
 	return gserrors.Newf(ErrLexer, "[lexer] %s\n\t%s", fmt.Sprintf(fmtstring, args...), lexer.position)
 }
@@ -625,25 +630,32 @@ func (lexer *Lexer) scanExponent(buff *bytes.Buffer) {
 
 //Peek peek next token
 func (lexer *Lexer) Peek() (token *Token, err error) {
-	if lexer.token != nil {
+	return lexer.PeekN(0)
+}
 
-		token = lexer.token
-		return
-	}
+//PeekN peek the n-th next token, PeekN(0) is the next token
+func (lexer *Lexer) PeekN(n int) (token *Token, err error) {
 
-	token, err = lexer.next()
-	if err == nil {
-		lexer.token = token
+	for len(lexer.tokens) <= n {
+
+		token, err = lexer.next()
+		if err != nil {
+			return
+		}
+
+		lexer.tokens = append(lexer.tokens, token)
 	}
 
+	token = lexer.tokens[n]
+
 	return
 }
 
 //Next get next token and move lexer's cursor
 func (lexer *Lexer) Next() (token *Token, err error) {
 
-	if lexer.token != nil {
-		token, lexer.token = lexer.token, nil
+	if len(lexer.tokens) != 0 {
+		token, lexer.tokens = lexer.tokens[0], lexer.tokens[1:]
 		return
 	}
 
@@ -2,25 +2,34 @@ package gslang
 
 import (
 	"fmt"
+	"math"
 	"path"
 	"strings"
 
 	"github.com/gsdocker/gserrors"
 	"github.com/gsdocker/gslogger"
 	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
 )
 
 type _Linker struct {
-	gslogger.Log                     //Mixin logger
-	types        map[string]ast.Type // defined types
-	importTypes  map[string]ast.Type // defined types
-	errorHandler ErrorHandler        // error handler
-	linkdepth    int                 // link depth
-	compiler     *Compiler           // compiler
+	gslogger.Log                            //Mixin logger
+	types        map[string]ast.Type        // defined types
+	importTypes  map[string]ast.Type        // defined types
+	errorHandler ErrorHandler               // error handler
+	linkdepth    int                        // link depth
+	compiler     *Compiler                  // compiler
+	evaluated    map[*ast.EnumConstant]bool // evaluated enum constants, false for the failed ones
+	evaluating   map[*ast.EnumConstant]bool // evaluating enum constants, which detect the circular references
 }
 
 // Link do sematic paring and type link
 func (compiler *Compiler) Link() (err error) {
+	return compiler.link(nil)
+}
+
+// link link selected scripts against the whole module, nil selects all scripts
+func (compiler *Compiler) link(scripts map[string]bool) (err error) {
 
 	defer func() {
 		if e := recover(); e != nil {
@@ -28,32 +37,73 @@ func (compiler *Compiler) Link() (err error) {
 		}
 	}()
 
+	compiler.linked = true
+
+	selected := func(script *ast.Script) bool {
+		return scripts == nil || scripts[script.Name()]
+	}
+
 	linker := &_Linker{
 		Log:          gslogger.Get("linker"),
 		types:        make(map[string]ast.Type),
 		errorHandler: compiler.errorHandler,
 		compiler:     compiler,
+		evaluated:    make(map[*ast.EnumConstant]bool),
+		evaluating:   make(map[*ast.EnumConstant]bool),
 	}
 
 	compiler.module.Foreach(func(script *ast.Script) bool {
-		linker.createSymbolTable(script)
+
+		if selected(script) {
+			compiler.invalidate(script.Name(), StageSemParing)
+			delete(compiler.deps, script.Name())
+		}
+
+		linker.createSymbolTable(script, selected(script))
+
 		return true
 	})
 
 	compiler.module.Types = linker.types
 
 	compiler.module.Foreach(func(script *ast.Script) bool {
-		linker.linkTypes(script)
+
+		if selected(script) {
+			compiler.current = script.Name()
+			linker.linkTypes(script)
+		}
+
 		return true
 	})
 
+	// the enum constant exprs may reference the constants of any script, so they are evaluated after linking
 	compiler.module.Foreach(func(script *ast.Script) bool {
 
+		if selected(script) {
+			compiler.current = script.Name()
+			linker.evalEnums(script)
+		}
+
+		return true
+	})
+
+	compiler.module.Foreach(func(script *ast.Script) bool {
+
+		if !selected(script) {
+			return true
+		}
+
+		compiler.current = script.Name()
+
+		linker.checkScriptAnnotation(script)
+
 		script.TypeForeach(func(gslangType ast.Type) {
 
 			linker.checkAnnotation(script, gslangType)
 		})
 
+		linker.checkDeprecated(script)
+
 		return true
 	})
 
@@ -97,6 +147,21 @@ func (linker *_Linker) errorf(err error, node ast.Node, fmtstr string, args ...i
 	linker.errorHandler.HandleError(errinfo)
 }
 
+func (linker *_Linker) warnf(err error, node ast.Node, fmtstr string, args ...interface{}) {
+	start, end := Pos(node)
+
+	errinfo := &Error{
+		Stage:   StageSemParing,
+		Orignal: err,
+		Start:   start,
+		End:     end,
+		Text:    fmt.Sprintf(fmtstr, args...),
+		Warning: true,
+	}
+
+	linker.errorHandler.HandleError(errinfo)
+}
+
 func (linker *_Linker) startLinkNode(node ast.Node) {
 
 	linker.D("<link %s>", node)
@@ -128,6 +193,7 @@ func (linker *_Linker) linkTypes(script *ast.Script) {
 		if gslangType, ok := linker.types[using.Name()]; ok {
 			linker.importTypes[name] = gslangType
 			using.Ref = gslangType
+			linker.depend(script, gslangType)
 			linker.D("link using(%s:%p) : %s -- success", using, using, name)
 			return
 		}
@@ -137,6 +203,16 @@ func (linker *_Linker) linkTypes(script *ast.Script) {
 
 	linker.D("create using symbol table for script : %s -- success", script)
 
+	script.UsingForeach(func(using *ast.Using) {
+		for _, annotation := range Annotations(using) {
+			linker.linkAnnotation(script, annotation)
+		}
+	})
+
+	for _, annotation := range Annotations(script) {
+		linker.linkAnnotation(script, annotation)
+	}
+
 	script.TypeForeach(func(gslangType ast.Type) {
 
 		linker.linkType(script, gslangType)
@@ -149,28 +225,154 @@ func (linker *_Linker) linkTypeAnnotation(script *ast.Script, typeDecl ast.Type)
 	}
 }
 
-func (linker *_Linker) checkAnnotation(script *ast.Script, typeDecl ast.Type) {
-	for _, annotation := range Annotations(typeDecl) {
+// usageTarget get the Usage target flags of annotation type, returns false if the annotation type isn't linked
+// or the Usage is illegal
+func (linker *_Linker) usageTarget(annotation *ast.Annotation) (int64, bool) {
+
+	if annotation.Type.Ref == nil {
+		return 0, false
+	}
+
+	usage, ok := FindAnnotation(annotation.Type.Ref, "gslang.annotations.Usage")
+
+	if !ok {
+		linker.errorf(ErrAnnotation, annotation, "illegal annotation type : table(%s) must be annotation by gslang.annotations.Usage", annotation.Type.Ref.FullName())
+		return 0, false
+	}
+
+	var usageVal struct {
+		Target int64
+	}
 
-		if annotation.Type.Ref == nil {
+	if err := DecodeAnnotation(usage, &usageVal); err != nil {
+		linker.errorHandler.HandleError(err.(*Error))
+		return 0, false
+	}
+
+	return usageVal.Target, true
+}
+
+// targetNames get the names of Target constants set in val
+func (linker *_Linker) targetNames(val int64) string {
+
+	var names []string
+
+	if enum, ok := linker.types["gslang.annotations.Target"].(*ast.Enum); ok {
+		for _, constant := range enum.Constants {
+			if constant.Value&val != 0 {
+				names = append(names, constant.Name())
+			}
+		}
+	}
+
+	return strings.Join(names, "|")
+}
+
+// checkRepeat check if the non-repeatable annotations are applied once to node
+func (linker *_Linker) checkRepeat(node ast.Node) {
+
+	applied := make(map[ast.Type]bool)
+
+	for _, annotation := range Annotations(node) {
+
+		typeDecl := annotation.Type.Ref
+
+		if typeDecl == nil {
+			continue
+		}
+
+		if applied[typeDecl] && !IsRepeatable(typeDecl) {
+			linker.errorf(ErrAnnotation, annotation, "annotation(%s) isn't repeatable, already applied to %s", typeDecl.FullName(), node)
+		}
+
+		applied[typeDecl] = true
+	}
+}
+
+// checkRequired check if the required fields are set by annotation and nested table instances, the field
+// annotations are linked after the table instances, so it's checked with the annotation targets
+func (linker *_Linker) checkRequired(annotation *ast.Annotation) {
+
+	linker.checkRequiredArgs(annotation, annotation.Type.Ref, annotation.Args)
+
+	if annotation.Args == nil {
+		return
+	}
+
+	ast.Inspect(annotation.Args, func(node ast.Node) bool {
+
+		if newObj, ok := node.(*ast.NewObj); ok {
+			linker.checkRequiredArgs(newObj, newObj.Type.Ref, newObj.Args)
+		}
+
+		return true
+	})
+}
+
+func (linker *_Linker) checkRequiredArgs(node ast.Node, typeDecl ast.Type, args *ast.ArgsTable) {
+
+	table, ok := typeDecl.(*ast.Table)
+
+	// positional args num is checked by linkTableNewObj
+	if !ok || (args != nil && !args.Named) {
+		return
+	}
+
+	for _, field := range table.Fields {
+
+		if !IsRequired(field) {
 			continue
 		}
 
-		usage, ok := FindAnnotation(annotation.Type.Ref, "gslang.annotations.Usage")
+		if args != nil {
+			if _, ok := args.NamedArg(field.Name()); ok {
+				continue
+			}
+		}
+
+		linker.errorf(ErrNewObj, node, "table(%s) required field(%s) not set", table, field)
+	}
+}
+
+// checkTarget check if the annotations of node are allowed on the target
+func (linker *_Linker) checkTarget(node ast.Node, target string) {
+
+	linker.checkRepeat(node)
+
+	for _, annotation := range Annotations(node) {
+
+		val, ok := linker.usageTarget(annotation)
 
 		if !ok {
-			linker.errorf(ErrAnnotation, annotation, "illegal annotation type : table(%s) must be annotation by gslang.annotations.Usage", annotation.Type.Ref.FullName())
+			continue
+		}
+
+		linker.checkRequired(annotation)
+
+		if linker.Eval().EvalEnumConstant("gslang.annotations.Target", target)&val == 0 {
+			linker.errorf(ErrAnnotation, annotation,
+				"annotation(%s) can't be applied to %s(%s), allowed targets : %s",
+				annotation.Type.Ref.FullName(), target, node, linker.targetNames(val))
 		}
+	}
+}
 
-		if usage.Args.Count() != 1 {
+func (linker *_Linker) checkAnnotation(script *ast.Script, typeDecl ast.Type) {
+	for _, annotation := range Annotations(typeDecl) {
+
+		val, ok := linker.usageTarget(annotation)
+
+		if !ok {
 			continue
 		}
 
-		val := linker.Eval().EvalInt(usage.Args.Arg(0))
+		scriptConstant := linker.Eval().EvalEnumConstant("gslang.annotations.Target", "Script")
 
-		scriptConstant := int64(linker.Eval().EvalEnumConstant("gslang.annotations.Target", "Script"))
+		moduleConstant := linker.Eval().EvalEnumConstant("gslang.annotations.Target", "Module")
 
-		moduleConstant := int64(linker.Eval().EvalEnumConstant("gslang.annotations.Target", "Module"))
+		if (scriptConstant|moduleConstant)&val != 0 {
+			linker.checkRequired(annotation)
+		}
 
 		if scriptConstant&val != 0 {
 			linker.D("move anntotation(%s) to script(%s)", annotation, script)
@@ -184,13 +386,246 @@ func (linker *_Linker) checkAnnotation(script *ast.Script, typeDecl ast.Type) {
 	}
 
 	switch typeDecl.(type) {
+	case *ast.Table:
+		linker.checkTarget(typeDecl, "Table")
+
+		for _, field := range typeDecl.(*ast.Table).Fields {
+			linker.checkTarget(field, "Field")
+			linker.checkConstraints(field)
+		}
+	case *ast.Enum:
+		linker.checkTarget(typeDecl, "Enum")
+
+		for _, constant := range typeDecl.(*ast.Enum).Constants {
+			linker.checkTarget(constant, "EnumConstant")
+		}
 	case *ast.Contract:
+		linker.checkTarget(typeDecl, "Contract")
+
+		for _, method := range typeDecl.(*ast.Contract).Methods {
+
+			linker.checkTarget(method, "Method")
+
+			for _, param := range method.Params {
+				linker.checkTarget(param, "Param")
+				linker.checkConstraints(param)
+			}
+
+			for _, result := range method.Results {
+				linker.checkTarget(result, "Param")
+				linker.checkConstraints(result)
+			}
+
+			for _, exception := range method.Exceptions {
+				linker.checkTarget(exception, "Exception")
+			}
+		}
+
 		linker.checkContractAnnotation(script, typeDecl.(*ast.Contract))
+	case *ast.Union:
+		linker.checkTarget(typeDecl, "Union")
+
+		for _, unionCase := range typeDecl.(*ast.Union).Cases {
+			linker.checkTarget(unionCase, "UnionCase")
+		}
+	}
+}
+
+// checkDeprecated report references of deprecated types, enum constants and table fields as warnings,
+// the references inside deprecated nodes are skipped
+func (linker *_Linker) checkDeprecated(script *ast.Script) {
+
+	deprecated := func(node ast.Node, target ast.Node, kind string) {
+
+		if !IsDeprecated(target) {
+			return
+		}
+
+		for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+			if IsDeprecated(parent) {
+				return
+			}
+		}
+
+		var reason struct {
+			Reason string
+			Since  string
+		}
+
+		annotation, _ := FindAnnotation(target, "gslang.Deprecated")
+
+		DecodeAnnotation(annotation, &reason)
+
+		text := fmt.Sprintf("%s(%s) is deprecated", kind, target)
+
+		if reason.Since != "" {
+			text += " since " + reason.Since
+		}
+
+		if reason.Reason != "" {
+			text += " : " + reason.Reason
+		}
+
+		linker.warnf(ErrDeprecated, node, "%s", text)
+	}
+
+	ast.Inspect(script, func(node ast.Node) bool {
+
+		switch node.(type) {
+		case *ast.TypeRef:
+			if ref := node.(*ast.TypeRef).Ref; ref != nil {
+				deprecated(node, ref, "type")
+			}
+		case *ast.ConstantRef:
+			if constant, ok := node.(*ast.ConstantRef).Value.(*ast.EnumConstant); ok {
+				deprecated(node, constant, "enum constant")
+			}
+		case *ast.NewObj:
+			linker.checkDeprecatedArgs(node.(*ast.NewObj).Type.Ref, node.(*ast.NewObj).Args, deprecated)
+		case *ast.Annotation:
+			linker.checkDeprecatedArgs(node.(*ast.Annotation).Type.Ref, node.(*ast.Annotation).Args, deprecated)
+		}
+
+		return true
+	})
+}
+
+func (linker *_Linker) checkDeprecatedArgs(typeDecl ast.Type, args *ast.ArgsTable, deprecated func(node ast.Node, target ast.Node, kind string)) {
+
+	table, ok := typeDecl.(*ast.Table)
+
+	if !ok || args == nil {
+		return
+	}
+
+	for i, arg := range args.Args() {
+
+		var field *ast.Field
+
+		if args.Named {
+			field, ok = table.Field(arg.Name())
+		} else {
+			ok = i < len(table.Fields)
+
+			if ok {
+				field = table.Fields[i]
+			}
+		}
+
+		if ok {
+			deprecated(arg, field, "field")
+		}
+	}
+}
+
+// checkConstraints check if the validation constraints of field or param are applicable to its type and
+// the constraint args can be evaluated
+func (linker *_Linker) checkConstraints(node ast.Node) {
+	if _, err := Constraints(node); err != nil {
+		linker.errorHandler.HandleError(err.(*Error))
+	}
+}
+
+// checkService check the gslang.Service args, the service name must be unique in module
+func (linker *_Linker) checkService(contract *ast.Contract) {
+
+	annotation, ok := FindAnnotation(contract, "gslang.Service")
+
+	if !ok {
+		return
+	}
+
+	var service _ServiceInfo
+
+	if err := DecodeAnnotation(annotation, &service); err != nil {
+		linker.errorHandler.HandleError(err.(*Error))
+		return
+	}
+
+	if service.Name != nil && *service.Name == "" {
+		linker.errorf(ErrAnnotation, annotation, "contract(%s) service name can't be empty", contract)
+	}
+
+	if service.Version != nil && *service.Version == 0 {
+		linker.errorf(ErrAnnotation, annotation, "contract(%s) service version must be greater than 0", contract)
+	}
+
+	name := ServiceName(contract)
+
+	for _, typeDecl := range linker.types {
+
+		if other, ok := typeDecl.(*ast.Contract); ok && other != contract && ServiceName(other) == name {
+			linker.errorf(ErrAnnotation, annotation, "duplicate service name(%s) : contract(%s) and contract(%s)", name, contract.FullName(), other.FullName())
+			return
+		}
+	}
+}
+
+// checkScriptAnnotation check the annotations at the end of script, which are attached to the script
+func (linker *_Linker) checkScriptAnnotation(script *ast.Script) {
+
+	script.UsingForeach(func(using *ast.Using) {
+		linker.checkTarget(using, "Using")
+	})
+
+	linker.checkRepeat(script)
+
+	for _, annotation := range Annotations(script) {
+
+		val, ok := linker.usageTarget(annotation)
+
+		if !ok {
+			continue
+		}
+
+		linker.checkRequired(annotation)
+
+		targets := linker.Eval().EvalEnumConstant("gslang.annotations.Target", "Script") |
+			linker.Eval().EvalEnumConstant("gslang.annotations.Target", "Module")
+
+		if targets&val == 0 {
+			linker.errorf(ErrAnnotation, annotation,
+				"annotation(%s) can't be applied to script(%s), allowed targets : %s",
+				annotation.Type.Ref.FullName(), script, linker.targetNames(val))
+		}
 	}
 }
 
 func (linker *_Linker) checkContractAnnotation(script *ast.Script, contract *ast.Contract) {
 
+	linker.checkService(contract)
+
+	methods := make(map[int]*ast.Method)
+
+	for _, method := range contract.Methods {
+
+		if annotation, ok := FindAnnotation(method, "gslang.MethodID"); ok {
+
+			var val struct {
+				ID *uint16
+			}
+
+			if err := DecodeAnnotation(annotation, &val); err != nil {
+				linker.errorHandler.HandleError(err.(*Error))
+				continue
+			}
+
+			if val.ID == nil {
+				linker.errorf(ErrAnnotation, annotation, "gslang.MethodID expect method id")
+				continue
+			}
+		}
+
+		id := MethodID(method)
+
+		if previous, ok := methods[id]; ok {
+			linker.errorf(ErrAnnotation, method, "contract(%s) method(%s) id(%d) conflicts with method(%s)", contract, method, id, previous)
+			continue
+		}
+
+		methods[id] = method
+	}
+
 	for _, method := range contract.Methods {
 		for _, exception := range method.Exceptions {
 			ref, ok := exception.Type.(*ast.TypeRef)
@@ -231,17 +666,63 @@ func (linker *_Linker) linkType(script *ast.Script, gslangType ast.Type) {
 	case *ast.Enum:
 		linker.linkTypeAnnotation(script, gslangType)
 		linker.linkEnum(script, gslangType.(*ast.Enum))
+	case *ast.Union:
+		linker.linkTypeAnnotation(script, gslangType)
+		linker.linkUnion(script, gslangType.(*ast.Union))
 	case *ast.TypeRef:
 		linker.linkTypeRef(script, gslangType.(*ast.TypeRef))
+		linker.linkTypeArgs(script, gslangType.(*ast.TypeRef))
 	case *ast.Seq:
 		linker.linkType(script, gslangType.(*ast.Seq).Component)
+		linker.checkSeq(gslangType.(*ast.Seq))
+	case *ast.Optional:
+		linker.linkType(script, gslangType.(*ast.Optional).Component)
+	case *ast.BuiltinType:
+		linker.checkBound(gslangType.(*ast.BuiltinType))
 	}
 
 	linker.endLinkNode(gslangType)
 }
 
+// checkSeq check the seq fixed size and max length bound
+func (linker *_Linker) checkSeq(seq *ast.Seq) {
+
+	if seq.Size == 0 || seq.Size < -1 || seq.Size > math.MaxInt32 {
+		linker.errorf(ErrType, seq, "seq(%s) size(%d) out of range [1,%d]", seq.Component, seq.Size, math.MaxInt32)
+	}
+
+	if seq.Bound < 0 || seq.Bound > math.MaxInt32 {
+		linker.errorf(ErrType, seq, "seq(%s) bound(%d) out of range [1,%d]", seq.Component, seq.Bound, math.MaxInt32)
+	}
+}
+
+// checkBound check the builtin type max length bound, which only can be applied to string
+func (linker *_Linker) checkBound(builtin *ast.BuiltinType) {
+
+	if builtin.Bound == 0 {
+		return
+	}
+
+	if builtin.Type != lexer.KeyString {
+		linker.errorf(ErrType, builtin, "bound can't be applied to builtin type(%s)", builtin.Name())
+	}
+
+	if builtin.Bound < 0 || builtin.Bound > math.MaxInt32 {
+		linker.errorf(ErrType, builtin, "string bound(%d) out of range [1,%d]", builtin.Bound, math.MaxInt32)
+	}
+}
+
 func (linker *_Linker) linkTypeRef(script *ast.Script, typeRef *ast.TypeRef) {
 
+	// generic table type params shadow the declared types
+	if table, ok := ast.EnclosingType(typeRef).(*ast.Table); ok {
+		if param, ok := table.TypeParam(typeRef.Name()); ok {
+			typeRef.Ref = param
+
+			return
+		}
+	}
+
 	linkedType, ok := script.Type(typeRef.Name())
 
 	if ok {
@@ -257,6 +738,8 @@ func (linker *_Linker) linkTypeRef(script *ast.Script, typeRef *ast.TypeRef) {
 	if ok {
 		typeRef.Ref = linkedType
 
+		linker.depend(script, linkedType)
+
 		linker.D("found import types %s", linkedType)
 
 		return
@@ -267,6 +750,8 @@ func (linker *_Linker) linkTypeRef(script *ast.Script, typeRef *ast.TypeRef) {
 	if ok {
 		typeRef.Ref = linkedType
 
+		linker.depend(script, linkedType)
+
 		linker.D("found import types %s", linkedType)
 
 		return
@@ -275,6 +760,57 @@ func (linker *_Linker) linkTypeRef(script *ast.Script, typeRef *ast.TypeRef) {
 	linker.errorf(ErrTypeNotFound, typeRef, "unknown type reference :%s", typeRef)
 }
 
+// linkTypeArgs link type args and check the generic table arity
+func (linker *_Linker) linkTypeArgs(script *ast.Script, typeRef *ast.TypeRef) {
+
+	for _, arg := range typeRef.TypeArgs {
+
+		linker.linkType(script, arg)
+
+		if IsVoid(arg) {
+			linker.errorf(ErrType, arg, "void can't be type arg of type(%s)", typeRef.Name())
+		}
+	}
+
+	if typeRef.Ref == nil {
+		return
+	}
+
+	var params int
+
+	if table, ok := typeRef.Ref.(*ast.Table); ok {
+		params = len(table.TypeParams)
+	}
+
+	if params == 0 && len(typeRef.TypeArgs) != 0 {
+		linker.errorf(ErrType, typeRef, "type(%s) is not generic table", typeRef.Ref)
+		return
+	}
+
+	if params != len(typeRef.TypeArgs) {
+		linker.errorf(ErrType, typeRef, "generic table(%s) expect %d type args but got %d", typeRef.Ref, params, len(typeRef.TypeArgs))
+	}
+}
+
+// depend record script dependency on the script which defines the type
+func (linker *_Linker) depend(script *ast.Script, typeDecl ast.Type) {
+
+	name := typeDecl.Script()
+
+	if name == script.Name() {
+		return
+	}
+
+	deps, ok := linker.compiler.deps[script.Name()]
+
+	if !ok {
+		deps = make(map[string]bool)
+		linker.compiler.deps[script.Name()] = deps
+	}
+
+	deps[name] = true
+}
+
 func (linker *_Linker) linkExpr(script *ast.Script, expr ast.Expr) {
 
 	gserrors.Assert(expr != nil, "input arg expr can't be nil")
@@ -312,15 +848,19 @@ func (linker *_Linker) linkBinaryOp(script *ast.Script, binary *ast.BinaryOp) {
 }
 
 func (linker *_Linker) linkNewObj(script *ast.Script, newObj *ast.NewObj) {
-	linker.linkType(script, newObj.Type)
+	linker.linkObj(script, newObj.Type, newObj.Args)
+}
 
-	if newObj.Args != nil {
-		linker.linkExpr(script, newObj.Args)
+func (linker *_Linker) linkObj(script *ast.Script, typeRef *ast.TypeRef, args *ast.ArgsTable) {
+	linker.linkType(script, typeRef)
 
-		if newObj.Type.Ref != nil {
-			switch newObj.Type.Ref.(type) {
+	if args != nil {
+		linker.linkExpr(script, args)
+
+		if typeRef.Ref != nil {
+			switch typeRef.Ref.(type) {
 			case *ast.Table:
-				linker.linkTableNewObj(script, newObj.Type.Ref.(*ast.Table), newObj.Args)
+				linker.linkTableNewObj(script, typeRef.Ref.(*ast.Table), args)
 			}
 		}
 	}
@@ -367,6 +907,15 @@ func (linker *_Linker) linkConstantRef(script *ast.Script, constantRef *ast.Cons
 	nodes := strings.Split(constantRef.Name(), ".")
 
 	if len(nodes) < 2 {
+
+		// enum constant value exprs can reference the constants of the same enum by name
+		if target, ok := valueConstant(constantRef); ok {
+			if constant, ok := ast.EnclosingType(target).(*ast.Enum).Constant(constantRef.Name()); ok {
+				constantRef.Value = constant
+				return
+			}
+		}
+
 		linker.errorf(ErrTypeNotFound, constantRef, "unknown constant val (%s)", constantRef.Name())
 		return
 	}
@@ -379,10 +928,17 @@ func (linker *_Linker) linkConstantRef(script *ast.Script, constantRef *ast.Cons
 
 	_setNodePos(typeRef, start, end)
 
+	typeRef.SetParent(constantRef)
+
 	linker.linkTypeRef(script, typeRef)
 
 	if typeRef.Ref != nil {
-		enum := typeRef.Ref.(*ast.Enum)
+		enum, ok := typeRef.Ref.(*ast.Enum)
+
+		if !ok {
+			linker.errorf(ErrType, constantRef, "constant val (%s) type(%s) is not enum", constantRef.Name(), typeRef.Ref)
+			return
+		}
 
 		for _, constant := range enum.Constants {
 
@@ -398,11 +954,215 @@ func (linker *_Linker) linkConstantRef(script *ast.Script, constantRef *ast.Cons
 }
 
 func (linker *_Linker) linkAnnotation(script *ast.Script, annotation *ast.Annotation) {
-	linker.linkNewObj(script, ast.NewNewObj2(annotation.Type, annotation.Args))
+	linker.linkObj(script, annotation.Type, annotation.Args)
+}
+
+// valueConstant get the enum constant whose value expr contains the expr,
+// returns false for the exprs out of the value expr, e.g. the constant annotation args
+func valueConstant(expr ast.Expr) (*ast.EnumConstant, bool) {
+
+	var child ast.Node = expr
+
+	for node := expr.Parent(); node != nil; child, node = node, node.Parent() {
+		if constant, ok := node.(*ast.EnumConstant); ok {
+			return constant, constant.Expr != nil && constant.Expr == child
+		}
+	}
+
+	return nil, false
 }
 
 func (linker *_Linker) linkEnum(script *ast.Script, enum *ast.Enum) {
 
+	for _, constant := range enum.Constants {
+		for _, annotation := range Annotations(constant) {
+			linker.linkAnnotation(script, annotation)
+		}
+
+		if constant.Expr != nil {
+			linker.linkExpr(script, constant.Expr)
+		}
+	}
+}
+
+// evalEnums eval the script enum constant values, then check them
+func (linker *_Linker) evalEnums(script *ast.Script) {
+
+	script.TypeForeach(func(typeDecl ast.Type) {
+
+		enum, ok := typeDecl.(*ast.Enum)
+
+		if !ok {
+			return
+		}
+
+		for _, constant := range enum.Constants {
+			linker.evalConstant(constant)
+		}
+
+		linker.checkEnumValues(enum)
+	})
+}
+
+// evalConstant eval enum constant value after the referenced constants, returns false if the value or the
+// referenced values can't be evaluated
+func (linker *_Linker) evalConstant(constant *ast.EnumConstant) bool {
+
+	if ok, visited := linker.evaluated[constant]; visited {
+		return ok
+	}
+
+	if linker.evaluating[constant] {
+		linker.errorf(ErrEval, constant, "enum constant(%s) value circular reference", constant)
+		return false
+	}
+
+	linker.evaluating[constant] = true
+
+	ok := linker.evalConstantValue(constant)
+
+	delete(linker.evaluating, constant)
+
+	linker.evaluated[constant] = ok
+
+	return ok
+}
+
+func (linker *_Linker) evalConstantValue(constant *ast.EnumConstant) bool {
+
+	enum := ast.EnclosingType(constant).(*ast.Enum)
+
+	var val int64
+
+	if constant.Expr == nil {
+
+		// the implicit value is the previous constant value plus one
+		for i, current := range enum.Constants[1:] {
+
+			if current != constant {
+				continue
+			}
+
+			previous := enum.Constants[i]
+
+			if !linker.evalConstant(previous) {
+				return false
+			}
+
+			val = previous.Value + 1
+		}
+
+	} else {
+
+		ok := true
+
+		ast.Inspect(constant.Expr, func(node ast.Node) bool {
+
+			if ref, isRef := node.(*ast.ConstantRef); isRef && ok {
+
+				// the unlinked references are reported by linkConstantRef
+				target, isConstant := ref.Value.(*ast.EnumConstant)
+
+				ok = isConstant && linker.evalConstant(target)
+			}
+
+			return ok
+		})
+
+		if !ok {
+			return false
+		}
+
+		val = linker.Eval().EvalInt(constant.Expr)
+	}
+
+	constant.Value = val
+
+	return true
+}
+
+// enum underlying integer types' value range, the constant values are int64,
+// so the uint64 values are limited to [0,math.MaxInt64]
+var enumRanges = map[lexer.TokenType]struct {
+	min, max int64
+}{
+	lexer.KeySByte:  {math.MinInt8, math.MaxInt8},
+	lexer.KeyByte:   {0, math.MaxUint8},
+	lexer.KeyInt16:  {math.MinInt16, math.MaxInt16},
+	lexer.KeyUInt16: {0, math.MaxUint16},
+	lexer.KeyInt32:  {math.MinInt32, math.MaxInt32},
+	lexer.KeyUInt32: {0, math.MaxUint32},
+	lexer.KeyInt64:  {math.MinInt64, math.MaxInt64},
+	lexer.KeyUInt64: {0, math.MaxInt64},
+}
+
+// checkEnumValues check the enum underlying type, the constant values must be in the underlying type range
+// and unique except the aliases of proto enum, the flag enum values must be power of two or combination of the other flags
+func (linker *_Linker) checkEnumValues(enum *ast.Enum) {
+
+	if enum.Underlying != nil {
+		builtin, ok := enum.Underlying.(*ast.BuiltinType)
+
+		if ok {
+			_, ok = enumRanges[builtin.Type]
+		}
+
+		if !ok {
+			linker.errorf(ErrType, enum.Underlying, "enum(%s) underlying type(%s) must be integer builtin type", enum, enum.Underlying)
+			return
+		}
+	}
+
+	underlying := EnumType(enum)
+
+	valueRange := enumRanges[underlying]
+
+	flag := IsFlag(enum)
+
+	if flag && valueRange.min < 0 {
+		linker.errorf(ErrType, enum.Underlying, "flag enum(%s) underlying type(%s) must be unsigned", enum, underlying)
+		return
+	}
+
+	values := make(map[int64]*ast.EnumConstant)
+
+	alias := ProtoAllowAlias(enum)
+
+	var bits int64
+
+	for _, constant := range enum.Constants {
+		if val := constant.Value; linker.evaluated[constant] && val > 0 && val&(val-1) == 0 {
+			bits |= val
+		}
+	}
+
+	for _, constant := range enum.Constants {
+
+		// the failed constants have been reported by evalConstant
+		if !linker.evaluated[constant] {
+			continue
+		}
+
+		val := constant.Value
+
+		if val < valueRange.min || val > valueRange.max {
+			linker.errorf(ErrType, constant, "enum(%s) constant(%s) value(%d) out of underlying type(%s) range [%d,%d]",
+				enum, constant, val, underlying, valueRange.min, valueRange.max)
+			continue
+		}
+
+		if previous, ok := values[constant.Value]; ok && !alias {
+			linker.errorf(ErrType, constant, "enum(%s) constant(%s) duplicate value(%d) with %s", enum, constant, val, previous)
+			continue
+		}
+
+		values[constant.Value] = constant
+
+		if flag && val&^bits != 0 {
+			linker.errorf(ErrType, constant, "flag enum(%s) constant(%s) value(%d) must be power of two or combination of the other flags",
+				enum, constant, val)
+		}
+	}
 }
 
 func (linker *_Linker) linkContract(script *ast.Script, contract *ast.Contract) {
@@ -414,6 +1174,8 @@ func (linker *_Linker) linkContract(script *ast.Script, contract *ast.Contract)
 
 		linker.linkType(script, method.Return)
 
+		linker.checkOptional(method.Return, false)
+
 		if IsAsync(method) {
 			if NotVoid(method.Return) {
 				linker.errorf(ErrAnnotation, method, "gslang.Async can't mark those method which's return type is not void")
@@ -422,6 +1184,39 @@ func (linker *_Linker) linkContract(script *ast.Script, contract *ast.Contract)
 			if method.Exceptions != nil {
 				linker.errorf(ErrAnnotation, method, "gslang.Async can't mark those method which has exception list")
 			}
+
+			if method.Results != nil {
+				linker.errorf(ErrAnnotation, method, "gslang.Async can't mark those method which has results")
+			}
+
+			if IsStream(method) {
+				linker.errorf(ErrAnnotation, method, "gslang.Async can't mark those streaming method")
+			}
+		}
+
+		if method.Stream&ast.StreamServer != 0 && IsVoid(method.Return) && method.Results == nil {
+			linker.errorf(ErrType, method, "method(%s) stream return can't be void", method)
+		}
+
+		// link method results
+		for _, result := range method.Results {
+
+			for _, annotation := range Annotations(result) {
+
+				linker.linkAnnotation(script, annotation)
+			}
+
+			linker.linkType(script, result.Type)
+
+			linker.checkOptional(result.Type, false)
+
+			if IsVoid(result.Type) {
+				linker.errorf(ErrType, result, "method(%s) result(%s) can't be void", method, result)
+			}
+
+			if _, ok := method.Param(result.Name()); ok {
+				linker.errorf(ErrType, result, "method(%s) result(%s) conflicts with param", method, result)
+			}
 		}
 
 		// link method params
@@ -433,6 +1228,12 @@ func (linker *_Linker) linkContract(script *ast.Script, contract *ast.Contract)
 			}
 
 			linker.linkType(script, param.Type)
+
+			linker.checkOptional(param.Type, false)
+
+			if param.Stream && param.ID != len(method.Params)-1 {
+				linker.errorf(ErrType, param, "method(%s) stream param(%s) must be the last param", method, param)
+			}
 		}
 
 		for _, exception := range method.Exceptions {
@@ -449,11 +1250,103 @@ func (linker *_Linker) linkContract(script *ast.Script, contract *ast.Contract)
 func (linker *_Linker) linkTable(script *ast.Script, table *ast.Table) {
 
 	for _, field := range table.Fields {
+		for _, annotation := range Annotations(field) {
+			linker.linkAnnotation(script, annotation)
+		}
+
 		linker.linkType(script, field.Type)
+
+		linker.checkOptional(field.Type, IsPOD(table))
+	}
+}
+
+func (linker *_Linker) linkUnion(script *ast.Script, union *ast.Union) {
+
+	// an empty union value can't be encoded by any case tag
+	if len(union.Cases) == 0 {
+		linker.errorf(ErrType, union, "union(%s) must declare at least one case", union)
+	}
+
+	tags := make(map[int]*ast.UnionCase)
+
+	types := make(map[string]*ast.UnionCase)
+
+	for _, unionCase := range union.Cases {
+		for _, annotation := range Annotations(unionCase) {
+			linker.linkAnnotation(script, annotation)
+		}
+
+		linker.linkType(script, unionCase.Type)
+
+		linker.checkOptional(unionCase.Type, false)
+
+		if unionCase.Tag > math.MaxUint16 {
+			linker.errorf(ErrType, unionCase, "union(%s) case(%s) tag(%d) out of range [0,%d]", union, unionCase, unionCase.Tag, math.MaxUint16)
+		}
+
+		if previous, ok := tags[unionCase.Tag]; ok {
+			linker.errorf(ErrType, unionCase, "duplicate union(%s) case tag(%d) : %s and %s", union, unionCase.Tag, previous, unionCase)
+		} else {
+			tags[unionCase.Tag] = unionCase
+		}
+
+		switch {
+		case IsVoid(unionCase.Type):
+			linker.errorf(ErrType, unionCase, "union(%s) case(%s) can't be void", union, unionCase)
+			continue
+		case IsOptional(unionCase.Type):
+			linker.errorf(ErrType, unionCase, "union(%s) case(%s) can't be optional", union, unionCase)
+			continue
+		}
+
+		if ref, ok := unionCase.Type.(*ast.TypeRef); ok && ref.Ref != nil {
+			if _, ok := ref.Ref.(*ast.Contract); ok {
+				linker.errorf(ErrType, unionCase, "union(%s) case(%s) can't be contract", union, unionCase)
+				continue
+			}
+		}
+
+		name := ast.TypeName(unionCase.Type)
+
+		if previous, ok := types[name]; ok {
+			linker.errorf(ErrType, unionCase, "duplicate union(%s) case type(%s) : %s and %s", union, name, previous, unionCase)
+		} else {
+			types[name] = unionCase
+		}
+	}
+}
+
+// checkOptional check optional types, which can't be contained by POD tables or fixed-size seqs
+func (linker *_Linker) checkOptional(typeDecl ast.Type, pod bool) {
+
+	switch typeDecl.(type) {
+	case *ast.Optional:
+
+		optional := typeDecl.(*ast.Optional)
+
+		if pod {
+			linker.errorf(ErrType, optional, "POD table(%s) can't contain optional type(%s)", ast.EnclosingType(optional), optional)
+		}
+
+		if IsVoid(optional.Component) {
+			linker.errorf(ErrType, optional, "void can't be optional")
+		}
+
+		linker.checkOptional(optional.Component, pod)
+
+	case *ast.Seq:
+
+		seq := typeDecl.(*ast.Seq)
+
+		if seq.Size > 0 && IsOptional(seq.Component) {
+			linker.errorf(ErrType, seq, "fixed-size seq(%s) can't contain optional type", seq)
+		}
+
+		linker.checkOptional(seq.Component, pod)
 	}
 }
 
-func (linker *_Linker) createSymbolTable(script *ast.Script) {
+func (linker *_Linker) createSymbolTable(script *ast.Script, report bool) {
 
 	linker.D("create global symoble table , search script defined types: %s", script)
 
@@ -461,7 +1354,10 @@ func (linker *_Linker) createSymbolTable(script *ast.Script) {
 		fullname := _fullName(script.Package, gslangType)
 
 		if previous, ok := linker.types[fullname]; ok {
-			linker.duplicateTypeDef(gslangType, previous)
+			if report {
+				linker.duplicateTypeDef(gslangType, previous)
+			}
+
 			return
 		}
 
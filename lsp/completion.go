@@ -0,0 +1,371 @@
+package lsp
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+)
+
+// _Completion completion context of the cursor
+type _Completion struct {
+	index     *_Index           // workspace index
+	script    *ast.Script       // cursor script, nil if the script failed before registering
+	line      int               // cursor line
+	character int               // cursor character
+	partial   string            // typed partial name under the cursor
+	items     []*CompletionItem // completion items
+	visited   map[string]bool   // visited labels
+}
+
+func (server *Server) completion(params json.RawMessage) (interface{}, error) {
+
+	var positionParams TextDocumentPositionParams
+
+	if err := unmarshal(params, &positionParams); err != nil {
+		return nil, err
+	}
+
+	if server.index == nil {
+		server.build()
+	}
+
+	file := URIToPath(positionParams.TextDocument.URI)
+
+	list := &CompletionList{Items: []*CompletionItem{}}
+
+	text, ok := server.source(file)
+
+	if !ok {
+		return list, nil
+	}
+
+	offset, ok := offsetOf(text, positionParams.Position)
+
+	if !ok {
+		return list, nil
+	}
+
+	prefix := text[:offset]
+
+	// scan the dotted name under the cursor
+	start := len(prefix)
+
+	for start > 0 && (isIdent(prefix[start-1]) || prefix[start-1] == '.') {
+		start--
+	}
+
+	word := prefix[start:]
+
+	completion := &_Completion{
+		index:     server.index,
+		script:    server.index.findScript(file),
+		line:      positionParams.Position.Line,
+		character: positionParams.Position.Character,
+		visited:   make(map[string]bool),
+	}
+
+	if dot := strings.LastIndex(word, "."); dot != -1 {
+
+		completion.partial = word[dot+1:]
+
+		completion.qualified(word[:dot])
+
+	} else {
+
+		completion.partial = word
+
+		switch {
+		case start > 0 && prefix[start-1] == '@':
+			completion.types(true)
+		default:
+			if name, ok := argsOwner(prefix[:start]); ok {
+				completion.labels(name)
+			}
+
+			completion.types(false)
+			completion.builtins()
+		}
+	}
+
+	list.Items = completion.items
+
+	return list, nil
+}
+
+// offsetOf convert LSP position to byte offset of text
+func offsetOf(text string, pos Position) (int, bool) {
+
+	offset := 0
+
+	for line := 0; line < pos.Line; line++ {
+
+		i := strings.IndexByte(text[offset:], '\n')
+
+		if i == -1 {
+			return 0, false
+		}
+
+		offset += i + 1
+	}
+
+	end := strings.IndexByte(text[offset:], '\n')
+
+	if end == -1 {
+		end = len(text) - offset
+	}
+
+	if pos.Character > end {
+		return offset + end, true
+	}
+
+	return offset + pos.Character, true
+}
+
+// argsOwner get the type name of the innermost unclosed args table if the cursor is at an arg start
+func argsOwner(prefix string) (string, bool) {
+
+	trimmed := strings.TrimRight(prefix, " \t\r\n")
+
+	if trimmed == "" || (trimmed[len(trimmed)-1] != '(' && trimmed[len(trimmed)-1] != ',') {
+		return "", false
+	}
+
+	depth := 0
+
+	for i := len(trimmed) - 1; i >= 0; i-- {
+
+		switch trimmed[i] {
+		case ')':
+			depth++
+		case '(':
+			if depth > 0 {
+				depth--
+				continue
+			}
+
+			end := strings.TrimRight(trimmed[:i], " \t")
+
+			start := len(end)
+
+			for start > 0 && (isIdent(end[start-1]) || end[start-1] == '.') {
+				start--
+			}
+
+			if start == len(end) {
+				return "", false
+			}
+
+			return end[start:], true
+
+		case ';', '{', '}':
+			return "", false
+		}
+	}
+
+	return "", false
+}
+
+// findScript find script by file name
+func (index *_Index) findScript(file string) *ast.Script {
+
+	var found *ast.Script
+
+	index.module.Foreach(func(script *ast.Script) bool {
+
+		if script.Name() == file {
+			found = script
+			return false
+		}
+
+		return true
+	})
+
+	return found
+}
+
+// resolve resolve type name with the same lookup order of the linker
+func (completion *_Completion) resolve(name string) (ast.Type, bool) {
+
+	if script := completion.script; script != nil {
+
+		if typeDecl, ok := script.Type(name); ok {
+			return typeDecl, true
+		}
+
+		var found ast.Type
+
+		script.UsingForeach(func(using *ast.Using) {
+			if using.Ref != nil && shortName(using.Name()) == name {
+				found = using.Ref
+			}
+		})
+
+		if found != nil {
+			return found, true
+		}
+	}
+
+	typeDecl, ok := completion.index.module.Types[name]
+
+	return typeDecl, ok
+}
+
+func shortName(fullname string) string {
+	return fullname[strings.LastIndex(fullname, ".")+1:]
+}
+
+func (completion *_Completion) add(label string, kind int, detail string) {
+
+	if completion.visited[label] || !strings.HasPrefix(label, completion.partial) {
+		return
+	}
+
+	completion.visited[label] = true
+
+	completion.items = append(completion.items, &CompletionItem{
+		Label:  label,
+		Kind:   kind,
+		Detail: detail,
+		TextEdit: &TextEdit{
+			Range: Range{
+				Start: Position{Line: completion.line, Character: completion.character - len(completion.partial)},
+				End:   Position{Line: completion.line, Character: completion.character},
+			},
+			NewText: label,
+		},
+	})
+}
+
+func kindOf(typeDecl ast.Type) int {
+
+	switch typeDecl.(type) {
+	case *ast.Enum:
+		return CompletionEnum
+	case *ast.Contract:
+		return CompletionInterface
+	}
+
+	return CompletionClass
+}
+
+func isAnnotation(typeDecl ast.Type) bool {
+
+	_, ok := gslang.FindAnnotation(typeDecl, "gslang.annotations.Usage")
+
+	return ok
+}
+
+// qualified complete enum constants of qualifier enum or the types of qualifier package
+func (completion *_Completion) qualified(qualifier string) {
+
+	if typeDecl, ok := completion.resolve(qualifier); ok {
+
+		if enum, ok := typeDecl.(*ast.Enum); ok {
+			for _, constant := range enum.Constants {
+				completion.add(constant.Name(), CompletionEnumMember, completion.index.signature(constant))
+			}
+		}
+
+		return
+	}
+
+	for _, name := range completion.typeNames() {
+
+		if !strings.HasPrefix(name, qualifier+".") {
+			continue
+		}
+
+		typeDecl := completion.index.module.Types[name]
+
+		completion.add(name[len(qualifier)+1:], kindOf(typeDecl), completion.index.signature(typeDecl))
+	}
+}
+
+// labels complete table field labels of named args table
+func (completion *_Completion) labels(name string) {
+
+	typeDecl, ok := completion.resolve(name)
+
+	if !ok {
+		return
+	}
+
+	table, ok := typeDecl.(*ast.Table)
+
+	if !ok {
+		return
+	}
+
+	for _, field := range table.Fields {
+		completion.add(field.Name()+":", CompletionField, completion.index.signature(field))
+	}
+}
+
+// types complete visible type names, script local and using names first
+func (completion *_Completion) types(annotationOnly bool) {
+
+	accept := func(typeDecl ast.Type) bool {
+		return typeDecl != nil && (!annotationOnly || isAnnotation(typeDecl))
+	}
+
+	if script := completion.script; script != nil {
+
+		var locals []ast.Type
+
+		script.TypeForeach(func(typeDecl ast.Type) {
+			locals = append(locals, typeDecl)
+		})
+
+		sort.Slice(locals, func(i, j int) bool {
+			return locals[i].Name() < locals[j].Name()
+		})
+
+		for _, typeDecl := range locals {
+			if accept(typeDecl) {
+				completion.add(typeDecl.Name(), kindOf(typeDecl), completion.index.signature(typeDecl))
+			}
+		}
+
+		var usings []*ast.Using
+
+		script.UsingForeach(func(using *ast.Using) {
+			usings = append(usings, using)
+		})
+
+		sort.Slice(usings, func(i, j int) bool {
+			return usings[i].Name() < usings[j].Name()
+		})
+
+		for _, using := range usings {
+			if accept(using.Ref) {
+				completion.add(shortName(using.Name()), kindOf(using.Ref), completion.index.signature(using.Ref))
+			}
+		}
+	}
+
+	for _, name := range completion.typeNames() {
+		if typeDecl := completion.index.module.Types[name]; accept(typeDecl) {
+			completion.add(name, kindOf(typeDecl), completion.index.signature(typeDecl))
+		}
+	}
+}
+
+func (completion *_Completion) builtins() {
+	for _, builtin := range builtins {
+		completion.add(builtin.String(), CompletionKeyword, "")
+	}
+}
+
+func (completion *_Completion) typeNames() (names []string) {
+
+	for name := range completion.index.module.Types {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return
+}
@@ -0,0 +1,503 @@
+package lsp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+// _Occurrence one source range referencing or declaring an ast node
+type _Occurrence struct {
+	File   string   // script file name
+	Range  Range    // source range
+	Target ast.Node // referenced node
+	Decl   bool     // declaration flag
+}
+
+// _Index source occurrence index of one linked workspace build
+type _Index struct {
+	module      *ast.Module                  // linked module
+	sources     map[string][]string          // script source lines
+	occurrences map[string][]*_Occurrence    // occurrences indexed by file name
+	decls       map[ast.Node]*_Occurrence    // declaration occurrence indexed by node
+	owners      map[ast.Node]ast.Type        // member owner types
+	enums       map[*ast.EnumConstant]string // enum constant owner full name
+	visited     map[string]bool              // visited occurrence keys
+}
+
+func newIndex(module *ast.Module, sources map[string][]string) *_Index {
+
+	index := &_Index{
+		module:      module,
+		sources:     sources,
+		occurrences: make(map[string][]*_Occurrence),
+		decls:       make(map[ast.Node]*_Occurrence),
+		owners:      make(map[ast.Node]ast.Type),
+		enums:       make(map[*ast.EnumConstant]string),
+		visited:     make(map[string]bool),
+	}
+
+	index.annotations(module)
+
+	module.Foreach(func(script *ast.Script) bool {
+		index.script(script)
+		return true
+	})
+
+	return index
+}
+
+// nameRange get the range of node name, the name is searched from the node start position
+func (index *_Index) nameRange(node ast.Node) (string, Range, bool) {
+
+	start, end := gslang.Pos(node)
+
+	if !start.Valid() {
+		return "", Range{}, false
+	}
+
+	name := node.Name()
+
+	if lines, ok := index.sources[start.FileName]; ok && start.Lines <= len(lines) {
+
+		line := lines[start.Lines-1]
+
+		offset := start.Column - 1
+
+		if offset >= 0 && offset <= len(line) {
+			if i := indexWord(line[offset:], name); i != -1 {
+				return start.FileName, Range{
+					Start: Position{Line: start.Lines - 1, Character: offset + i},
+					End:   Position{Line: start.Lines - 1, Character: offset + i + len(name)},
+				}, true
+			}
+		}
+	}
+
+	return start.FileName, toRange(start, end), true
+}
+
+// indexWord find the first whole word occurrence of name in text
+func indexWord(text string, name string) int {
+
+	for offset := 0; offset < len(text); {
+
+		i := strings.Index(text[offset:], name)
+
+		if i == -1 {
+			return -1
+		}
+
+		i += offset
+
+		if (i == 0 || !isIdent(text[i-1])) && (i+len(name) == len(text) || !isIdent(text[i+len(name)])) {
+			return i
+		}
+
+		offset = i + 1
+	}
+
+	return -1
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// prefixRange get the range of name written at node start position
+func prefixRange(node ast.Node, name string) (string, Range, bool) {
+
+	start, _ := gslang.Pos(node)
+
+	if !start.Valid() {
+		return "", Range{}, false
+	}
+
+	begin := toPosition(start)
+
+	return start.FileName, Range{
+		Start: begin,
+		End:   Position{Line: begin.Line, Character: begin.Character + len(name)},
+	}, true
+}
+
+func (index *_Index) add(file string, r Range, target ast.Node, decl bool) {
+
+	if target == nil || file == "" {
+		return
+	}
+
+	key := fmt.Sprintf("%s#%v", file, r)
+
+	// annotations moved to script or module are still attached to the orignal nodes
+	if index.visited[key] {
+		return
+	}
+
+	index.visited[key] = true
+
+	occurrence := &_Occurrence{
+		File:   file,
+		Range:  r,
+		Target: target,
+		Decl:   decl,
+	}
+
+	index.occurrences[file] = append(index.occurrences[file], occurrence)
+
+	if decl {
+		index.decls[target] = occurrence
+	}
+}
+
+func (index *_Index) declare(node ast.Node) {
+	if file, r, ok := index.nameRange(node); ok {
+		index.add(file, r, node, true)
+	}
+}
+
+func (index *_Index) script(script *ast.Script) {
+
+	script.UsingForeach(func(using *ast.Using) {
+
+		start, end := gslang.Pos(using)
+
+		if using.Ref != nil && start.Valid() {
+			index.add(start.FileName, toRange(start, end), using.Ref, false)
+		}
+
+		index.annotations(using)
+	})
+
+	index.annotations(script)
+
+	script.TypeForeach(func(typeDecl ast.Type) {
+		index.typeDecl(typeDecl)
+	})
+}
+
+func (index *_Index) typeDecl(typeDecl ast.Type) {
+
+	index.declare(typeDecl)
+
+	index.annotations(typeDecl)
+
+	switch typeDecl.(type) {
+	case *ast.Table:
+		for _, param := range typeDecl.(*ast.Table).TypeParams {
+
+			index.owners[param] = typeDecl
+
+			if file, r, ok := prefixRange(param, param.Name()); ok {
+				index.add(file, r, param, true)
+			}
+		}
+
+		for _, field := range typeDecl.(*ast.Table).Fields {
+
+			index.owners[field] = typeDecl
+
+			index.annotations(field)
+
+			index.typeRef(field.Type)
+
+			// field position starts at the field type and ends at the field name
+			_, end := gslang.Pos(field)
+
+			if end.Valid() {
+				index.add(end.FileName, Range{
+					Start: Position{Line: end.Lines - 1, Character: end.Column - 1 - len(field.Name())},
+					End:   toPosition(end),
+				}, field, true)
+			}
+		}
+
+	case *ast.Enum:
+		for _, constant := range typeDecl.(*ast.Enum).Constants {
+
+			index.owners[constant] = typeDecl
+
+			index.enums[constant] = typeDecl.FullName()
+
+			index.annotations(constant)
+
+			if file, r, ok := prefixRange(constant, constant.Name()); ok {
+				index.add(file, r, constant, true)
+			}
+		}
+
+	case *ast.Union:
+		for _, unionCase := range typeDecl.(*ast.Union).Cases {
+
+			index.owners[unionCase] = typeDecl
+
+			index.annotations(unionCase)
+
+			index.typeRef(unionCase.Type)
+
+			// case position starts at the case type and ends at the case name
+			_, end := gslang.Pos(unionCase)
+
+			if end.Valid() {
+				index.add(end.FileName, Range{
+					Start: Position{Line: end.Lines - 1, Character: end.Column - 1 - len(unionCase.Name())},
+					End:   toPosition(end),
+				}, unionCase, true)
+			}
+		}
+
+	case *ast.Contract:
+		for _, method := range typeDecl.(*ast.Contract).Methods {
+
+			index.owners[method] = typeDecl
+
+			index.annotations(method)
+
+			index.typeRef(method.Return)
+
+			index.declare(method)
+
+			for _, param := range method.Params {
+
+				index.annotations(param)
+
+				index.typeRef(param.Type)
+			}
+
+			for _, result := range method.Results {
+
+				index.annotations(result)
+
+				index.typeRef(result.Type)
+			}
+
+			for _, exception := range method.Exceptions {
+
+				index.annotations(exception)
+
+				index.typeRef(exception.Type)
+			}
+		}
+	}
+}
+
+func (index *_Index) typeRef(typeDecl ast.Type) {
+
+	switch typeDecl.(type) {
+	case *ast.TypeRef:
+
+		ref := typeDecl.(*ast.TypeRef)
+
+		start, end := gslang.Pos(ref)
+
+		if start.Valid() {
+			index.add(start.FileName, toRange(start, end), ref.Ref, false)
+		}
+
+		for _, arg := range ref.TypeArgs {
+			index.typeRef(arg)
+		}
+
+	case *ast.Seq:
+		index.typeRef(typeDecl.(*ast.Seq).Component)
+	case *ast.Optional:
+		index.typeRef(typeDecl.(*ast.Optional).Component)
+	}
+}
+
+func (index *_Index) annotations(node ast.Node) {
+
+	for _, annotation := range gslang.Annotations(node) {
+
+		if file, r, ok := prefixRange(annotation, annotation.Name()); ok {
+			index.add(file, r, annotation.Type.Ref, false)
+		}
+
+		if annotation.Args != nil {
+			index.expr(annotation.Args)
+		}
+	}
+}
+
+func (index *_Index) expr(expr ast.Expr) {
+
+	switch expr.(type) {
+	case *ast.ArgsTable:
+		for _, arg := range expr.(*ast.ArgsTable).Args() {
+			index.expr(arg)
+		}
+	case *ast.NamedArg:
+		index.expr(expr.(*ast.NamedArg).Arg)
+	case *ast.ConstantRef:
+
+		start, end := gslang.Pos(expr)
+
+		if constant := expr.(*ast.ConstantRef).Value; constant != nil && start.Valid() {
+			index.add(start.FileName, toRange(start, end), constant, false)
+		}
+
+	case *ast.NewObj:
+		newObj := expr.(*ast.NewObj)
+
+		if file, r, ok := prefixRange(newObj, newObj.Type.Name()); ok {
+			index.add(file, r, newObj.Type.Ref, false)
+		}
+
+		if newObj.Args != nil {
+			index.expr(newObj.Args)
+		}
+
+	case *ast.UnaryOp:
+		index.expr(expr.(*ast.UnaryOp).Operand)
+	case *ast.BinaryOp:
+		index.expr(expr.(*ast.BinaryOp).LHS)
+		index.expr(expr.(*ast.BinaryOp).RHS)
+	}
+}
+
+// lookup find the innermost occurrence containing the position
+func (index *_Index) lookup(file string, pos Position) (found *_Occurrence) {
+
+	for _, occurrence := range index.occurrences[file] {
+
+		if !occurrence.Range.contains(pos) {
+			continue
+		}
+
+		if found == nil || occurrence.Range.size() < found.Range.size() {
+			found = occurrence
+		}
+	}
+
+	return
+}
+
+// references get all occurrences referencing target node
+func (index *_Index) references(target ast.Node, includeDecl bool) (occurrences []*_Occurrence) {
+
+	for _, file := range sortedKeys(index.occurrences) {
+		for _, occurrence := range index.occurrences[file] {
+
+			if occurrence.Target != target || (occurrence.Decl && !includeDecl) {
+				continue
+			}
+
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	return
+}
+
+// signature get node hover signature
+func (index *_Index) signature(node ast.Node) string {
+
+	switch node.(type) {
+	case *ast.Table:
+		if _, ok := gslang.FindAnnotation(node, "gslang.annotations.Usage"); ok {
+			return "annotation " + node.(ast.Type).FullName()
+		}
+
+		return "table " + node.(ast.Type).FullName()
+	case *ast.Enum:
+		return "enum " + node.(ast.Type).FullName() + " : " + gslang.EnumType(node.(ast.Type)).String()
+	case *ast.Contract:
+		return "contract " + node.(ast.Type).FullName()
+	case *ast.Union:
+		return "union " + node.(ast.Type).FullName()
+	case *ast.TypeParam:
+		return "type param " + index.memberName(node)
+	case *ast.UnionCase:
+		return ast.TypeName(node.(*ast.UnionCase).Type) + " " + index.memberName(node) + "(" + strconv.Itoa(node.(*ast.UnionCase).Tag) + ")"
+	case *ast.Field:
+		return ast.TypeName(node.(*ast.Field).Type) + " " + index.memberName(node)
+	case *ast.EnumConstant:
+		return index.enums[node.(*ast.EnumConstant)] + "." + node.Name() + "(" + strconv.FormatInt(node.(*ast.EnumConstant).Value, 10) + ")"
+	case *ast.Method:
+		method := node.(*ast.Method)
+
+		var params []string
+
+		for _, param := range method.Params {
+			if param.Stream {
+				params = append(params, "stream "+ast.TypeName(param.Type)+" "+param.Name())
+			} else {
+				params = append(params, ast.TypeName(param.Type)+" "+param.Name())
+			}
+		}
+
+		returns := ast.TypeName(method.Return)
+
+		if len(method.Results) != 0 {
+
+			var results []string
+
+			for _, result := range method.Results {
+				results = append(results, ast.TypeName(result.Type)+" "+result.Name())
+			}
+
+			returns = "(" + strings.Join(results, ", ") + ")"
+		}
+
+		if method.Stream&ast.StreamServer != 0 {
+			returns = "stream " + returns
+		}
+
+		signature := returns + " " + index.memberName(node) + "(" + strings.Join(params, ", ") + ")"
+
+		if len(method.Exceptions) != 0 {
+
+			var exceptions []string
+
+			for _, exception := range method.Exceptions {
+				exceptions = append(exceptions, ast.TypeName(exception.Type))
+			}
+
+			signature += " throws (" + strings.Join(exceptions, ", ") + ")"
+		}
+
+		return signature
+	}
+
+	return node.String()
+}
+
+func (index *_Index) memberName(node ast.Node) string {
+
+	if owner, ok := index.owners[node]; ok {
+		return owner.FullName() + "." + node.Name()
+	}
+
+	return node.Name()
+}
+
+// comment get node doc comment
+func comment(node ast.Node) string {
+
+	val, ok := node.GetExtra(gslang.ExtraComment)
+
+	if !ok {
+		return ""
+	}
+
+	var lines []string
+
+	for _, line := range strings.Split(val.(*ast.Comment).String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// builtin type keywords
+var builtins = []lexer.TokenType{
+	lexer.KeyByte, lexer.KeySByte, lexer.KeyInt16, lexer.KeyUInt16,
+	lexer.KeyInt32, lexer.KeyUInt32, lexer.KeyInt64, lexer.KeyUInt64,
+	lexer.KeyFloat32, lexer.KeyFloat64, lexer.KeyString, lexer.KeyBool, lexer.KeyVoid,
+}
@@ -0,0 +1,350 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gsrpc/gslang/lexer"
+)
+
+// jsonrpc error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Message jsonrpc 2.0 message, request/response/notification share the same layout
+type Message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+// ResponseError jsonrpc error object
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *ResponseError) Error() string {
+	return err.Message
+}
+
+// Conn jsonrpc connection with LSP base protocol header framing
+type Conn struct {
+	sync.Mutex               // write lock
+	reader     *bufio.Reader // input reader
+	writer     io.Writer     // output writer
+}
+
+// NewConn create new LSP connection
+func NewConn(reader io.Reader, writer io.Writer) *Conn {
+	return &Conn{
+		reader: bufio.NewReader(reader),
+		writer: writer,
+	}
+}
+
+// Read read one message
+func (conn *Conn) Read() (*Message, error) {
+
+	length := -1
+
+	for {
+		line, err := conn.reader.ReadString('\n')
+
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			break
+		}
+
+		if strings.HasPrefix(line, "Content-Length:") {
+			length, err = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:")))
+
+			if err != nil {
+				return nil, &ResponseError{Code: codeParseError, Message: "illegal Content-Length header"}
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, &ResponseError{Code: codeParseError, Message: "missing Content-Length header"}
+	}
+
+	content := make([]byte, length)
+
+	if _, err := io.ReadFull(conn.reader, content); err != nil {
+		return nil, err
+	}
+
+	message := &Message{}
+
+	if err := json.Unmarshal(content, message); err != nil {
+		return nil, &ResponseError{Code: codeParseError, Message: err.Error()}
+	}
+
+	return message, nil
+}
+
+// Write write one message
+func (conn *Conn) Write(message *Message) error {
+
+	message.JSONRPC = "2.0"
+
+	content, err := json.Marshal(message)
+
+	if err != nil {
+		return err
+	}
+
+	conn.Lock()
+	defer conn.Unlock()
+
+	if _, err := fmt.Fprintf(conn.writer, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+
+	_, err = conn.writer.Write(content)
+
+	return err
+}
+
+// Notify send notification
+func (conn *Conn) Notify(method string, params interface{}) error {
+
+	content, err := json.Marshal(params)
+
+	if err != nil {
+		return err
+	}
+
+	return conn.Write(&Message{Method: method, Params: content})
+}
+
+// Call send request with id
+func (conn *Conn) Call(id int, method string, params interface{}) error {
+
+	content, err := json.Marshal(params)
+
+	if err != nil {
+		return err
+	}
+
+	raw := json.RawMessage(strconv.Itoa(id))
+
+	return conn.Write(&Message{ID: &raw, Method: method, Params: content})
+}
+
+// Position LSP zero based position
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range LSP range
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location LSP location
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// Diagnostic severities
+const (
+	SeverityError   = 1
+	SeverityWarning = 2
+)
+
+// Diagnostic LSP diagnostic
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// PublishDiagnosticsParams textDocument/publishDiagnostics params
+type PublishDiagnosticsParams struct {
+	URI         string        `json:"uri"`
+	Diagnostics []*Diagnostic `json:"diagnostics"`
+}
+
+// TextDocumentItem LSP text document
+type TextDocumentItem struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+// TextDocumentIdentifier LSP text document identifier
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// TextDocumentPositionParams LSP text document position params
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// ReferenceParams textDocument/references params
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	Context struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+// InitializeParams initialize params
+type InitializeParams struct {
+	RootURI               string `json:"rootUri"`
+	InitializationOptions struct {
+		Includes []string `json:"includes"` // include script files or directories, e.g. the gslang prelude
+	} `json:"initializationOptions"`
+}
+
+// DidOpenTextDocumentParams textDocument/didOpen params
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// DidChangeTextDocumentParams textDocument/didChange params, only full sync is supported
+type DidChangeTextDocumentParams struct {
+	TextDocument   TextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// DidCloseTextDocumentParams textDocument/didClose params
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DidSaveTextDocumentParams textDocument/didSave params
+type DidSaveTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// MarkupContent LSP markup content
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Hover textDocument/hover result
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// Completion item kinds
+const (
+	CompletionField      = 5
+	CompletionClass      = 7
+	CompletionInterface  = 8
+	CompletionKeyword    = 14
+	CompletionEnum       = 13
+	CompletionEnumMember = 20
+)
+
+// TextEdit LSP text edit
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// CompletionItem LSP completion item
+type CompletionItem struct {
+	Label    string    `json:"label"`
+	Kind     int       `json:"kind"`
+	Detail   string    `json:"detail,omitempty"`
+	TextEdit *TextEdit `json:"textEdit,omitempty"`
+}
+
+// CompletionList textDocument/completion result
+type CompletionList struct {
+	IsIncomplete bool              `json:"isIncomplete"`
+	Items        []*CompletionItem `json:"items"`
+}
+
+// toRange convert gslang source range to LSP range
+func toRange(start lexer.Position, end lexer.Position) Range {
+	return Range{
+		Start: toPosition(start),
+		End:   toPosition(end),
+	}
+}
+
+func toPosition(pos lexer.Position) Position {
+
+	position := Position{Line: pos.Lines - 1, Character: pos.Column - 1}
+
+	if position.Line < 0 {
+		position.Line = 0
+	}
+
+	if position.Character < 0 {
+		position.Character = 0
+	}
+
+	return position
+}
+
+// before check if position lhs is before or equal to rhs
+func (lhs Position) before(rhs Position) bool {
+	return lhs.Line < rhs.Line || (lhs.Line == rhs.Line && lhs.Character <= rhs.Character)
+}
+
+// contains check if range contains position
+func (r Range) contains(pos Position) bool {
+	return r.Start.before(pos) && pos.before(r.End)
+}
+
+// size get comparable range size
+func (r Range) size() int {
+	return (r.End.Line-r.Start.Line)*1<<16 + r.End.Character - r.Start.Character
+}
+
+// URIToPath convert file uri to file path
+func URIToPath(uri string) string {
+
+	parsed, err := url.Parse(uri)
+
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+
+	return filepath.FromSlash(parsed.Path)
+}
+
+// PathToURI convert file path to file uri
+func PathToURI(path string) string {
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
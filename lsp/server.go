@@ -0,0 +1,582 @@
+// Package lsp gslang language server over the LSP base protocol
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+)
+
+// errors
+var (
+	errAbort = errors.New("gslang lsp: compile aborted")
+)
+
+type _Handler func(server *Server, params json.RawMessage) (interface{}, error)
+
+var handlers = map[string]_Handler{
+	"initialize":              (*Server).initialize,
+	"initialized":             (*Server).initialized,
+	"shutdown":                (*Server).shutdown,
+	"textDocument/didOpen":    (*Server).didOpen,
+	"textDocument/didChange":  (*Server).didChange,
+	"textDocument/didClose":   (*Server).didClose,
+	"textDocument/didSave":    (*Server).didSave,
+	"textDocument/definition": (*Server).definition,
+	"textDocument/references": (*Server).references,
+	"textDocument/hover":      (*Server).hover,
+	"textDocument/completion": (*Server).completion,
+}
+
+// Server gslang language server
+type Server struct {
+	gslogger.Log                     // Mixin log
+	conn         *Conn               // client connection
+	root         string              // workspace root directory
+	includes     []string            // include scripts or directories
+	documents    map[string]string   // open documents indexed by file path
+	compiler     *gslang.Compiler    // workspace compiler
+	sources      map[string][]string // compiled script source lines
+	index        *_Index             // last build index
+	published    map[string]bool     // files with published diagnostics
+	exit         bool                // exit flag
+}
+
+// NewServer create new language server
+func NewServer() *Server {
+	return &Server{
+		Log:       gslogger.Get("lsp"),
+		documents: make(map[string]string),
+		published: make(map[string]bool),
+	}
+}
+
+// Serve serve one client until the exit notification or reader closed
+func (server *Server) Serve(reader io.Reader, writer io.Writer) error {
+
+	server.conn = NewConn(reader, writer)
+
+	for !server.exit {
+
+		message, err := server.conn.Read()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			if _, ok := err.(*ResponseError); ok {
+				server.E("%s", err)
+				continue
+			}
+
+			return err
+		}
+
+		if err := server.dispatch(message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (server *Server) dispatch(message *Message) error {
+
+	if message.Method == "exit" {
+		server.exit = true
+		return nil
+	}
+
+	handler, ok := handlers[message.Method]
+
+	// notification
+	if message.ID == nil {
+		if ok {
+			if _, err := server.call(handler, message.Params); err != nil {
+				server.E("handle notification %s error :%s", message.Method, err)
+			}
+		}
+
+		return nil
+	}
+
+	response := &Message{ID: message.ID}
+
+	if !ok {
+		response.Error = &ResponseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", message.Method)}
+		return server.conn.Write(response)
+	}
+
+	result, err := server.call(handler, message.Params)
+
+	if err != nil {
+
+		if rerr, ok := err.(*ResponseError); ok {
+			response.Error = rerr
+		} else {
+			response.Error = &ResponseError{Code: codeInternalError, Message: err.Error()}
+		}
+
+		return server.conn.Write(response)
+	}
+
+	response.Result, err = json.Marshal(result)
+
+	if err != nil {
+		return err
+	}
+
+	return server.conn.Write(response)
+}
+
+func (server *Server) call(handler _Handler, params json.RawMessage) (result interface{}, err error) {
+
+	defer func() {
+		if e := recover(); e != nil {
+			err = &ResponseError{Code: codeInternalError, Message: fmt.Sprintf("%v", e)}
+		}
+	}()
+
+	return handler(server, params)
+}
+
+func unmarshal(params json.RawMessage, val interface{}) error {
+	if err := json.Unmarshal(params, val); err != nil {
+		return &ResponseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (server *Server) initialize(params json.RawMessage) (interface{}, error) {
+
+	var initParams InitializeParams
+
+	if err := unmarshal(params, &initParams); err != nil {
+		return nil, err
+	}
+
+	if initParams.RootURI != "" {
+		server.root = URIToPath(initParams.RootURI)
+	}
+
+	for _, include := range initParams.InitializationOptions.Includes {
+
+		if !filepath.IsAbs(include) && server.root != "" {
+			include = filepath.Join(server.root, include)
+		}
+
+		server.includes = append(server.includes, include)
+	}
+
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":   1, // full document sync
+			"definitionProvider": true,
+			"referencesProvider": true,
+			"hoverProvider":      true,
+			"completionProvider": map[string]interface{}{
+				"triggerCharacters": []string{".", "@", "(", ","},
+			},
+		},
+		"serverInfo": map[string]interface{}{
+			"name": "gslang",
+		},
+	}, nil
+}
+
+func (server *Server) initialized(params json.RawMessage) (interface{}, error) {
+	server.build()
+	return nil, nil
+}
+
+func (server *Server) shutdown(params json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (server *Server) didOpen(params json.RawMessage) (interface{}, error) {
+
+	var openParams DidOpenTextDocumentParams
+
+	if err := unmarshal(params, &openParams); err != nil {
+		return nil, err
+	}
+
+	file := URIToPath(openParams.TextDocument.URI)
+
+	server.documents[file] = openParams.TextDocument.Text
+
+	server.update(file)
+
+	return nil, nil
+}
+
+func (server *Server) didChange(params json.RawMessage) (interface{}, error) {
+
+	var changeParams DidChangeTextDocumentParams
+
+	if err := unmarshal(params, &changeParams); err != nil {
+		return nil, err
+	}
+
+	if len(changeParams.ContentChanges) == 0 {
+		return nil, nil
+	}
+
+	// full sync, the last change holds the whole document
+	text := changeParams.ContentChanges[len(changeParams.ContentChanges)-1].Text
+
+	file := URIToPath(changeParams.TextDocument.URI)
+
+	server.documents[file] = text
+
+	server.update(file)
+
+	return nil, nil
+}
+
+func (server *Server) didClose(params json.RawMessage) (interface{}, error) {
+
+	var closeParams DidCloseTextDocumentParams
+
+	if err := unmarshal(params, &closeParams); err != nil {
+		return nil, err
+	}
+
+	file := URIToPath(closeParams.TextDocument.URI)
+
+	delete(server.documents, file)
+
+	// fallback to the file content on disk
+	server.update(file)
+
+	return nil, nil
+}
+
+func (server *Server) didSave(params json.RawMessage) (interface{}, error) {
+
+	var saveParams DidSaveTextDocumentParams
+
+	if err := unmarshal(params, &saveParams); err != nil {
+		return nil, err
+	}
+
+	server.update(URIToPath(saveParams.TextDocument.URI))
+
+	return nil, nil
+}
+
+// files get the build script list, include scripts first
+func (server *Server) files() (files []string) {
+
+	visited := make(map[string]bool)
+
+	add := func(file string) {
+
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+
+		if !visited[file] {
+			visited[file] = true
+			files = append(files, file)
+		}
+	}
+
+	walk := func(root string) {
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+			if err != nil {
+				server.W("walk %s error :%s", path, err)
+				return nil
+			}
+
+			if !info.IsDir() && filepath.Ext(path) == ".gs" {
+				add(path)
+			}
+
+			return nil
+		})
+	}
+
+	for _, include := range server.includes {
+		walk(include)
+	}
+
+	if server.root != "" {
+		walk(server.root)
+	}
+
+	for _, file := range sortedKeys(server.documents) {
+		add(file)
+	}
+
+	return
+}
+
+// source get script source, open documents override the file content on disk
+func (server *Server) source(file string) (string, bool) {
+
+	if text, ok := server.documents[file]; ok {
+		return text, true
+	}
+
+	content, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return "", false
+	}
+
+	return string(content), true
+}
+
+// build compile and link the whole workspace, then publish diagnostics
+func (server *Server) build() {
+
+	server.sources = make(map[string][]string)
+
+	server.compiler = gslang.NewCompiler("lsp", gslang.HandleError(func(err *gslang.Error) {
+		// the parser can't recover from syntax errors
+		if err.Stage != gslang.StageSemParing {
+			panic(errAbort)
+		}
+	}))
+
+	for _, file := range server.files() {
+
+		text, ok := server.source(file)
+
+		if !ok {
+			continue
+		}
+
+		server.sources[file] = strings.Split(text, "\n")
+
+		server.compiler.CompileSource(file, []byte(text))
+	}
+
+	if err := server.compiler.Link(); err != nil {
+		server.E("link workspace error :%s", err)
+	}
+
+	server.reindex()
+}
+
+// update recompile one changed script with the incremental compiler API
+func (server *Server) update(file string) {
+
+	if server.compiler == nil {
+		server.build()
+		return
+	}
+
+	if text, ok := server.source(file); ok {
+
+		server.sources[file] = strings.Split(text, "\n")
+
+		if err := server.compiler.Update(file, []byte(text)); err != nil {
+			server.D("update %s error :%s", file, err)
+		}
+
+	} else {
+
+		delete(server.sources, file)
+
+		if err := server.compiler.Remove(file); err != nil {
+			server.D("remove %s error :%s", file, err)
+		}
+	}
+
+	server.reindex()
+}
+
+func (server *Server) reindex() {
+
+	server.index = newIndex(server.compiler.Module(), server.sources)
+
+	server.publish()
+}
+
+func newDiagnostic(err *gslang.Error) *Diagnostic {
+
+	r := toRange(err.Start, err.End)
+
+	if !err.End.Valid() || !r.Start.before(r.End) || r.Start == r.End {
+		r.End = Position{Line: r.Start.Line, Character: r.Start.Character + 1}
+	}
+
+	severity := SeverityError
+
+	if err.Warning {
+		severity = SeverityWarning
+	}
+
+	return &Diagnostic{
+		Range:    r,
+		Severity: severity,
+		Source:   "gslang",
+		Message:  err.Text,
+	}
+}
+
+// publish publish diagnostics of open documents and the files with errors
+func (server *Server) publish() {
+
+	files := make(map[string]bool)
+
+	for file := range server.sources {
+		files[file] = true
+	}
+
+	for file := range server.published {
+		files[file] = true
+	}
+
+	for file := range server.documents {
+		files[file] = true
+	}
+
+	published := make(map[string]bool)
+
+	for _, file := range sortedKeys(files) {
+
+		diagnostics := []*Diagnostic{}
+
+		for _, err := range server.compiler.Diagnostics(file) {
+			diagnostics = append(diagnostics, newDiagnostic(err))
+		}
+
+		if _, ok := server.documents[file]; !ok && len(diagnostics) == 0 && !server.published[file] {
+			continue
+		}
+
+		if len(diagnostics) != 0 {
+			published[file] = true
+		}
+
+		err := server.conn.Notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+			URI:         PathToURI(file),
+			Diagnostics: diagnostics,
+		})
+
+		if err != nil {
+			server.E("publish diagnostics error :%s", err)
+		}
+	}
+
+	server.published = published
+}
+
+// lookup find occurrence under the cursor
+func (server *Server) lookup(params json.RawMessage) (*_Occurrence, error) {
+
+	var positionParams TextDocumentPositionParams
+
+	if err := unmarshal(params, &positionParams); err != nil {
+		return nil, err
+	}
+
+	if server.index == nil {
+		server.build()
+	}
+
+	return server.index.lookup(URIToPath(positionParams.TextDocument.URI), positionParams.Position), nil
+}
+
+func (server *Server) definition(params json.RawMessage) (interface{}, error) {
+
+	occurrence, err := server.lookup(params)
+
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+
+	decl, ok := server.index.decls[occurrence.Target]
+
+	if !ok {
+		return nil, nil
+	}
+
+	return &Location{URI: PathToURI(decl.File), Range: decl.Range}, nil
+}
+
+func (server *Server) references(params json.RawMessage) (interface{}, error) {
+
+	var referenceParams ReferenceParams
+
+	if err := unmarshal(params, &referenceParams); err != nil {
+		return nil, err
+	}
+
+	occurrence, err := server.lookup(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []*Location{}
+
+	if occurrence == nil {
+		return locations, nil
+	}
+
+	for _, reference := range server.index.references(occurrence.Target, referenceParams.Context.IncludeDeclaration) {
+		locations = append(locations, &Location{URI: PathToURI(reference.File), Range: reference.Range})
+	}
+
+	return locations, nil
+}
+
+func (server *Server) hover(params json.RawMessage) (interface{}, error) {
+
+	occurrence, err := server.lookup(params)
+
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+
+	text := "```gslang\n" + server.index.signature(occurrence.Target) + "\n```"
+
+	if doc := comment(occurrence.Target); doc != "" {
+		text += "\n\n" + doc
+	}
+
+	r := occurrence.Range
+
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: text},
+		Range:    &r,
+	}, nil
+}
+
+func sortedKeys(m interface{}) (keys []string) {
+
+	switch m.(type) {
+	case map[string]string:
+		for key := range m.(map[string]string) {
+			keys = append(keys, key)
+		}
+	case map[string]bool:
+		for key := range m.(map[string]bool) {
+			keys = append(keys, key)
+		}
+	case map[string][]*_Occurrence:
+		for key := range m.(map[string][]*_Occurrence) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return
+}
@@ -0,0 +1,542 @@
+// Package openapi convert gslang tables, enums and contracts to JSON Schema
+// definitions and OpenAPI operations
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+// OpenAPI document version
+const Version = "3.0.3"
+
+// Schema JSON Schema object
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	OneOf       []*Schema          `json:"oneOf,omitempty"`
+	Deprecated  bool               `json:"deprecated,omitempty"`
+}
+
+// MediaType OpenAPI media type object
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody OpenAPI request body object
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// Response OpenAPI response object
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Operation OpenAPI operation object
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+}
+
+// PathItem OpenAPI path item object
+type PathItem struct {
+	Post *Operation `json:"post,omitempty"`
+}
+
+// Info OpenAPI info object
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components OpenAPI components object
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Document OpenAPI document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Write write document as indented json
+func (document *Document) Write(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(document)
+}
+
+// Generator gslang codegen backend, implement gslang.Visitor
+type Generator struct {
+	gslogger.Log           // Mixin Log
+	document     *Document // generated document
+}
+
+// NewGenerator create new openapi backend
+func NewGenerator(title string, version string) *Generator {
+	return &Generator{
+		Log: gslogger.Get("openapi"),
+		document: &Document{
+			OpenAPI: Version,
+			Info: Info{
+				Title:   title,
+				Version: version,
+			},
+			Paths: make(map[string]*PathItem),
+			Components: Components{
+				Schemas: make(map[string]*Schema),
+			},
+		},
+	}
+}
+
+// Generate generate openapi document for compiler's linked module
+func Generate(compiler *gslang.Compiler, title string, version string) (*Document, error) {
+
+	gen := NewGenerator(title, version)
+
+	if err := compiler.Visit(gen); err != nil {
+		return nil, err
+	}
+
+	return gen.Document(), nil
+}
+
+// Document get generated openapi document
+func (gen *Generator) Document() *Document {
+	return gen.document
+}
+
+// BeginScript implement gslang.Visitor
+func (gen *Generator) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+	return true
+}
+
+// Using implement gslang.Visitor
+func (gen *Generator) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+// Annotation implement gslang.Visitor, annotations have no schema
+func (gen *Generator) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+// EndScript implement gslang.Visitor
+func (gen *Generator) EndScript(compiler *gslang.Compiler) {
+}
+
+// Table implement gslang.Visitor
+func (gen *Generator) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	// generic table declarations have no schema, the instances are generated on reference
+	if gslang.IsGeneric(tableType) {
+		return
+	}
+
+	gen.D("generate table(%s) schema", tableType.FullName())
+
+	schema := &Schema{
+		Type:        "object",
+		Description: description(tableType),
+		Properties:  make(map[string]*Schema),
+		Deprecated:  gslang.IsDeprecated(tableType),
+	}
+
+	// registered before the fields, so the recursive instances are generated once
+	gen.document.Components.Schemas[tableType.FullName()] = schema
+
+	for _, field := range tableType.Fields {
+
+		property := gen.typeSchema(field.Type)
+
+		// $ref siblings are ignored by openapi, so the description is dropped for references
+		if property.Ref == "" {
+			property.Description = description(field)
+			property.Deprecated = gslang.IsDeprecated(field)
+			constrain(property, field)
+		}
+
+		schema.Properties[field.Name()] = property
+
+		if !gslang.IsOptional(field.Type) {
+			schema.Required = append(schema.Required, field.Name())
+		}
+	}
+}
+
+// Enum implement gslang.Visitor
+func (gen *Generator) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+
+	gen.D("generate enum(%s) schema", enum.FullName())
+
+	schema := &Schema{
+		Description: description(enum),
+		Deprecated:  gslang.IsDeprecated(enum),
+	}
+
+	// flag enum values are bit combinations, which can't be listed as names
+	if gslang.IsFlag(enum) {
+		schema.Type = "integer"
+		schema.Format = integers[gslang.EnumType(enum)].format
+	} else {
+		schema.Type = "string"
+
+		for _, constant := range enum.Constants {
+			schema.Enum = append(schema.Enum, constant.Name())
+		}
+	}
+
+	gen.document.Components.Schemas[enum.FullName()] = schema
+}
+
+// Union implement gslang.Visitor, each case is an object with the single case property
+func (gen *Generator) Union(compiler *gslang.Compiler, union *ast.Union) {
+
+	gen.D("generate union(%s) schema", union.FullName())
+
+	schema := &Schema{
+		Description: description(union),
+		Deprecated:  gslang.IsDeprecated(union),
+	}
+
+	for _, unionCase := range union.Cases {
+
+		property := gen.typeSchema(unionCase.Type)
+
+		if property.Ref == "" {
+			property.Description = description(unionCase)
+			property.Deprecated = gslang.IsDeprecated(unionCase)
+		}
+
+		schema.OneOf = append(schema.OneOf, &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{unionCase.Name(): property},
+			Required:   []string{unionCase.Name()},
+		})
+	}
+
+	gen.document.Components.Schemas[union.FullName()] = schema
+}
+
+// Contract implement gslang.Visitor
+func (gen *Generator) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+
+	gen.D("generate contract(%s) operations", contract.FullName())
+
+	for _, method := range contract.Methods {
+
+		operation := &Operation{
+			OperationID: contract.Name() + "_" + method.Name(),
+			Summary:     description(method),
+			Tags:        []string{gslang.ServiceName(contract)},
+			Responses:   make(map[string]*Response),
+			Deprecated:  gslang.IsDeprecated(method) || gslang.IsDeprecated(contract),
+		}
+
+		if len(method.Params) > 0 {
+
+			params := &Schema{
+				Type:       "object",
+				Properties: make(map[string]*Schema),
+			}
+
+			for _, param := range method.Params {
+
+				property := gen.typeSchema(param.Type)
+
+				if property.Ref == "" {
+					property.Description = description(param)
+					constrain(property, param)
+				}
+
+				params.Properties[param.Name()] = property
+
+				if !gslang.IsOptional(param.Type) {
+					params.Required = append(params.Required, param.Name())
+				}
+			}
+
+			operation.RequestBody = &RequestBody{
+				Required: true,
+				Content:  content(params, method.Stream&ast.StreamClient != 0),
+			}
+		}
+
+		switch {
+		case gslang.IsAsync(method):
+			operation.Responses["202"] = &Response{Description: "accepted"}
+		case len(method.Results) > 0:
+
+			results := &Schema{
+				Type:       "object",
+				Properties: make(map[string]*Schema),
+			}
+
+			for _, result := range method.Results {
+
+				property := gen.typeSchema(result.Type)
+
+				if property.Ref == "" {
+					property.Description = description(result)
+				}
+
+				results.Properties[result.Name()] = property
+
+				if !gslang.IsOptional(result.Type) {
+					results.Required = append(results.Required, result.Name())
+				}
+			}
+
+			operation.Responses["200"] = &Response{
+				Description: "success",
+				Content:     content(results, method.Stream&ast.StreamServer != 0),
+			}
+		case gslang.IsVoid(method.Return):
+			operation.Responses["204"] = &Response{Description: "success"}
+		default:
+			operation.Responses["200"] = &Response{
+				Description: "success",
+				Content:     content(gen.typeSchema(method.Return), method.Stream&ast.StreamServer != 0),
+			}
+		}
+
+		if len(method.Exceptions) > 0 {
+
+			var exceptions []*Schema
+
+			var names []string
+
+			for _, exception := range method.Exceptions {
+				exceptions = append(exceptions, gen.typeSchema(exception.Type))
+				names = append(names, exception.Name())
+			}
+
+			schema := exceptions[0]
+
+			if len(exceptions) > 1 {
+				schema = &Schema{OneOf: exceptions}
+			}
+
+			operation.Responses["500"] = &Response{
+				Description: "exception : " + strings.Join(names, ","),
+				Content:     jsonContent(schema),
+			}
+		}
+
+		gen.document.Paths[Path(contract, method)] = &PathItem{Post: operation}
+	}
+}
+
+// Path get method's http path
+func Path(contract *ast.Contract, method *ast.Method) string {
+	return fmt.Sprintf("/%s/%s", contract.FullName(), method.Name())
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{
+		"application/json": {Schema: schema},
+	}
+}
+
+// content returns the streamed values as newline delimited json
+func content(schema *Schema, stream bool) map[string]*MediaType {
+	if stream {
+		return map[string]*MediaType{
+			"application/x-ndjson": {Schema: schema},
+		}
+	}
+
+	return jsonContent(schema)
+}
+
+// integer builtin types' json schema format and range
+var integers = map[lexer.TokenType]struct {
+	format   string
+	min, max float64
+}{
+	lexer.KeySByte:  {"int8", -1 << 7, 1<<7 - 1},
+	lexer.KeyByte:   {"uint8", 0, 1<<8 - 1},
+	lexer.KeyInt16:  {"int16", -1 << 15, 1<<15 - 1},
+	lexer.KeyUInt16: {"uint16", 0, 1<<16 - 1},
+	lexer.KeyInt32:  {"int32", -1 << 31, 1<<31 - 1},
+	lexer.KeyUInt32: {"uint32", 0, 1<<32 - 1},
+	lexer.KeyInt64:  {"int64", 0, 0},
+	lexer.KeyUInt64: {"uint64", 0, 0},
+}
+
+func (gen *Generator) typeSchema(typeDecl ast.Type) *Schema {
+
+	switch typeDecl.(type) {
+	case *ast.TypeRef:
+		ref := typeDecl.(*ast.TypeRef)
+
+		if ref.Ref == nil {
+			return &Schema{Ref: "#/components/schemas/" + ref.Name()}
+		}
+
+		if instance, ok := gslang.Instantiate(ref); ok {
+
+			if _, ok := gen.document.Components.Schemas[instance.FullName()]; !ok {
+				gen.Table(nil, instance)
+			}
+
+			return &Schema{Ref: "#/components/schemas/" + instance.FullName()}
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + ref.Ref.FullName()}
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if builtin, ok := seq.Component.(*ast.BuiltinType); ok && builtin.Type == lexer.KeyByte {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		schema := &Schema{
+			Type:  "array",
+			Items: gen.typeSchema(seq.Component),
+		}
+
+		if seq.Size > 0 {
+			size := seq.Size
+			schema.MinItems = &size
+			schema.MaxItems = &size
+		}
+
+		if seq.Bound > 0 {
+			bound := seq.Bound
+			schema.MaxItems = &bound
+		}
+
+		return schema
+
+	case *ast.Optional:
+		// optional values are excluded from the object required list
+		return gen.typeSchema(typeDecl.(*ast.Optional).Component)
+
+	case *ast.BuiltinType:
+		builtin := typeDecl.(*ast.BuiltinType)
+
+		switch builtin.Type {
+		case lexer.KeyString:
+			schema := &Schema{Type: "string"}
+
+			if builtin.Bound > 0 {
+				bound := builtin.Bound
+				schema.MaxLength = &bound
+			}
+
+			return schema
+		case lexer.KeyBool:
+			return &Schema{Type: "boolean"}
+		case lexer.KeyFloat32:
+			return &Schema{Type: "number", Format: "float"}
+		case lexer.KeyFloat64:
+			return &Schema{Type: "number", Format: "double"}
+		}
+
+		if integer, ok := integers[builtin.Type]; ok {
+
+			schema := &Schema{Type: "integer", Format: integer.format}
+
+			if integer.min != integer.max {
+				min, max := integer.min, integer.max
+				schema.Minimum = &min
+				schema.Maximum = &max
+			}
+
+			return schema
+		}
+	}
+
+	gen.W("unsupport type(%s) schema", typeDecl)
+
+	return &Schema{}
+}
+
+// constrain apply the validation constraints of field or param to its schema
+func constrain(schema *Schema, node ast.Node) {
+
+	// the illegal constraints are reported by linker
+	set, err := gslang.Constraints(node)
+
+	if err != nil || set.Empty() {
+		return
+	}
+
+	if set.Min != nil {
+		schema.Minimum = set.Min
+	}
+
+	if set.Max != nil {
+		schema.Maximum = set.Max
+	}
+
+	schema.Pattern = set.Pattern
+
+	min, max := set.MinLength, set.MaxLength
+
+	switch {
+	case schema.Type == "array":
+		if min > 0 {
+			schema.MinItems = &min
+		}
+
+		if max > 0 {
+			schema.MaxItems = &max
+		}
+	case schema.Type == "string" && schema.Format != "byte":
+		if min > 0 {
+			schema.MinLength = &min
+		}
+
+		if max > 0 {
+			schema.MaxLength = &max
+		}
+	}
+}
+
+// description get node's attached doc comment
+func description(node ast.Node) string {
+
+	val, ok := node.GetExtra(gslang.ExtraComment)
+
+	if !ok {
+		return ""
+	}
+
+	return strings.TrimSpace(val.(*ast.Comment).String())
+}
@@ -32,7 +32,17 @@ func (compiler *Compiler) parse(lexer *lexer.Lexer, errorHandler ErrorHandler) *
 func (parser *Parser) peek() *lexer.Token {
 	token, err := parser.lexer.Peek()
 	if err != nil {
-		panic(err)
+		parser.lexerError(err)
+	}
+
+	return token
+}
+
+// peekN peek the n-th next token, peekN(0) equals peek
+func (parser *Parser) peekN(n int) *lexer.Token {
+	token, err := parser.lexer.PeekN(n)
+	if err != nil {
+		parser.lexerError(err)
 	}
 
 	return token
@@ -41,12 +51,30 @@ func (parser *Parser) peek() *lexer.Token {
 func (parser *Parser) next() (token *lexer.Token) {
 	token, err := parser.lexer.Next()
 	if err != nil {
-		panic(err)
+		parser.lexerError(err)
 	}
 
 	return token
 }
 
+// lexerError report lexer error and abort parsing
+func (parser *Parser) lexerError(err error) {
+
+	position := parser.lexer.Position()
+
+	errinfo := &Error{
+		Stage:   StageLexer,
+		Orignal: err,
+		Start:   position,
+		End:     position,
+		Text:    err.Error(),
+	}
+
+	parser.errorHandler.HandleError(errinfo)
+
+	panic(err)
+}
+
 func (parser *Parser) errorf(position lexer.Position, fmtstring string, args ...interface{}) {
 
 	errinfo := &Error{
@@ -79,7 +107,59 @@ func (parser *Parser) expectf(expect lexer.TokenType, fmtstring string, args ...
 		token := parser.next()
 
 		if token.Type != expect {
-			parser.errorf(token.Start, fmt.Sprintf("current token(%s) \n%s", token.Type, fmt.Sprintf(fmtstring, args...)))
+			parser.errorf(token.Start, "current token(%s) \n%s", token.Type, fmt.Sprintf(fmtstring, args...))
+			continue
+		}
+
+		return token
+	}
+}
+
+// isKeyword check if token is contextual keyword, contextual keywords such as union, stream and
+// the proto keywords are plain ids, which are legal names out of the keyword positions
+func (parser *Parser) isKeyword(token *lexer.Token, keyword string) bool {
+	return token.Type == lexer.TokenID && token.Value.(string) == keyword
+}
+
+// isStreamModifier check if the next token is the contextual stream modifier,
+// rather than the type or the name called stream
+func (parser *Parser) isStreamModifier() bool {
+
+	if !parser.isKeyword(parser.peek(), "stream") {
+		return false
+	}
+
+	token := parser.peekN(1)
+
+	switch token.Type {
+	case lexer.TokenType('('):
+		// stream (results) name
+		return true
+	case lexer.KeyByte, lexer.KeySByte, lexer.KeyInt16, lexer.KeyUInt16,
+		lexer.KeyInt32, lexer.KeyUInt32, lexer.KeyInt64, lexer.KeyUInt64,
+		lexer.KeyFloat32, lexer.KeyFloat64, lexer.KeyString, lexer.KeyBool, lexer.KeyVoid:
+		return true
+	case lexer.TokenID:
+		// stream type name, otherwise the type called stream is followed by method or param name
+		switch parser.peekN(2).Type {
+		case lexer.TokenType('('), lexer.TokenType(','), lexer.TokenType(')'):
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// expectKeyword expect contextual keyword, see isKeyword
+func (parser *Parser) expectKeyword(keyword string, fmtstring string, args ...interface{}) *lexer.Token {
+
+	for {
+		token := parser.next()
+
+		if !parser.isKeyword(token, keyword) {
+			parser.errorf(token.Start, "current token(%s) \n%s", token.Type, fmt.Sprintf(fmtstring, args...))
 			continue
 		}
 
@@ -114,6 +194,11 @@ func (parser *Parser) parseType() bool {
 
 	token := parser.peek()
 
+	if parser.isKeyword(token, "union") {
+		parser.expectUnion("expect union type define")
+		return true
+	}
+
 	switch token.Type {
 	case lexer.KeyTable:
 		parser.expectTable("expect table type define")
@@ -170,11 +255,22 @@ func (parser *Parser) expectEnum(fmtstring string, args ...interface{}) *ast.Enu
 		parser.errorf(token.Start, "%s\n\tduplicate enum(%s) defined", msg, name)
 	}
 
+	if parser.peek().Type == lexer.TokenType(':') {
+
+		parser.next()
+
+		underlying := parser.expectTypeDecl("expect enum(%s) underlying type", name)
+
+		underlying.SetParent(enum)
+
+		enum.(*ast.Enum).Underlying = underlying
+	}
+
 	parser.expectf(lexer.TokenType('{'), "contract body must start with {")
 
 	for {
 
-		for parser.parseComment() {
+		for parser.parseAnnotation() {
 
 		}
 
@@ -190,6 +286,8 @@ func (parser *Parser) expectEnum(fmtstring string, args ...interface{}) *ast.Enu
 
 		constant, ok := enum.(*ast.Enum).NewConstant(name)
 
+		parser.attachAnnotation(constant)
+
 		if !ok {
 			parser.errorf(token.Start, "%s\n\tduplicate enum(%s) contract(%s) defined", msg, enum, name)
 		}
@@ -202,9 +300,9 @@ func (parser *Parser) expectEnum(fmtstring string, args ...interface{}) *ast.Enu
 
 			parser.next()
 
-			val := int32(parser.expectf(lexer.TokenINT, "expect constant value").Value.(int64))
+			constant.Expr = parser.expectExpr("expect enum constant value")
 
-			constant.Value = val
+			constant.Expr.SetParent(constant)
 
 			end = parser.expectf(lexer.TokenType(')'), "enum constant val must end with )").End
 		}
@@ -277,8 +375,6 @@ func (parser *Parser) expectTable(fmtstring string, args ...interface{}) *ast.Ta
 
 	name := token.Value.(string)
 
-	parser.expectf(lexer.TokenType('{'), "table body must start with {")
-
 	table, ok := parser.script.NewTable(name)
 
 	parser.attachAnnotation(table)
@@ -289,6 +385,10 @@ func (parser *Parser) expectTable(fmtstring string, args ...interface{}) *ast.Ta
 		parser.errorf(token.Start, "%s\n\tduplicate table(%s) defined", msg, name)
 	}
 
+	parser.parseTypeParams(table.(*ast.Table))
+
+	parser.expectf(lexer.TokenType('{'), "table body must start with {")
+
 	for parser.parseFieldDecl(table.(*ast.Table)) {
 
 	}
@@ -304,6 +404,72 @@ func (parser *Parser) expectTable(fmtstring string, args ...interface{}) *ast.Ta
 	return table.(*ast.Table)
 }
 
+func (parser *Parser) expectUnion(fmtstring string, args ...interface{}) *ast.Union {
+
+	msg := fmt.Sprintf(fmtstring, args...)
+
+	start := parser.expectKeyword("union", "expect keyword union").Start
+
+	token := parser.expectf(lexer.TokenID, "expect union name")
+
+	name := token.Value.(string)
+
+	parser.expectf(lexer.TokenType('{'), "union body must start with {")
+
+	union, ok := parser.script.NewUnion(name)
+
+	parser.attachAnnotation(union)
+
+	parser.D("parse union %s", name)
+
+	if !ok {
+		parser.errorf(token.Start, "%s\n\tduplicate union(%s) defined", msg, name)
+	}
+
+	for parser.parseCaseDecl(union.(*ast.Union)) {
+
+	}
+
+	end := parser.expectf(lexer.TokenType('}'), "union body must end with }").End
+
+	_setNodePos(union, start, end)
+
+	parser.attachComment(union)
+
+	parser.D("parse union %s -- success", name)
+
+	return union.(*ast.Union)
+}
+
+func (parser *Parser) parseTypeParams(table *ast.Table) {
+
+	if parser.peek().Type != lexer.TokenType('<') {
+		return
+	}
+
+	parser.next()
+
+	for {
+		token := parser.expectf(lexer.TokenID, "expect table(%s) type param name", table)
+
+		param, ok := table.NewTypeParam(token.Value.(string))
+
+		if !ok {
+			parser.errorf(token.Start, "duplicate table(%s) type param(%s)", table, param)
+		}
+
+		_setNodePos(param, token.Start, token.End)
+
+		if parser.peek().Type != lexer.TokenType(',') {
+			break
+		}
+
+		parser.next()
+	}
+
+	parser.expectf(lexer.TokenType('>'), "table(%s) type params must end with >", table)
+}
+
 func (parser *Parser) attachAnnotation(node ast.Node) {
 
 	if parser.annotationStack != nil {
@@ -334,7 +500,7 @@ func (parser *Parser) attachComment(node ast.Node) {
 }
 
 func (parser *Parser) parseMethodDecl(contract *ast.Contract) bool {
-	for parser.parseAnnotation() {
+	for parser.parseMethodAnnotation() {
 
 	}
 
@@ -342,7 +508,33 @@ func (parser *Parser) parseMethodDecl(contract *ast.Contract) bool {
 
 	if token.Type != lexer.TokenType('}') {
 
-		returnVal := parser.expectTypeDecl("expect method return type")
+		var returnVal ast.Type
+
+		var results []func(method *ast.Method)
+
+		var stream ast.StreamMode
+
+		if parser.isStreamModifier() {
+			parser.next()
+			stream = ast.StreamServer
+		}
+
+		if parser.peek().Type == lexer.TokenType('(') {
+
+			// the method annotations are attached after the results
+			annotations := parser.annotationStack
+
+			parser.annotationStack = nil
+
+			results = parser.parseResults()
+
+			parser.annotationStack = annotations
+
+			returnVal = ast.NewBuiltinType(lexer.KeyVoid)
+
+		} else {
+			returnVal = parser.expectTypeDecl("expect method return type")
+		}
 
 		tokenName := parser.expectf(lexer.TokenID, "expect method name")
 
@@ -360,6 +552,14 @@ func (parser *Parser) parseMethodDecl(contract *ast.Contract) bool {
 
 		method.Return = returnVal
 
+		method.Stream = stream
+
+		returnVal.SetParent(method)
+
+		for _, result := range results {
+			result(method)
+		}
+
 		parser.parseParams(method)
 
 		parser.parseExceptions(method)
@@ -390,10 +590,16 @@ func (parser *Parser) parseExceptions(method *ast.Method) {
 
 	for {
 
+		for parser.parseAnnotation() {
+
+		}
+
 		typeDecl := parser.expectTypeDecl("expect exception type")
 
 		exception := method.NewException(typeDecl)
 
+		parser.attachAnnotation(exception)
+
 		start, end := Pos(typeDecl)
 
 		_setNodePos(exception, start, end)
@@ -412,7 +618,77 @@ func (parser *Parser) parseExceptions(method *ast.Method) {
 
 func (parser *Parser) parseParams(method *ast.Method) {
 
-	parser.expectf(lexer.TokenType('('), "method param table must start with (")
+	parser.parseParamList("param", func(token *lexer.Token, nameToken *lexer.Token, typeDecl ast.Type, stream bool, comment *ast.Comment) {
+
+		name := nameToken.Value.(string)
+
+		param, ok := method.NewParam(name, typeDecl)
+
+		if stream {
+			param.Stream = true
+			method.Stream |= ast.StreamClient
+		}
+
+		parser.attachAnnotation(param)
+
+		if !ok {
+			parser.errorf(token.Start, "duplicate method(%s) param(%s)", method, name)
+		}
+
+		_setNodePos(param, token.Start, nameToken.End)
+
+		if comment != nil {
+			param.SetExtra(ExtraComment, comment)
+		}
+	})
+}
+
+// parseResults parse method results list, which is parsed before the method name,
+// so the results are created by the returned funcs after the method declared
+func (parser *Parser) parseResults() (results []func(method *ast.Method)) {
+
+	parser.parseParamList("result", func(token *lexer.Token, nameToken *lexer.Token, typeDecl ast.Type, stream bool, comment *ast.Comment) {
+
+		if stream {
+			parser.errorf(token.Start, "result(%s) can't be stream, mark the method results as stream instead", nameToken.Value)
+		}
+
+		annotations := parser.annotationStack
+
+		parser.annotationStack = nil
+
+		results = append(results, func(method *ast.Method) {
+
+			name := nameToken.Value.(string)
+
+			result, ok := method.NewResult(name, typeDecl)
+
+			if !ok {
+				parser.errorf(token.Start, "duplicate method(%s) result(%s)", method, name)
+			}
+
+			if annotations != nil {
+				_AttachAnnotation(result, annotations...)
+			}
+
+			_setNodePos(result, token.Start, nameToken.End)
+
+			if comment != nil {
+				result.SetExtra(ExtraComment, comment)
+			}
+		})
+	})
+
+	return
+}
+
+// parseParamList parse ([stream] type name, ...) list, f is called for each entry with the entry annotations in the stack
+// and the comment declared before the entry
+func (parser *Parser) parseParamList(kind string, f func(token *lexer.Token, nameToken *lexer.Token, typeDecl ast.Type, stream bool, comment *ast.Comment)) {
+
+	parser.expectf(lexer.TokenType('('), "method %s table must start with (", kind)
+
+	depth := len(parser.commentStack)
 
 	for {
 
@@ -426,21 +702,25 @@ func (parser *Parser) parseParams(method *ast.Method) {
 
 		}
 
-		typeDecl := parser.expectTypeDecl("expect method param type declare")
+		stream := parser.isStreamModifier()
 
-		nameToken := parser.expectf(lexer.TokenID, "expect method param name")
+		if stream {
+			parser.next()
+		}
 
-		name := nameToken.Value.(string)
+		typeDecl := parser.expectTypeDecl("expect method %s type declare", kind)
 
-		param, ok := method.NewParam(name, typeDecl)
+		nameToken := parser.expectf(lexer.TokenID, "expect method %s name", kind)
 
-		parser.attachAnnotation(param)
+		// only the comments inside the list document the entry, the outer ones belong to the method
+		var comment *ast.Comment
 
-		if !ok {
-			parser.errorf(token.Start, "duplicate method(%s) param(%s)", method, name)
+		if len(parser.commentStack) > depth {
+			comment = parser.commentStack[len(parser.commentStack)-1]
+			parser.commentStack = parser.commentStack[:depth]
 		}
 
-		_setNodePos(param, token.Start, nameToken.End)
+		f(token, nameToken, typeDecl, stream, comment)
 
 		token = parser.peek()
 
@@ -451,7 +731,7 @@ func (parser *Parser) parseParams(method *ast.Method) {
 		parser.next()
 	}
 
-	parser.expectf(lexer.TokenType(')'), "method param table must end with )")
+	parser.expectf(lexer.TokenType(')'), "method %s table must end with )", kind)
 }
 
 func (parser *Parser) parseFieldDecl(table *ast.Table) bool {
@@ -490,6 +770,51 @@ func (parser *Parser) parseFieldDecl(table *ast.Table) bool {
 	return false
 }
 
+func (parser *Parser) parseCaseDecl(union *ast.Union) bool {
+
+	token := parser.peek()
+
+	if token.Type != lexer.TokenType('}') {
+
+		for parser.parseAnnotation() {
+
+		}
+
+		typeDecl := parser.expectTypeDecl("expect union(%s) case type declare", union)
+
+		tokenName := parser.expectf(lexer.TokenID, "expect union(%s) case name", union)
+
+		name := tokenName.Value.(string)
+
+		unionCase, ok := union.NewCase(name, typeDecl)
+
+		if !ok {
+			parser.errorf(token.Start, "duplicate union(%s) case(%s)", union, name)
+		}
+
+		if parser.peek().Type == lexer.TokenType('(') {
+
+			parser.next()
+
+			unionCase.Tag = int(parser.expectf(lexer.TokenINT, "expect union(%s) case tag", union).Value.(int64))
+
+			parser.expectf(lexer.TokenType(')'), "union case tag must end with )")
+		}
+
+		parser.expectf(lexer.TokenType(';'), "expect union(%s) case end tag ;", union)
+
+		parser.attachAnnotation(unionCase)
+
+		_setNodePos(unionCase, token.Start, tokenName.End)
+
+		parser.attachComment(unionCase)
+
+		return true
+	}
+
+	return false
+}
+
 func (parser *Parser) expectTypeDecl(fmtstring string, args ...interface{}) (typeDecl ast.Type) {
 
 	msg := fmt.Sprintf(fmtstring, args...)
@@ -502,18 +827,31 @@ func (parser *Parser) expectTypeDecl(fmtstring string, args ...interface{}) (typ
 			lexer.KeyInt32, lexer.KeyUInt32, lexer.KeyInt64, lexer.KeyUInt64,
 			lexer.KeyFloat32, lexer.KeyFloat64, lexer.KeyString, lexer.KeyBool, lexer.KeyVoid:
 
-			typeDecl = ast.NewBuiltinType(token.Type)
-
-			_setNodePos(typeDecl, token.Start, token.End)
+			builtin := ast.NewBuiltinType(token.Type)
 
 			parser.next()
 
+			end := token.End
+
+			if bound, boundEnd, ok := parser.parseBound(); ok {
+				builtin.Bound = bound
+				end = boundEnd
+			}
+
+			_setNodePos(builtin, token.Start, end)
+
+			typeDecl = builtin
+
 		case lexer.TokenID:
 			name, star, end := parser.expectFullName("expect type declare")
 
-			typeDecl = ast.NewTypeRef(name)
+			typeRef := ast.NewTypeRef(name)
+
+			_setNodePos(typeRef, star, end)
 
-			_setNodePos(typeDecl, star, end)
+			parser.parseTypeArgs(typeRef)
+
+			typeDecl = typeRef
 
 		default:
 			parser.errorf(token.Start, "%s\n\tunexpect token %s", msg, token)
@@ -527,6 +865,11 @@ func (parser *Parser) expectTypeDecl(fmtstring string, args ...interface{}) (typ
 				continue
 			}
 
+			if optionalType, ok := parser.parseOptional(typeDecl); ok {
+				typeDecl = optionalType
+				continue
+			}
+
 			break
 		}
 
@@ -535,6 +878,70 @@ func (parser *Parser) expectTypeDecl(fmtstring string, args ...interface{}) (typ
 
 }
 
+func (parser *Parser) parseTypeArgs(typeRef *ast.TypeRef) {
+
+	if parser.peek().Type != lexer.TokenType('<') {
+		return
+	}
+
+	parser.next()
+
+	for {
+		typeRef.NewTypeArg(parser.expectTypeDecl("expect type(%s) type arg", typeRef.Name()))
+
+		if parser.peek().Type != lexer.TokenType(',') {
+			break
+		}
+
+		parser.next()
+	}
+
+	parser.expectf(lexer.TokenType('>'), "type(%s) type args must end with >", typeRef.Name())
+}
+
+func (parser *Parser) parseOptional(component ast.Type) (typeDecl ast.Type, ok bool) {
+
+	token := parser.peek()
+
+	if token.Type != lexer.TokenType('?') {
+		return nil, false
+	}
+
+	parser.next()
+
+	if _, ok := component.(*ast.Optional); ok {
+		parser.errorf(token.Start, "duplicate optional type modifier ?")
+	}
+
+	typeDecl = ast.NewOptional(component)
+
+	start, _ := Pos(component)
+
+	_setNodePos(typeDecl, start, token.End)
+
+	return typeDecl, true
+}
+
+// parseBound parse the optional max length bound <=N, returns the bound and its end position
+func (parser *Parser) parseBound() (bound int, end lexer.Position, ok bool) {
+
+	if parser.peek().Type != lexer.TokenType('<') {
+		return
+	}
+
+	parser.next()
+
+	parser.expectf(lexer.TokenType('='), "bound must be declared as <=N")
+
+	token := parser.expectf(lexer.TokenINT, "expect bound max length")
+
+	if token.Value.(int64) <= 0 {
+		parser.errorf(token.Start, "bound max length must be greater than 0")
+	}
+
+	return int(token.Value.(int64)), token.End, true
+}
+
 func (parser *Parser) parseSeq(component ast.Type) (typeDecl ast.Type, ok bool) {
 
 	token := parser.peek()
@@ -553,7 +960,11 @@ func (parser *Parser) parseSeq(component ast.Type) (typeDecl ast.Type, ok bool)
 		parser.next()
 		typeDecl = ast.NewSeq(component, int(token.Value.(int64)))
 	} else {
-		typeDecl = ast.NewSeq(component, -1)
+		seq := ast.NewSeq(component, -1)
+
+		seq.Bound, _, _ = parser.parseBound()
+
+		typeDecl = seq
 	}
 
 	end := parser.expectf(lexer.TokenType(']'), "seq type must end with ]").End
@@ -704,7 +1115,7 @@ func (parser *Parser) parseArg() ast.Expr {
 		parser.next()
 		numeric := parser.expectNumeric("unary op %s expect numeric object", lexer.OpSub)
 
-		numeric.Val = -numeric.Val
+		numeric.Negate()
 
 		_, end := Pos(numeric)
 
@@ -731,7 +1142,7 @@ func (parser *Parser) expectExpr(fmtStr string, args ...interface{}) ast.Expr {
 		case lexer.TokenINT:
 			parser.next()
 
-			expr = ast.NewNumeric(float64(token.Value.(int64)))
+			expr = ast.NewInteger(token.Value.(int64))
 
 			_setNodePos(expr, token.Start, token.End)
 
@@ -743,6 +1154,20 @@ func (parser *Parser) expectExpr(fmtStr string, args ...interface{}) ast.Expr {
 
 			_setNodePos(expr, token.Start, token.End)
 
+		case lexer.OpSub:
+
+			parser.next()
+
+			end := parser.peek().End
+
+			numeric := parser.expectNumeric("unary op %s expect numeric object", lexer.OpSub)
+
+			numeric.Negate()
+
+			_setNodePos(numeric, token.Start, end)
+
+			expr = numeric
+
 		case lexer.TokenSTRING:
 			parser.next()
 
@@ -823,7 +1248,7 @@ func (parser *Parser) expectNumeric(fmtStr string, args ...interface{}) (number
 		parser.D("expect numeric :%s", token)
 
 		if token.Type == lexer.TokenINT {
-			number = ast.NewNumeric(float64(token.Value.(int64)))
+			number = ast.NewInteger(token.Value.(int64))
 		} else if token.Type == lexer.TokenFLOAT {
 			number = ast.NewNumeric(token.Value.(float64))
 		}
@@ -872,6 +1297,15 @@ func (parser *Parser) expectFullName(fmtstring string, args ...interface{}) (str
 }
 
 func (parser *Parser) parseAnnotation() bool {
+	return parser.annotation(false)
+}
+
+// parseMethodAnnotation parse method annotation, which may be followed by the method results list
+func (parser *Parser) parseMethodAnnotation() bool {
+	return parser.annotation(true)
+}
+
+func (parser *Parser) annotation(method bool) bool {
 
 	for parser.parseComment() {
 	}
@@ -894,13 +1328,15 @@ func (parser *Parser) parseAnnotation() bool {
 
 	token = parser.peek()
 
-	if token.Type == lexer.TokenType('(') {
+	if token.Type == lexer.TokenType('(') && !(method && parser.isResults()) {
 
 		args := parser.expectArgsTable("expect annotation arg table")
 
 		_, end = Pos(args)
 
 		annotation.Args = args
+
+		args.SetParent(annotation)
 	}
 
 	_setNodePos(annotation, start, end)
@@ -910,6 +1346,29 @@ func (parser *Parser) parseAnnotation() bool {
 	return true
 }
 
+// isResults check if the next '(' opens the method results list, not the annotation args table :
+// the matching ')' of the results list is followed by the method name and the params list
+func (parser *Parser) isResults() bool {
+
+	depth := 0
+
+	for n := 0; ; n++ {
+
+		switch parser.peekN(n).Type {
+		case lexer.TokenType('('):
+			depth++
+		case lexer.TokenType(')'):
+			depth--
+
+			if depth == 0 {
+				return parser.peekN(n+1).Type == lexer.TokenID && parser.peekN(n+2).Type == lexer.TokenType('(')
+			}
+		case lexer.TokenEOF:
+			return false
+		}
+	}
+}
+
 func (parser *Parser) parseComment() bool {
 
 	token := parser.peek()
@@ -975,7 +1434,8 @@ func (parser *Parser) popTailComment() (*ast.Comment, bool) {
 
 func (parser *Parser) parseImport() bool {
 
-	for parser.parseComment() {
+	// annotations before the first type are kept for the type
+	for parser.parseAnnotation() {
 	}
 
 	token := parser.peek()
@@ -992,6 +1452,8 @@ func (parser *Parser) parseImport() bool {
 
 	using := parser.script.Using(usingNamePath)
 
+	parser.attachAnnotation(using)
+
 	parser.expectf(lexer.TokenType(';'), "import name path must end with ';'")
 
 	parser.D("parse using :%s", using)
@@ -0,0 +1,1253 @@
+package gslang
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+// proto import annotation types, defined in proto.gs
+const (
+	ProtoFileOption = "gslang.proto.FileOption"
+	ProtoOption     = "gslang.proto.Option"
+	ProtoTag        = "gslang.proto.Tag"
+	ProtoOneOf      = "gslang.proto.OneOf"
+)
+
+// proto scalar types mapping
+var protoScalars = map[string]lexer.TokenType{
+	"double":   lexer.KeyFloat64,
+	"float":    lexer.KeyFloat32,
+	"int32":    lexer.KeyInt32,
+	"sint32":   lexer.KeyInt32,
+	"sfixed32": lexer.KeyInt32,
+	"int64":    lexer.KeyInt64,
+	"sint64":   lexer.KeyInt64,
+	"sfixed64": lexer.KeyInt64,
+	"uint32":   lexer.KeyUInt32,
+	"fixed32":  lexer.KeyUInt32,
+	"uint64":   lexer.KeyUInt64,
+	"fixed64":  lexer.KeyUInt64,
+	"bool":     lexer.KeyBool,
+	"string":   lexer.KeyString,
+}
+
+// _ProtoRef unresolved proto type reference
+type _ProtoRef struct {
+	slot  *ast.Type      // the type slot to fill with resolved reference
+	name  string         // proto type name as written in source
+	scope string         // proto scope the reference appears in
+	start lexer.Position // reference start position
+	end   lexer.Position // reference end position
+}
+
+// _ProtoParser parse proto3 script into gslang ast, reusing the gslang
+// parser's token and comment handling
+type _ProtoParser struct {
+	*Parser                     // mixin gslang parser
+	compiler  *Compiler         // compiler
+	includes  []string          // proto import search paths
+	pkg       string            // proto package name
+	options   []*ast.Annotation // proto file options
+	refs      []*_ProtoRef      // unresolved type references
+	filepath  string            // proto source file path
+	lastToken *lexer.Token      // last consumed token
+}
+
+// ImportProto import proto3 schema file and it's dependencies into compiler's module,
+// the imported types can be linked and referenced as gslang types. The types of the proto file
+// without package statement are declared in the package named by the file name, see protoDefaultPackage
+func (compiler *Compiler) ImportProto(filepath string, includes ...string) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+
+			gserr, ok := e.(gserrors.GSError)
+
+			if ok {
+				err = gserr
+			} else if origin, ok := e.(error); ok {
+				err = gserrors.Newf(origin, "catch unknown error")
+			} else {
+				err = gserrors.Newf(ErrParser, "catch unknown error :%v", e)
+			}
+		}
+	}()
+
+	return compiler.importProto(filepath, includes)
+}
+
+// ProtoAllowAlias check if the imported proto enum is declared with option allow_alias = true,
+// which constants can share the same value
+func ProtoAllowAlias(enum *ast.Enum) bool {
+
+	for _, annotation := range FindAnnotations(enum, ProtoOption) {
+
+		var option struct {
+			Name  *string
+			Value *string
+		}
+
+		if DecodeAnnotation(annotation, &option) != nil || option.Name == nil || option.Value == nil {
+			continue
+		}
+
+		if *option.Name == "allow_alias" && *option.Value == "true" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (compiler *Compiler) importProto(path string, includes []string) error {
+
+	fullpath, err := filepath.Abs(path)
+
+	if err != nil {
+		return err
+	}
+
+	if compiler.protoFiles[fullpath] {
+		return nil
+	}
+
+	compiler.protoFiles[fullpath] = true
+
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	parser := &_ProtoParser{
+		Parser: &Parser{
+			Log:          gslogger.Get("proto"),
+			lexer:        lexer.NewLexer(path, bytes.NewBuffer(content)),
+			errorHandler: compiler.errorHandler,
+		},
+		compiler: compiler,
+		includes: append([]string{filepath.Dir(path)}, includes...),
+		filepath: path,
+	}
+
+	parser.parse()
+
+	return nil
+}
+
+func (parser *_ProtoParser) next() *lexer.Token {
+	parser.lastToken = parser.Parser.next()
+
+	return parser.lastToken
+}
+
+func (parser *_ProtoParser) expectf(expect lexer.TokenType, fmtstring string, args ...interface{}) *lexer.Token {
+	parser.lastToken = parser.Parser.expectf(expect, fmtstring, args...)
+
+	return parser.lastToken
+}
+
+func (parser *_ProtoParser) parse() {
+
+	for parser.parseComment() {
+	}
+
+	// the package statement may appear after syntax and import statements,
+	// so parse the header before creating the script
+	parser.parseHeader()
+
+	parser.script = parser.compiler.module.NewScript(parser.filepath)
+
+	parser.script.Package = parser.pkg
+
+	// the gslang types must be declared in package
+	if parser.pkg == "" {
+		parser.script.Package = protoDefaultPackage(parser.filepath)
+	}
+
+	for parser.parseDecl() {
+	}
+
+	parser.resolve()
+
+	parser.annotationStack = parser.options
+
+	parser.attachAnnotation(parser.script)
+
+	parser.attachComment(parser.script)
+}
+
+func (parser *_ProtoParser) parseHeader() {
+
+	for {
+		for parser.parseComment() {
+		}
+
+		token := parser.peek()
+
+		switch {
+		case token.Type == lexer.TokenType(';'):
+			parser.next()
+			continue
+		case token.Type == lexer.KeyPackage:
+			parser.next()
+			parser.pkg, _, _ = parser.expectFullIdent("expect proto package name")
+			parser.expectf(lexer.TokenType(';'), "package statement must end with ';'")
+			continue
+		case parser.isKeyword(token, "syntax"):
+			parser.next()
+			parser.expectf(lexer.TokenType('='), "expect syntax '='")
+			syntax := parser.expectf(lexer.TokenSTRING, "expect syntax version string")
+			if syntax.Value.(string) != "proto3" {
+				parser.errorf(syntax.Start, "unsupport proto syntax(%s), expect proto3", syntax.Value)
+			}
+			parser.expectf(lexer.TokenType(';'), "syntax statement must end with ';'")
+			continue
+		case parser.isKeyword(token, "import"):
+			parser.parseImport()
+			continue
+		case parser.isKeyword(token, "option"):
+			parser.options = append(parser.options, parser.parseOption(ProtoFileOption))
+			continue
+		}
+
+		break
+	}
+}
+
+func (parser *_ProtoParser) parseImport() {
+
+	parser.next()
+
+	token := parser.peek()
+
+	if parser.isKeyword(token, "public") || parser.isKeyword(token, "weak") {
+		parser.next()
+	}
+
+	token = parser.expectf(lexer.TokenSTRING, "expect import file path")
+
+	parser.expectf(lexer.TokenType(';'), "import statement must end with ';'")
+
+	name := token.Value.(string)
+
+	for _, include := range parser.includes {
+
+		path := filepath.Join(include, name)
+
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		parser.D("import proto %s", path)
+
+		if err := parser.compiler.importProto(path, parser.includes[1:]); err != nil {
+			parser.errorf2(err, token.Start, "import proto(%s) error :%s", path, err)
+		}
+
+		return
+	}
+
+	parser.errorf2(ErrTypeNotFound, token.Start, "import proto(%s) -- not found", name)
+}
+
+func (parser *_ProtoParser) parseDecl() bool {
+
+	for parser.parseComment() {
+	}
+
+	token := parser.peek()
+
+	switch {
+	case token.Type == lexer.TokenEOF:
+		return false
+	case token.Type == lexer.TokenType(';'):
+		parser.next()
+	case token.Type == lexer.KeyEnum:
+		parser.parseEnum(parser.pkg, nil)
+	case parser.isKeyword(token, "message"):
+		parser.parseMessage(parser.pkg, nil)
+	case parser.isKeyword(token, "service"):
+		parser.parseService()
+	case parser.isKeyword(token, "extend"):
+		parser.D("skip proto extend statement")
+		parser.skipStatement()
+	case parser.isKeyword(token, "option"):
+		parser.options = append(parser.options, parser.parseOption(ProtoFileOption))
+	case parser.isKeyword(token, "import"), token.Type == lexer.KeyPackage, parser.isKeyword(token, "syntax"):
+		parser.errorf(token.Start, "proto syntax/package/import statement must be placed before type declarations")
+		parser.skipStatement()
+	default:
+		parser.errorf(token.Start, "unexpect token\n%s", token)
+		parser.next()
+	}
+
+	return true
+}
+
+// protoDefaultPackage get gslang package of the proto file without package statement,
+// which is the file name without extension, e.g. common for common.proto
+func protoDefaultPackage(path string) string {
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	pkg := strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+
+		return '_'
+	}, name)
+
+	if pkg == "" || unicode.IsDigit(rune(pkg[0])) {
+		pkg = "_" + pkg
+	}
+
+	return pkg
+}
+
+// protoFullName get proto full name of type path in package
+func protoFullName(pkg string, path []string) string {
+	if pkg == "" {
+		return strings.Join(path, ".")
+	}
+
+	return pkg + "." + strings.Join(path, ".")
+}
+
+// declare record proto type name to gslang type full name mapping
+func (parser *_ProtoParser) declare(path []string, typeDecl ast.Type) {
+	parser.compiler.protoTypes[protoFullName(parser.pkg, path)] = typeDecl.FullName()
+}
+
+func (parser *_ProtoParser) parseMessage(scope string, path []string) {
+
+	start := parser.next().Start
+
+	token := parser.expectIdent("expect message name")
+
+	name := token.Value.(string)
+
+	path = append(path[:len(path):len(path)], name)
+
+	parser.D("parse message %s", protoFullName(parser.pkg, path))
+
+	table, ok := parser.script.NewTable(strings.Join(path, "_"))
+
+	if !ok {
+		parser.errorf(token.Start, "duplicate message(%s) defined", protoFullName(parser.pkg, path))
+	}
+
+	parser.declare(path, table)
+
+	scope = protoFullName(parser.pkg, path)
+
+	parser.expectf(lexer.TokenType('{'), "message body must start with {")
+
+	var options []*ast.Annotation
+
+	for parser.parseMessageBody(scope, path, table.(*ast.Table), &options) {
+	}
+
+	end := parser.expectf(lexer.TokenType('}'), "message body must end with }").End
+
+	parser.annotationStack = options
+
+	parser.attachAnnotation(table)
+
+	_setNodePos(table, start, end)
+
+	parser.attachComment(table)
+}
+
+func (parser *_ProtoParser) parseMessageBody(scope string, path []string, table *ast.Table, options *[]*ast.Annotation) bool {
+
+	for parser.parseComment() {
+	}
+
+	token := parser.peek()
+
+	switch {
+	case token.Type == lexer.TokenType('}'):
+		return false
+	case token.Type == lexer.TokenType(';'):
+		parser.next()
+	case token.Type == lexer.KeyEnum:
+		parser.parseEnum(scope, path)
+	case parser.isKeyword(token, "message"):
+		parser.parseMessage(scope, path)
+	case parser.isKeyword(token, "option"):
+		*options = append(*options, parser.parseOption(ProtoOption))
+	case parser.isKeyword(token, "oneof"):
+		parser.parseOneOf(scope, path, table)
+	case parser.isKeyword(token, "reserved"), parser.isKeyword(token, "extensions"), parser.isKeyword(token, "extend"):
+		parser.D("skip proto %s statement", token.Value)
+		parser.skipStatement()
+	case token.Type == lexer.KeyMap:
+		parser.parseMapField(scope, path, table)
+	case token.Type == lexer.TokenEOF:
+		parser.errorf(token.Start, "message body must end with }")
+		return false
+	default:
+		parser.parseField(scope, table, "")
+	}
+
+	return true
+}
+
+func (parser *_ProtoParser) parseOneOf(scope string, path []string, table *ast.Table) {
+
+	parser.next()
+
+	name := parser.expectIdent("expect oneof name").Value.(string)
+
+	parser.expectf(lexer.TokenType('{'), "oneof body must start with {")
+
+	for {
+		for parser.parseComment() {
+		}
+
+		token := parser.peek()
+
+		if token.Type == lexer.TokenType('}') || token.Type == lexer.TokenEOF {
+			break
+		}
+
+		if parser.isKeyword(token, "option") {
+			// oneof options has no gslang counterpart
+			parser.parseOption(ProtoOption)
+			continue
+		}
+
+		if token.Type == lexer.TokenType(';') {
+			parser.next()
+			continue
+		}
+
+		parser.parseField(scope, table, name)
+	}
+
+	parser.expectf(lexer.TokenType('}'), "oneof body must end with }")
+}
+
+func (parser *_ProtoParser) parseField(scope string, table *ast.Table, oneof string) {
+
+	start := parser.peek().Start
+
+	repeated, optional := false, false
+
+	if token := parser.peek(); parser.isKeyword(token, "repeated") {
+		parser.next()
+		repeated = true
+	} else if parser.isKeyword(token, "optional") {
+		parser.next()
+		optional = true
+	} else if parser.isKeyword(token, "required") {
+		parser.next()
+	}
+
+	elem, ref := parser.expectFieldType(scope)
+
+	var fieldType = elem
+
+	var slot *ast.Type
+
+	if repeated {
+		seq := ast.NewSeq(elem, -1)
+		seqStart, _ := Pos(elem)
+		_setNodePos(seq, seqStart, parser.lastToken.End)
+		fieldType = seq
+		slot = &seq.Component
+	}
+
+	// proto3 explicit presence fields
+	if optional {
+		optionalType := ast.NewOptional(elem)
+		optionalStart, _ := Pos(elem)
+		_setNodePos(optionalType, optionalStart, parser.lastToken.End)
+		fieldType = optionalType
+		slot = &optionalType.Component
+	}
+
+	nameToken := parser.expectIdent("expect message(%s) field name", table)
+
+	name := nameToken.Value.(string)
+
+	field, ok := table.NewField(name, fieldType)
+
+	if !ok {
+		parser.errorf(nameToken.Start, "duplicate message(%s) field(%s)", table, name)
+	}
+
+	if slot == nil {
+		slot = &field.Type
+	}
+
+	if ref != nil {
+		ref.slot = slot
+		parser.refs = append(parser.refs, ref)
+	}
+
+	parser.parseFieldTail(field, nameToken, oneof)
+
+	_setNodePos(field, start, nameToken.End)
+
+	parser.attachComment(field)
+}
+
+func (parser *_ProtoParser) parseMapField(scope string, path []string, table *ast.Table) {
+
+	start := parser.next().Start
+
+	parser.expectf(lexer.TokenType('<'), "expect map key type start with <")
+
+	key, _ := parser.expectFieldType(scope)
+
+	parser.expectf(lexer.TokenType(','), "expect map key/value separator ,")
+
+	val, ref := parser.expectFieldType(scope)
+
+	parser.expectf(lexer.TokenType('>'), "expect map value type end with >")
+
+	nameToken := parser.expectIdent("expect message(%s) field name", table)
+
+	name := nameToken.Value.(string)
+
+	// map field is encoded as repeated entry message like protoc does
+	entryPath := append(path[:len(path):len(path)], protoCamelCase(name)+"Entry")
+
+	entry, ok := parser.script.NewTable(strings.Join(entryPath, "_"))
+
+	if !ok {
+		parser.errorf(nameToken.Start, "duplicate message(%s) defined", protoFullName(parser.pkg, entryPath))
+	}
+
+	parser.declare(entryPath, entry)
+
+	_setNodePos(entry, start, nameToken.End)
+
+	entry.(*ast.Table).NewField("Key", key)
+
+	valField, _ := entry.(*ast.Table).NewField("Value", val)
+
+	if ref != nil {
+		ref.slot = &valField.Type
+		parser.refs = append(parser.refs, ref)
+	}
+
+	entryRef := ast.NewTypeRef(entry.Name())
+
+	_setNodePos(entryRef, start, nameToken.End)
+
+	seq := ast.NewSeq(entryRef, -1)
+
+	_setNodePos(seq, start, nameToken.End)
+
+	field, ok := table.NewField(name, seq)
+
+	if !ok {
+		parser.errorf(nameToken.Start, "duplicate message(%s) field(%s)", table, name)
+	}
+
+	parser.parseFieldTail(field, nameToken, "")
+
+	_setNodePos(field, start, nameToken.End)
+
+	parser.attachComment(field)
+}
+
+// parseFieldTail parse `= number [options];` and attach field annotations
+func (parser *_ProtoParser) parseFieldTail(field *ast.Field, nameToken *lexer.Token, oneof string) {
+
+	parser.expectf(lexer.TokenType('='), "expect field(%s) number", field)
+
+	tag := parser.expectf(lexer.TokenINT, "expect field(%s) number", field)
+
+	parser.annotationStack = append(
+		parser.annotationStack,
+		parser.newAnnotation(ProtoTag, tag.Start, tag.End, ast.NewInteger(tag.Value.(int64))))
+
+	if oneof != "" {
+		parser.annotationStack = append(
+			parser.annotationStack,
+			parser.newAnnotation(ProtoOneOf, nameToken.Start, nameToken.End, ast.NewString(oneof)))
+	}
+
+	parser.annotationStack = append(parser.annotationStack, parser.parseInlineOptions()...)
+
+	parser.expectf(lexer.TokenType(';'), "field(%s) must end with ;", field)
+
+	parser.attachAnnotation(field)
+}
+
+func (parser *_ProtoParser) parseEnum(scope string, path []string) {
+
+	start := parser.next().Start
+
+	token := parser.expectIdent("expect enum name")
+
+	name := token.Value.(string)
+
+	path = append(path[:len(path):len(path)], name)
+
+	enum, ok := parser.script.NewEnum(strings.Join(path, "_"))
+
+	if !ok {
+		parser.errorf(token.Start, "duplicate enum(%s) defined", protoFullName(parser.pkg, path))
+	}
+
+	parser.declare(path, enum)
+
+	// proto3 enums are int32
+	underlying := ast.NewBuiltinType(lexer.KeyInt32)
+
+	underlying.SetParent(enum)
+
+	enum.(*ast.Enum).Underlying = underlying
+
+	parser.expectf(lexer.TokenType('{'), "enum body must start with {")
+
+	var options []*ast.Annotation
+
+	for {
+		for parser.parseComment() {
+		}
+
+		token = parser.peek()
+
+		if token.Type == lexer.TokenType('}') || token.Type == lexer.TokenEOF {
+			break
+		}
+
+		if token.Type == lexer.TokenType(';') {
+			parser.next()
+			continue
+		}
+
+		if parser.isKeyword(token, "option") {
+			options = append(options, parser.parseOption(ProtoOption))
+			continue
+		}
+
+		if parser.isKeyword(token, "reserved") {
+			parser.skipStatement()
+			continue
+		}
+
+		nameToken := parser.expectIdent("expect enum constant name")
+
+		constant, ok := enum.(*ast.Enum).NewConstant(nameToken.Value.(string))
+
+		if !ok {
+			parser.errorf(nameToken.Start, "duplicate enum(%s) constant(%s) defined", enum, nameToken.Value)
+		}
+
+		parser.expectf(lexer.TokenType('='), "expect enum constant value")
+
+		negative := false
+
+		if parser.peek().Type == lexer.OpSub {
+			parser.next()
+			negative = true
+		}
+
+		valToken := parser.expectf(lexer.TokenINT, "expect enum constant value")
+
+		val := valToken.Value.(int64)
+
+		if negative {
+			val = -val
+		}
+
+		// proto3 enum values are int32
+		if val < math.MinInt32 || val > math.MaxInt32 {
+			parser.errorf(valToken.Start, "enum(%s) constant(%s) value(%d) overflow int32", enum, constant, val)
+		}
+
+		constant.Value = val
+
+		constant.Expr = ast.NewInteger(val)
+
+		constant.Expr.SetParent(constant)
+
+		_setNodePos(constant.Expr, valToken.Start, valToken.End)
+
+		parser.annotationStack = parser.parseInlineOptions()
+
+		parser.attachAnnotation(constant)
+
+		end := parser.expectf(lexer.TokenType(';'), "enum constant must end with ;").End
+
+		_setNodePos(constant, nameToken.Start, end)
+
+		parser.attachComment(constant)
+	}
+
+	end := parser.expectf(lexer.TokenType('}'), "enum body must end with }").End
+
+	parser.annotationStack = options
+
+	parser.attachAnnotation(enum)
+
+	_setNodePos(enum, start, end)
+
+	parser.attachComment(enum)
+}
+
+func (parser *_ProtoParser) parseService() {
+
+	start := parser.next().Start
+
+	token := parser.expectIdent("expect service name")
+
+	name := token.Value.(string)
+
+	contract, ok := parser.script.NewContract(name)
+
+	if !ok {
+		parser.errorf(token.Start, "duplicate service(%s) defined", name)
+	}
+
+	parser.declare([]string{name}, contract)
+
+	parser.expectf(lexer.TokenType('{'), "service body must start with {")
+
+	var options []*ast.Annotation
+
+	for {
+		for parser.parseComment() {
+		}
+
+		token = parser.peek()
+
+		if token.Type == lexer.TokenType('}') || token.Type == lexer.TokenEOF {
+			break
+		}
+
+		if token.Type == lexer.TokenType(';') {
+			parser.next()
+			continue
+		}
+
+		if parser.isKeyword(token, "option") {
+			options = append(options, parser.parseOption(ProtoOption))
+			continue
+		}
+
+		if !parser.isKeyword(token, "rpc") {
+			parser.errorf(token.Start, "unexpect token\n%s", token)
+			parser.next()
+			continue
+		}
+
+		parser.parseRPC(contract.(*ast.Contract))
+	}
+
+	end := parser.expectf(lexer.TokenType('}'), "service body must end with }").End
+
+	parser.annotationStack = options
+
+	parser.attachAnnotation(contract)
+
+	_setNodePos(contract, start, end)
+
+	parser.attachComment(contract)
+}
+
+func (parser *_ProtoParser) parseRPC(contract *ast.Contract) {
+
+	start := parser.next().Start
+
+	token := parser.expectIdent("expect rpc name")
+
+	method, ok := contract.NewMethod(token.Value.(string))
+
+	if !ok {
+		parser.errorf(token.Start, "duplicate service(%s) rpc(%s)", contract, token.Value)
+	}
+
+	parser.expectf(lexer.TokenType('('), "rpc request type must start with (")
+
+	paramStart := parser.peek().Start
+
+	clientStream := parser.expectStream()
+
+	request, ref := parser.expectMessageType(contract.FullName())
+
+	param, _ := method.NewParam("request", request)
+
+	if clientStream {
+		param.Stream = true
+		method.Stream |= ast.StreamClient
+	}
+
+	_setNodePos(param, paramStart, parser.lastToken.End)
+
+	ref.slot = &param.Type
+
+	parser.refs = append(parser.refs, ref)
+
+	parser.expectf(lexer.TokenType(')'), "rpc request type must end with )")
+
+	if !parser.isKeyword(parser.next(), "returns") {
+		parser.errorf(parser.lastToken.Start, "expect rpc returns statement")
+	}
+
+	parser.expectf(lexer.TokenType('('), "rpc response type must start with (")
+
+	if parser.expectStream() {
+		method.Stream |= ast.StreamServer
+	}
+
+	method.Return, ref = parser.expectMessageType(contract.FullName())
+
+	method.Return.SetParent(method)
+
+	ref.slot = &method.Return
+
+	parser.refs = append(parser.refs, ref)
+
+	parser.expectf(lexer.TokenType(')'), "rpc response type must end with )")
+
+	end := parser.lastToken.End
+
+	if parser.peek().Type == lexer.TokenType('{') {
+
+		parser.next()
+
+		for {
+			for parser.parseComment() {
+			}
+
+			token := parser.peek()
+
+			if token.Type == lexer.TokenType('}') || token.Type == lexer.TokenEOF {
+				break
+			}
+
+			if token.Type == lexer.TokenType(';') {
+				parser.next()
+				continue
+			}
+
+			if !parser.isKeyword(token, "option") {
+				parser.errorf(token.Start, "unexpect token\n%s", token)
+				parser.next()
+				continue
+			}
+
+			parser.annotationStack = append(parser.annotationStack, parser.parseOption(ProtoOption))
+		}
+
+		end = parser.expectf(lexer.TokenType('}'), "rpc body must end with }").End
+	} else {
+		end = parser.expectf(lexer.TokenType(';'), "rpc statement must end with ;").End
+	}
+
+	parser.attachAnnotation(method)
+
+	_setNodePos(method, start, end)
+
+	parser.attachComment(method)
+}
+
+// expectStream skip the optional stream keyword, returns true if found,
+// stream followed by ) is the message type called stream
+func (parser *_ProtoParser) expectStream() bool {
+	if parser.isKeyword(parser.peek(), "stream") && parser.peekN(1).Type != lexer.TokenType(')') {
+		parser.next()
+		return true
+	}
+
+	return false
+}
+
+// expectMessageType expect rpc request/response message type
+func (parser *_ProtoParser) expectMessageType(scope string) (ast.Type, *_ProtoRef) {
+
+	name, start, end := parser.expectFullIdent("expect message type")
+
+	typeRef := ast.NewTypeRef(name)
+
+	_setNodePos(typeRef, start, end)
+
+	return typeRef, &_ProtoRef{name: name, scope: scope, start: start, end: end}
+}
+
+// expectFieldType expect scalar type or message/enum type reference,
+// the returned *_ProtoRef is nil for scalar types
+func (parser *_ProtoParser) expectFieldType(scope string) (ast.Type, *_ProtoRef) {
+
+	name, start, end := parser.expectFullIdent("expect field type")
+
+	if name == "bytes" {
+		component := ast.NewBuiltinType(lexer.KeyByte)
+		_setNodePos(component, start, end)
+		seq := ast.NewSeq(component, -1)
+		_setNodePos(seq, start, end)
+		return seq, nil
+	}
+
+	if builtin, ok := protoScalars[name]; ok {
+		typeDecl := ast.NewBuiltinType(builtin)
+		_setNodePos(typeDecl, start, end)
+		return typeDecl, nil
+	}
+
+	typeRef := ast.NewTypeRef(name)
+
+	_setNodePos(typeRef, start, end)
+
+	return typeRef, &_ProtoRef{name: name, scope: scope, start: start, end: end}
+}
+
+// parseInlineOptions parse `[name = value, ...]` options list
+func (parser *_ProtoParser) parseInlineOptions() (anns []*ast.Annotation) {
+
+	if parser.peek().Type != lexer.TokenType('[') {
+		return
+	}
+
+	parser.next()
+
+	for {
+		start := parser.peek().Start
+
+		name := parser.expectOptionName()
+
+		parser.expectf(lexer.TokenType('='), "expect option(%s) value", name)
+
+		value := parser.expectOptionValue()
+
+		anns = append(anns, parser.newOptionAnnotation(ProtoOption, name, value, start, parser.lastToken.End))
+
+		if parser.peek().Type != lexer.TokenType(',') {
+			break
+		}
+
+		parser.next()
+	}
+
+	parser.expectf(lexer.TokenType(']'), "options list must end with ]")
+
+	return
+}
+
+// parseOption parse `option name = value;` statement
+func (parser *_ProtoParser) parseOption(annotationType string) *ast.Annotation {
+
+	start := parser.next().Start
+
+	name := parser.expectOptionName()
+
+	parser.expectf(lexer.TokenType('='), "expect option(%s) value", name)
+
+	value := parser.expectOptionValue()
+
+	end := parser.expectf(lexer.TokenType(';'), "option statement must end with ;").End
+
+	return parser.newOptionAnnotation(annotationType, name, value, start, end)
+}
+
+func (parser *_ProtoParser) expectOptionName() string {
+
+	var buff bytes.Buffer
+
+	if parser.peek().Type == lexer.TokenType('(') {
+		parser.next()
+
+		name, _, _ := parser.expectFullIdent("expect custom option name")
+
+		buff.WriteString("(" + name + ")")
+
+		parser.expectf(lexer.TokenType(')'), "custom option name must end with )")
+	} else {
+		buff.WriteString(parser.expectIdent("expect option name").Value.(string))
+	}
+
+	for parser.peek().Type == lexer.TokenType('.') {
+		parser.next()
+		buff.WriteString("." + parser.expectIdent("expect option name").Value.(string))
+	}
+
+	return buff.String()
+}
+
+func (parser *_ProtoParser) expectOptionValue() string {
+
+	token := parser.peek()
+
+	switch token.Type {
+	case lexer.TokenSTRING:
+		var buff bytes.Buffer
+		// adjacent string literals are concatenated
+		for parser.peek().Type == lexer.TokenSTRING {
+			buff.WriteString(parser.next().Value.(string))
+		}
+		return buff.String()
+	case lexer.TokenINT:
+		return strconv.FormatInt(parser.next().Value.(int64), 10)
+	case lexer.TokenFLOAT:
+		return strconv.FormatFloat(parser.next().Value.(float64), 'g', -1, 64)
+	case lexer.OpSub, lexer.OpPlus:
+		parser.next()
+		return token.Type.String() + parser.expectOptionValue()
+	case lexer.TokenType('{'):
+		// aggregate value text format is kept unparsed
+		parser.skipBlock()
+		return "{}"
+	}
+
+	name, _, _ := parser.expectFullIdent("expect option value")
+
+	return name
+}
+
+func (parser *_ProtoParser) newOptionAnnotation(annotationType string, name string, value string, start, end lexer.Position) *ast.Annotation {
+
+	nameArg := ast.NewNamedArg("Name", ast.NewString(name))
+
+	valueArg := ast.NewNamedArg("Value", ast.NewString(value))
+
+	for _, node := range []ast.Node{nameArg, nameArg.Arg, valueArg, valueArg.Arg} {
+		_setNodePos(node, start, end)
+	}
+
+	args := ast.NewArgsTable(true)
+
+	args.Append(nameArg)
+
+	args.Append(valueArg)
+
+	_setNodePos(args, start, end)
+
+	annotation := ast.NewAnnotation(annotationType)
+
+	annotation.Args = args
+
+	args.SetParent(annotation)
+
+	_setNodePos(annotation, start, end)
+
+	_setNodePos(annotation.Type, start, end)
+
+	return annotation
+}
+
+func (parser *_ProtoParser) newAnnotation(annotationType string, start, end lexer.Position, args ...ast.Expr) *ast.Annotation {
+
+	annotation := ast.NewAnnotation(annotationType)
+
+	annotation.Args = ast.NewArgsTable(false)
+
+	annotation.Args.SetParent(annotation)
+
+	for _, arg := range args {
+		_setNodePos(arg, start, end)
+		annotation.Args.Append(arg)
+	}
+
+	_setNodePos(annotation.Args, start, end)
+
+	_setNodePos(annotation, start, end)
+
+	_setNodePos(annotation.Type, start, end)
+
+	return annotation
+}
+
+// resolve resolve proto type references with proto scoping rules
+func (parser *_ProtoParser) resolve() {
+	for _, ref := range parser.refs {
+
+		var candidates []string
+
+		if strings.HasPrefix(ref.name, ".") {
+			candidates = append(candidates, ref.name[1:])
+		} else {
+			scope := ref.scope
+
+			for scope != "" {
+				candidates = append(candidates, scope+"."+ref.name)
+
+				index := strings.LastIndex(scope, ".")
+
+				if index == -1 {
+					break
+				}
+
+				scope = scope[:index]
+			}
+
+			candidates = append(candidates, ref.name)
+		}
+
+		fullname, ok := "", false
+
+		for _, candidate := range candidates {
+			if fullname, ok = parser.compiler.protoTypes[candidate]; ok {
+				break
+			}
+		}
+
+		if !ok {
+			parser.errorf2(ErrTypeNotFound, ref.start, "unknown proto type reference :%s", ref.name)
+			continue
+		}
+
+		name := fullname
+
+		// types defined in the same script are referenced by short name
+		if prefix := parser.script.Package + "."; strings.HasPrefix(fullname, prefix) {
+			if _, ok := parser.script.Type(strings.TrimPrefix(fullname, prefix)); ok {
+				name = strings.TrimPrefix(fullname, prefix)
+			}
+		}
+
+		parser.D("resolve proto type reference %s -> %s", ref.name, name)
+
+		typeRef := ast.NewTypeRef(name)
+
+		_setNodePos(typeRef, ref.start, ref.end)
+
+		typeRef.SetParent((*ref.slot).Parent())
+
+		*ref.slot = typeRef
+	}
+}
+
+// skipStatement skip tokens until statement end ; or a balanced {} block
+func (parser *_ProtoParser) skipStatement() {
+	for {
+		token := parser.peek()
+
+		switch token.Type {
+		case lexer.TokenEOF:
+			return
+		case lexer.TokenType(';'):
+			parser.next()
+			return
+		case lexer.TokenType('{'):
+			parser.skipBlock()
+			return
+		}
+
+		parser.next()
+	}
+}
+
+// skipBlock skip balanced {} block
+func (parser *_ProtoParser) skipBlock() {
+
+	parser.expectf(lexer.TokenType('{'), "expect block start with {")
+
+	for depth := 1; depth > 0; {
+		token := parser.next()
+
+		switch token.Type {
+		case lexer.TokenEOF:
+			parser.errorf(token.Start, "block must end with }")
+			return
+		case lexer.TokenType('{'):
+			depth++
+		case lexer.TokenType('}'):
+			depth--
+		}
+	}
+}
+
+// protoIdentKeywords gslang keywords which are legal proto names
+var protoIdentKeywords = map[lexer.TokenType]bool{
+	lexer.KeyByte: true, lexer.KeySByte: true, lexer.KeyInt16: true, lexer.KeyUInt16: true,
+	lexer.KeyInt32: true, lexer.KeyUInt32: true, lexer.KeyInt64: true, lexer.KeyUInt64: true,
+	lexer.KeyFloat32: true, lexer.KeyFloat64: true, lexer.KeyString: true, lexer.KeyBool: true,
+	lexer.KeyEnum: true, lexer.KeyStruct: true, lexer.KeyTable: true, lexer.KeyContract: true,
+	lexer.KeyImport: true, lexer.KeyPackage: true, lexer.KeyVoid: true, lexer.KeyThrows: true,
+	lexer.KeyType: true, lexer.KeyMap: true,
+}
+
+// isProtoIdent check if token can be used as proto identifier,
+// gslang keywords such as type or map are legal proto names
+func isProtoIdent(token *lexer.Token) bool {
+	if token.Type == lexer.TokenID || token.Type == lexer.TokenTrue || token.Type == lexer.TokenFalse {
+		return true
+	}
+
+	return protoIdentKeywords[token.Type]
+}
+
+func (parser *_ProtoParser) expectIdent(fmtstring string, args ...interface{}) *lexer.Token {
+	for {
+		token := parser.next()
+
+		if token.Type == lexer.TokenEOF {
+			panic(fmt.Errorf("%s\n\tunexpect EOF %s", fmt.Sprintf(fmtstring, args...), token.Start))
+		}
+
+		if !isProtoIdent(token) {
+			parser.errorf(token.Start, "current token(%s) \n%s", token.Type, fmt.Sprintf(fmtstring, args...))
+			continue
+		}
+
+		return token
+	}
+}
+
+// expectFullIdent expect proto full identifier, which may start with .
+func (parser *_ProtoParser) expectFullIdent(fmtstring string, args ...interface{}) (string, lexer.Position, lexer.Position) {
+
+	var buff bytes.Buffer
+
+	start := parser.peek().Start
+
+	if parser.peek().Type == lexer.TokenType('.') {
+		parser.next()
+		buff.WriteRune('.')
+	}
+
+	buff.WriteString(parser.expectIdent(fmtstring, args...).Value.(string))
+
+	for parser.peek().Type == lexer.TokenType('.') {
+		parser.next()
+		buff.WriteRune('.')
+		buff.WriteString(parser.expectIdent(fmtstring, args...).Value.(string))
+	}
+
+	return buff.String(), start, parser.lastToken.End
+}
+
+// protoCamelCase convert proto field name to camel case as protoc does for map entry names
+func protoCamelCase(name string) string {
+
+	var buff bytes.Buffer
+
+	upper := true
+
+	for _, c := range name {
+		if c == '_' {
+			upper = true
+			continue
+		}
+
+		if upper && c >= 'a' && c <= 'z' {
+			c = c - 'a' + 'A'
+		}
+
+		upper = false
+
+		buff.WriteRune(c)
+	}
+
+	return buff.String()
+}
@@ -0,0 +1,30 @@
+package gslang.proto;
+
+using gslang.annotations.Usage;
+using gslang.annotations.Target;
+
+// proto file option, e.g. option go_package = "foo";
+@Usage(Target.Script)
+table FileOption {
+    string Name; // option name, custom option name is quoted by ()
+    string Value; // option value text
+}
+
+// proto message/enum/enum value/service/rpc/field option
+@Usage(Target.Table|Target.Enum|Target.EnumConstant|Target.Contract|Target.Method|Target.Field)
+table Option {
+    string Name; // option name, custom option name is quoted by ()
+    string Value; // option value text
+}
+
+// proto field number
+@Usage(Target.Field)
+table Tag {
+    int32 Value;
+}
+
+// proto oneof group name the field belongs to
+@Usage(Target.Field)
+table OneOf {
+    string Name;
+}
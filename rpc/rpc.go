@@ -0,0 +1,589 @@
+// Package rpc minimal streaming rpc runtime of the generated go stubs, see package gogen.
+//
+// Each call is a stream of json frames multiplexed over one io.ReadWriteCloser, e.g.
+// net.Conn or net.Pipe. The opening frame carries the method route and the args,
+// the following frames carry the stream values, and each side half-closes the stream
+// with a close frame. The accepting side closes the stream with an error frame if the
+// handler failed, or with an exception frame if the handler threw a typed contract exception.
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/gsdocker/gserrors"
+)
+
+// errors
+var (
+	ErrClosed = errors.New("rpc stream closed")
+	ErrMethod = errors.New("rpc method not found")
+	ErrArgs   = errors.New("rpc args mismatch")
+)
+
+// frame kinds
+const (
+	FrameData      = "data"
+	FrameClose     = "close"
+	FrameError     = "error"
+	FrameException = "exception"
+)
+
+// Frame wire frame, the frames of one stream share the same ID
+type Frame struct {
+	ID        uint32          `json:"id"`
+	Reply     bool            `json:"reply,omitempty"`     // sent by the accepting side of the stream
+	Method    string          `json:"method,omitempty"`    // method route of the opening frame
+	Kind      string          `json:"kind"`                // data, close, error or exception
+	Payload   json.RawMessage `json:"payload,omitempty"`   // json encoded value of data or exception frame
+	Error     string          `json:"error,omitempty"`     // error text of error or exception frame
+	Exception int8            `json:"exception,omitempty"` // exception id of exception frame
+}
+
+// Error remote error, which is returned by the remote handler
+type Error struct {
+	Text string
+}
+
+func (err *Error) Error() string {
+	return err.Text
+}
+
+// Exception typed contract exception, identified by the exception id of the method throws list
+type Exception struct {
+	ID    int8            // exception id
+	Text  string          // exception error text
+	Value json.RawMessage // json encoded exception value
+}
+
+func (err *Exception) Error() string {
+	return err.Text
+}
+
+// Exceptions method exception constructors indexed by exception id, the generated stubs
+// convert the exception values with it
+type Exceptions map[int8]func() error
+
+// Throw convert the handler error to *Exception if it's one of the method exceptions
+func (exceptions Exceptions) Throw(err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	for id, exception := range exceptions {
+
+		if reflect.TypeOf(exception()) != reflect.TypeOf(err) {
+			continue
+		}
+
+		value, marshalErr := json.Marshal(err)
+
+		if marshalErr != nil {
+			return marshalErr
+		}
+
+		return &Exception{ID: id, Text: err.Error(), Value: value}
+	}
+
+	return err
+}
+
+// Catch convert the received *Exception to the method exception value, other errors are unchanged
+func (exceptions Exceptions) Catch(err error) error {
+
+	exception, ok := err.(*Exception)
+
+	if !ok {
+		return err
+	}
+
+	newException, ok := exceptions[exception.ID]
+
+	if !ok {
+		return err
+	}
+
+	value := newException()
+
+	if json.Unmarshal(exception.Value, value) != nil {
+		return err
+	}
+
+	return value
+}
+
+// Handler serve the stream opened by peer, the returned error is sent to the peer
+type Handler func(stream *Stream) error
+
+// Server method handlers indexed by method route
+type Server struct {
+	sync.RWMutex                    // mixin mutex
+	handlers     map[string]Handler // registered handlers
+}
+
+// NewServer create new empty server
+func NewServer() *Server {
+	return &Server{
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle register method handler
+func (server *Server) Handle(method string, handler Handler) {
+	server.Lock()
+	defer server.Unlock()
+
+	server.handlers[method] = handler
+}
+
+func (server *Server) handler(method string) (Handler, bool) {
+	server.RLock()
+	defer server.RUnlock()
+
+	handler, ok := server.handlers[method]
+
+	return handler, ok
+}
+
+// Conn rpc connection, the streams can be opened by both sides
+type Conn struct {
+	rw       io.ReadWriteCloser // underlying transport
+	server   *Server            // handlers of the streams opened by peer, nil for client only conn
+	wmutex   sync.Mutex         // frame write mutex
+	encoder  *json.Encoder      // frame encoder
+	mutex    sync.Mutex         // streams mutex
+	id       uint32             // last opened stream id
+	opened   map[uint32]*Stream // streams opened by this side
+	accepted map[uint32]*Stream // streams opened by peer
+	err      error              // connection error
+}
+
+// NewConn create rpc connection over rw, the streams opened by peer are served by server,
+// which can be nil for client only connection
+func NewConn(rw io.ReadWriteCloser, server *Server) *Conn {
+
+	conn := &Conn{
+		rw:       rw,
+		server:   server,
+		encoder:  json.NewEncoder(rw),
+		opened:   make(map[uint32]*Stream),
+		accepted: make(map[uint32]*Stream),
+	}
+
+	go conn.read()
+
+	return conn
+}
+
+// Close close the connection and the underlying transport, the pending streams are failed with ErrClosed
+func (conn *Conn) Close() error {
+	err := conn.rw.Close()
+
+	conn.fail(gserrors.Newf(ErrClosed, "rpc connection closed"))
+
+	return err
+}
+
+// Open open stream of remote method, the args are sent with the opening frame
+func (conn *Conn) Open(method string, args ...interface{}) (*Stream, error) {
+
+	payload, err := json.Marshal(values(args))
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn.mutex.Lock()
+
+	if conn.err != nil {
+		conn.mutex.Unlock()
+		return nil, conn.err
+	}
+
+	conn.id++
+
+	stream := newStream(conn, conn.id, method, false)
+
+	conn.opened[stream.id] = stream
+
+	conn.mutex.Unlock()
+
+	if err := conn.write(&Frame{ID: stream.id, Method: method, Kind: FrameData, Payload: payload}); err != nil {
+
+		conn.mutex.Lock()
+		delete(conn.opened, stream.id)
+		conn.mutex.Unlock()
+
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+// Call call remote method and wait for the results
+func (conn *Conn) Call(method string, args []interface{}, results ...interface{}) error {
+
+	stream, err := conn.Open(method, args...)
+
+	if err != nil {
+		return err
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	return stream.RecvArgs(results...)
+}
+
+func (conn *Conn) write(frame *Frame) error {
+	conn.wmutex.Lock()
+	defer conn.wmutex.Unlock()
+
+	return conn.encoder.Encode(frame)
+}
+
+func (conn *Conn) read() {
+
+	decoder := json.NewDecoder(conn.rw)
+
+	for {
+		frame := &Frame{}
+
+		if err := decoder.Decode(frame); err != nil {
+			conn.fail(gserrors.Newf(ErrClosed, "rpc connection closed :%s", err))
+			return
+		}
+
+		conn.dispatch(frame)
+	}
+}
+
+func (conn *Conn) dispatch(frame *Frame) {
+
+	conn.mutex.Lock()
+
+	var stream *Stream
+
+	if frame.Reply {
+		stream = conn.opened[frame.ID]
+
+		// the peer never sends after the close or error frame
+		if stream != nil && frame.Kind != FrameData {
+			delete(conn.opened, frame.ID)
+		}
+
+	} else {
+		stream = conn.accepted[frame.ID]
+
+		if stream == nil && frame.Method != "" {
+			stream = newStream(conn, frame.ID, frame.Method, true)
+
+			conn.accepted[frame.ID] = stream
+
+			go conn.serve(stream)
+		}
+	}
+
+	conn.mutex.Unlock()
+
+	// frames of finished streams are dropped
+	if stream != nil {
+		stream.push(frame)
+	}
+}
+
+func (conn *Conn) serve(stream *Stream) {
+
+	var err error = gserrors.Newf(ErrMethod, "rpc method(%s) not found", stream.method)
+
+	if conn.server != nil {
+		if handler, ok := conn.server.handler(stream.method); ok {
+			err = handler(stream)
+		}
+	}
+
+	conn.mutex.Lock()
+	delete(conn.accepted, stream.id)
+	conn.mutex.Unlock()
+
+	stream.finish(ErrClosed)
+
+	frame := &Frame{ID: stream.id, Reply: true, Kind: FrameClose}
+
+	if exception, ok := err.(*Exception); ok {
+		frame.Kind = FrameException
+		frame.Error = exception.Text
+		frame.Exception = exception.ID
+		frame.Payload = exception.Value
+	} else if err != nil {
+		frame.Kind = FrameError
+		frame.Error = err.Error()
+	}
+
+	conn.write(frame)
+}
+
+func (conn *Conn) fail(err error) {
+
+	conn.mutex.Lock()
+
+	if conn.err != nil {
+		conn.mutex.Unlock()
+		return
+	}
+
+	conn.err = err
+
+	var streams []*Stream
+
+	for _, stream := range conn.opened {
+		streams = append(streams, stream)
+	}
+
+	for _, stream := range conn.accepted {
+		streams = append(streams, stream)
+	}
+
+	conn.mutex.Unlock()
+
+	for _, stream := range streams {
+		stream.finish(err)
+	}
+}
+
+// Stream rpc call stream
+type Stream struct {
+	conn     *Conn         // rpc connection
+	id       uint32        // stream id
+	method   string        // method route
+	reply    bool          // accepted stream flag
+	mutex    sync.Mutex    // frames mutex
+	cond     *sync.Cond    // frames condition
+	frames   []*Frame      // received frames
+	err      error         // receive error, set after the last frame is received
+	sendDone bool          // the send side is closed
+	done     chan struct{} // closed when the stream is finished
+}
+
+func newStream(conn *Conn, id uint32, method string, reply bool) *Stream {
+
+	stream := &Stream{
+		conn:   conn,
+		id:     id,
+		method: method,
+		reply:  reply,
+		done:   make(chan struct{}),
+	}
+
+	stream.cond = sync.NewCond(&stream.mutex)
+
+	return stream
+}
+
+// Method get the method route of stream
+func (stream *Stream) Method() string {
+	return stream.method
+}
+
+// Done get the channel closed when the stream is finished, the accepted stream is finished
+// after the handler returned, the opened stream is finished after peer closed it
+func (stream *Stream) Done() <-chan struct{} {
+	return stream.done
+}
+
+// Send send stream value
+func (stream *Stream) Send(val interface{}) error {
+
+	payload, err := json.Marshal(val)
+
+	if err != nil {
+		return err
+	}
+
+	stream.mutex.Lock()
+	sendDone := stream.sendDone
+	stream.mutex.Unlock()
+
+	if sendDone {
+		return gserrors.Newf(ErrClosed, "rpc stream(%s) send side closed", stream.method)
+	}
+
+	return stream.conn.write(&Frame{ID: stream.id, Reply: stream.reply, Kind: FrameData, Payload: payload})
+}
+
+// SendArgs send args or results as one stream value
+func (stream *Stream) SendArgs(vals ...interface{}) error {
+	return stream.Send(values(vals))
+}
+
+// CloseSend half-close the stream, the peer receives io.EOF after the sent values.
+// the accepted stream is closed after the handler returned, so the handlers needn't call it
+func (stream *Stream) CloseSend() error {
+
+	stream.mutex.Lock()
+
+	if stream.sendDone {
+		stream.mutex.Unlock()
+		return nil
+	}
+
+	stream.sendDone = true
+
+	stream.mutex.Unlock()
+
+	return stream.conn.write(&Frame{ID: stream.id, Reply: stream.reply, Kind: FrameClose})
+}
+
+// Recv receive next stream value, returns io.EOF if the peer closed the stream,
+// *Error if the remote handler failed, or *Exception if the remote handler threw exception
+func (stream *Stream) Recv(val interface{}) error {
+
+	stream.mutex.Lock()
+
+	for len(stream.frames) == 0 && stream.err == nil {
+		stream.cond.Wait()
+	}
+
+	if len(stream.frames) == 0 {
+		err := stream.err
+		stream.mutex.Unlock()
+		return err
+	}
+
+	frame := stream.frames[0]
+
+	stream.frames = stream.frames[1:]
+
+	switch frame.Kind {
+	case FrameClose:
+		stream.err = io.EOF
+	case FrameError:
+		stream.err = &Error{Text: frame.Error}
+	case FrameException:
+		stream.err = &Exception{ID: frame.Exception, Text: frame.Error, Value: frame.Payload}
+	}
+
+	err := stream.err
+
+	stream.mutex.Unlock()
+
+	if frame.Kind != FrameData {
+		return err
+	}
+
+	return json.Unmarshal(frame.Payload, val)
+}
+
+// RecvArgs receive args or results sent by SendArgs, vals are pointers of the values
+func (stream *Stream) RecvArgs(vals ...interface{}) error {
+
+	var payloads []json.RawMessage
+
+	if err := stream.Recv(&payloads); err != nil {
+		return err
+	}
+
+	if len(payloads) != len(vals) {
+		return gserrors.Newf(ErrArgs, "rpc method(%s) expect %d args, got %d", stream.method, len(vals), len(payloads))
+	}
+
+	for i, payload := range payloads {
+		if err := json.Unmarshal(payload, vals[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (stream *Stream) push(frame *Frame) {
+
+	stream.mutex.Lock()
+
+	stream.frames = append(stream.frames, frame)
+
+	stream.cond.Signal()
+
+	stream.mutex.Unlock()
+
+	if frame.Reply && frame.Kind != FrameData {
+		stream.close()
+	}
+}
+
+// finish finish the stream, the received frames are still returned by Recv before err
+func (stream *Stream) finish(err error) {
+
+	stream.mutex.Lock()
+
+	if stream.err == nil {
+		stream.err = err
+	}
+
+	stream.sendDone = true
+
+	stream.cond.Broadcast()
+
+	stream.mutex.Unlock()
+
+	stream.close()
+}
+
+func (stream *Stream) close() {
+
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	select {
+	case <-stream.done:
+	default:
+		close(stream.done)
+	}
+}
+
+func values(vals []interface{}) []interface{} {
+
+	if vals == nil {
+		return []interface{}{}
+	}
+
+	return vals
+}
+
+// Iterator stream values iterator, the generated stubs wrap it as typed iterator
+type Iterator struct {
+	stream *Stream // iterated stream
+	err    error   // iteration error
+}
+
+// NewIterator create stream values iterator
+func NewIterator(stream *Stream) *Iterator {
+	return &Iterator{stream: stream}
+}
+
+// Next receive next stream value into val, returns false if the stream is closed or failed
+func (iter *Iterator) Next(val interface{}) bool {
+
+	if iter.err != nil {
+		return false
+	}
+
+	iter.err = iter.stream.Recv(val)
+
+	return iter.err == nil
+}
+
+// Err get iteration error, nil if the stream is closed by peer normally
+func (iter *Iterator) Err() error {
+
+	if iter.err == io.EOF {
+		return nil
+	}
+
+	return iter.err
+}
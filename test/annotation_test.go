@@ -0,0 +1,301 @@
+package test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+func TestDecodeAnnotation(t *testing.T) {
+
+	compiler := compile(t, "test.gs")
+
+	post, _ := compiler.Module().Types["gslang.test.HttpREST"].(*ast.Contract).Method("Post")
+
+	timeout, ok := gslang.FindAnnotation(post, "gslang.test.Timeout")
+
+	if !ok {
+		t.Fatal("annotation Timeout not found")
+	}
+
+	var val struct {
+		Duration *struct {
+			Value int32
+			Unit  *gslang.AnnotationValue `gslang:"Unit"`
+		}
+	}
+
+	if err := gslang.DecodeAnnotation(timeout, &val); err != nil {
+		t.Fatal(err)
+	}
+
+	if val.Duration.Value != -100 {
+		t.Fatalf("expect duration value -100, got %d", val.Duration.Value)
+	}
+
+	unit := val.Duration.Unit
+
+	if unit.Kind != gslang.ValueEnum || unit.Type.FullName() != "gslang.test.TimeUnit" || unit.Constants[0].Name() != "Second" {
+		t.Fatalf("unexpect duration unit %v", unit)
+	}
+
+	// flag constants are combined
+	description := compiler.Module().Types["gslang.test.Description"]
+
+	usage, _ := gslang.FindAnnotation(description, "gslang.annotations.Usage")
+
+	var usageVal struct {
+		Target uint32
+	}
+
+	if err := gslang.DecodeAnnotation(usage, &usageVal); err != nil {
+		t.Fatal(err)
+	}
+
+	if usageVal.Target != 3 {
+		t.Fatalf("expect usage target 3, got %d", usageVal.Target)
+	}
+
+	// type mismatch error carry the arg position
+	var mismatch struct {
+		Duration string
+	}
+
+	err := gslang.DecodeAnnotation(timeout, &mismatch)
+
+	if err == nil {
+		t.Fatal("expect decode error")
+	}
+
+	if err := err.(*gslang.Error); err.Orignal != gslang.ErrDecode || err.Start.Lines != 57 || err.Start.Column != 14 {
+		t.Fatalf("unexpect decode error %s", err)
+	}
+}
+
+var integersScript = `package integers;
+
+using gslang.annotations.Usage;
+using gslang.annotations.Target;
+
+@Usage(Target.Table)
+table Limit {
+    int64 Min;
+    uint64 Max;
+}
+
+@Limit(Min:-9223372036854775807, Max:9223372036854775807)
+table Bounded {}
+`
+
+func TestDecodeIntegers(t *testing.T) {
+
+	compiler, errs := compileModule(t, "integers.gs", integersScript)
+
+	if len(errs) != 0 {
+		t.Fatalf("unexpect errors %v", errs)
+	}
+
+	limit, ok := gslang.FindAnnotation(compiler.Module().Types["integers.Bounded"], "integers.Limit")
+
+	if !ok {
+		t.Fatal("annotation Limit not found")
+	}
+
+	var val struct {
+		Min int64
+		Max uint64
+	}
+
+	// boundary integer literals are decoded exactly
+	if err := gslang.DecodeAnnotation(limit, &val); err != nil {
+		t.Fatal(err)
+	}
+
+	if val.Min != -math.MaxInt64 || val.Max != math.MaxInt64 {
+		t.Fatalf("expect limit (%d, %d), got (%d, %d)", -math.MaxInt64, uint64(math.MaxInt64), val.Min, val.Max)
+	}
+
+	// unary op, e.g. from descriptor decoded args
+	args := ast.NewArgsTable(true)
+
+	args.Append(ast.NewNamedArg("Min", ast.NewUnaryOp(lexer.OpSub, ast.NewInteger(1))))
+
+	limit.Args = args
+
+	if err := gslang.DecodeAnnotation(limit, &val); err != nil {
+		t.Fatal(err)
+	}
+
+	if val.Min != -1 {
+		t.Fatalf("expect limit min -1, got %d", val.Min)
+	}
+
+	// 2^63 float overflows int64
+	for _, numeric := range []float64{math.Pow(2, 63), -math.Pow(2, 63), 1.5} {
+
+		args := ast.NewArgsTable(true)
+
+		args.Append(ast.NewNamedArg("Min", ast.NewNumeric(numeric)))
+
+		limit.Args = args
+
+		if err := gslang.DecodeAnnotation(limit, &val); err == nil {
+			t.Fatalf("expect decode error for %f, got %d", numeric, val.Min)
+		}
+	}
+}
+
+var constraintsScript = `package constraints;
+
+using gslang.Range;
+using gslang.Pattern;
+using gslang.Length;
+using gslang.NotEmpty;
+
+table User {
+    @Range(Min:0, Max:150)
+    int32 Age;
+    @Pattern("^[a-z]+$") @Length(Max:64)
+    string<=128 Name;
+    @NotEmpty
+    string<=32[<=10]? Tags;
+}
+
+table Bad {
+    @Pattern("^[a-z]+$")
+    int32 Age;
+    @Range(Min:10, Max:1)
+    float32 Score;
+    @Pattern("[a-z")
+    string Name;
+    @Length(Min:-1)
+    string Nick;
+    @Range(Min:-1)
+    uint16 Port;
+    @Range(Min:0, Max:1000)
+    byte Level;
+}
+
+contract Service {
+    void Create(@Length(Min:1, Max:16) string name);
+}
+`
+
+func TestConstraints(t *testing.T) {
+
+	compiler, errs := compileModule(t, "constraints.gs", constraintsScript)
+
+	expect := []string{
+		"constraint(gslang.Pattern) can't be applied to Age type(int32)",
+		"constraint(gslang.Range) min(10) greater than max(1)",
+		"constraint(gslang.Pattern) illegal regexp([a-z)",
+		"as uint32",
+		"constraint(gslang.Range) bound(-1) out of Port type(uint16) range [0,65535]",
+		"constraint(gslang.Range) bound(1000) out of Level type(byte) range [0,255]",
+	}
+
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors, got %v", len(expect), errs)
+	}
+
+	for i, err := range errs {
+		if !strings.Contains(err.Text, expect[i]) {
+			t.Fatalf("expect error %s, got %s", expect[i], err)
+		}
+	}
+
+	user := compiler.Module().Types["constraints.User"].(*ast.Table)
+
+	age, _ := user.Field("Age")
+
+	set, err := gslang.Constraints(age)
+
+	if err != nil || *set.Min != 0 || *set.Max != 150 {
+		t.Fatalf("expect age range [0,150], got %v", err)
+	}
+
+	name, _ := user.Field("Name")
+
+	if set, _ := gslang.Constraints(name); set.Pattern != "^[a-z]+$" || set.MaxLength != 64 || set.Min != nil {
+		t.Fatal("expect name pattern and max length 64")
+	}
+
+	tags, _ := user.Field("Tags")
+
+	if set, _ := gslang.Constraints(tags); set.MinLength != 1 || set.MaxLength != 10 {
+		t.Fatalf("expect tags length [1,10], got [%d,%d]", set.MinLength, set.MaxLength)
+	}
+
+	create, _ := compiler.Module().Types["constraints.Service"].(*ast.Contract).Method("Create")
+
+	if set, _ := gslang.Constraints(create.Params[0]); set.MinLength != 1 || set.MaxLength != 16 {
+		t.Fatalf("expect param length [1,16], got [%d,%d]", set.MinLength, set.MaxLength)
+	}
+}
+
+var serviceScript = `package services;
+
+using gslang.Service;
+using gslang.MethodID;
+
+@Service(Name:"auth", Version:2)
+contract Auth {
+    @MethodID(10)
+    void Login(string name);
+    void Logout();
+}
+
+@Service(Name:"auth")
+contract Duplicate {
+    void Get();
+    @MethodID(0)
+    void Conflict();
+}
+
+@Service(Version:0)
+contract Zero {
+}
+`
+
+func TestService(t *testing.T) {
+
+	compiler, errs := compileModule(t, "services.gs", serviceScript)
+
+	for _, expect := range []string{
+		"duplicate service name(auth) : contract(services.Duplicate) and contract(services.Auth)",
+		"contract(Duplicate) method(Conflict) id(0) conflicts with method(Get)",
+		"contract(Zero) service version must be greater than 0",
+	} {
+		if !containsError(errs, expect) {
+			t.Fatalf("expect error %s, got %v", expect, errs)
+		}
+	}
+
+	if len(errs) != 4 {
+		t.Fatalf("expect 4 errors, got %v", errs)
+	}
+
+	auth := compiler.Module().Types["services.Auth"].(*ast.Contract)
+
+	if gslang.ServiceName(auth) != "auth" || gslang.ServiceVersion(auth) != 2 {
+		t.Fatalf("expect service auth version 2, got %s %d", gslang.ServiceName(auth), gslang.ServiceVersion(auth))
+	}
+
+	login, _ := auth.Method("Login")
+
+	logout, _ := auth.Method("Logout")
+
+	if gslang.MethodID(login) != 10 || gslang.MethodID(logout) != 1 {
+		t.Fatalf("expect method ids 10 and 1, got %d and %d", gslang.MethodID(login), gslang.MethodID(logout))
+	}
+
+	zero := compiler.Module().Types["services.Zero"].(*ast.Contract)
+
+	if gslang.ServiceName(zero) != "services.Zero" {
+		t.Fatalf("expect default service name, got %s", gslang.ServiceName(zero))
+	}
+}
@@ -0,0 +1,41 @@
+package gslang.test.compat;
+
+using gslang.Exception;
+using gslang.Service;
+using gslang.MethodID;
+
+enum Level {
+    Low,High
+}
+
+table Record {
+    string Name;
+    int32 Count;
+    Level Level;
+}
+
+table Limits {
+    string<=64 Title;
+    int32[<=16] Tags;
+    string Note;
+}
+
+@Exception
+table NotFound {}
+
+contract Store {
+    Record Get(string name);
+    void Put(Record record);
+    void Rename(string<=64 name);
+    void Delete(string name);
+}
+
+@Service(Name:"files")
+contract Files {
+    @MethodID(1)
+    void Open(string name);
+    @MethodID(2)
+    void Close(string name);
+    @MethodID(3)
+    void Stat(string name);
+}
@@ -0,0 +1,42 @@
+package gslang.test.compat;
+
+using gslang.Exception;
+using gslang.Service;
+using gslang.MethodID;
+
+enum Level {
+    Low,Medium
+}
+
+table Record {
+    string Name;
+    int64 Count;
+    Level Level;
+    string Owner;
+}
+
+table Limits {
+    string<=128 Title;
+    int32[<=8] Tags;
+    string<=32 Note;
+}
+
+@Exception
+table NotFound {}
+
+contract Store {
+    void Delete(string key);
+    Record Get(string name) throws (NotFound);
+    void Put(Record record);
+    void Rename(string<=256 name);
+}
+
+@Service(Name:"blobs", Version:2)
+contract Files {
+    @MethodID(2)
+    void Close(string name);
+    @MethodID(1)
+    void Open(string name);
+    @MethodID(4)
+    void Stat(string name);
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gsrpc/gslang/compat"
+)
+
+func TestCompat(t *testing.T) {
+
+	old := compile(t, "compat/v1/api.gs").Module()
+
+	current := compile(t, "compat/v2/api.gs").Module()
+
+	report := compat.Check(old, current)
+
+	expect := map[string][2]compat.Level{
+		"gslang.test.compat.Level.High":        {compat.Compatible, compat.Breaking},
+		"gslang.test.compat.Record.Count":      {compat.Breaking, compat.Breaking},
+		"gslang.test.compat.Record.Owner":      {compat.Compatible, compat.Compatible},
+		"gslang.test.compat.Store.Get":         {compat.Breaking, compat.Compatible},
+		"gslang.test.compat.Store.Delete":      {compat.Breaking, compat.Compatible},
+		"gslang.test.compat.Store.Delete.name": {compat.Compatible, compat.Breaking},
+		"gslang.test.compat.Files.Stat":        {compat.Breaking, compat.Compatible},
+		// raising a bound is compatible, lowering or adding one breaks the wire
+		"gslang.test.compat.Limits.Title":      {compat.Compatible, compat.Compatible},
+		"gslang.test.compat.Limits.Tags":       {compat.Breaking, compat.Compatible},
+		"gslang.test.compat.Limits.Note":       {compat.Breaking, compat.Compatible},
+		"gslang.test.compat.Store.Rename.name": {compat.Compatible, compat.Compatible},
+	}
+
+	for _, change := range report.Changes {
+
+		t.Log(change)
+
+		level, ok := expect[change.Path]
+
+		if !ok {
+			continue
+		}
+
+		if change.Wire != level[0] || change.Source != level[1] {
+			t.Fatalf("unexpect change level: %s", change)
+		}
+
+		delete(expect, change.Path)
+	}
+
+	for path := range expect {
+		t.Fatalf("expect change %s", path)
+	}
+
+	var service []string
+
+	for _, change := range report.Changes {
+
+		switch change.Path {
+		case "gslang.test.compat.Files":
+			if change.Wire != compat.Breaking {
+				t.Fatalf("expect wire breaking change: %s", change)
+			}
+
+			service = append(service, change.Text)
+		case "gslang.test.compat.Files.Open", "gslang.test.compat.Files.Close":
+			// the reordered methods keep the ids declared by gslang.MethodID
+			t.Fatalf("unexpect change: %s", change)
+		}
+	}
+
+	if strings.Join(service, ";") != "service name changed from files to blobs;service version changed from 1 to 2" {
+		t.Fatalf("expect service name and version changes, got %v", service)
+	}
+
+	if !report.WireBreaking() {
+		t.Fatal("expect wire breaking report")
+	}
+
+	if len(compat.Check(old, old).Changes) != 0 {
+		t.Fatal("expect no changes between the same module")
+	}
+}
@@ -0,0 +1,125 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/descriptor"
+)
+
+func TestDescriptor(t *testing.T) {
+
+	compiler := compile(t, "test.gs")
+
+	var buff bytes.Buffer
+
+	if err := descriptor.New(compiler.Module()).Write(&buff); err != nil {
+		t.Fatal(err)
+	}
+
+	content := buff.String()
+
+	module, err := descriptor.Load(&buff)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contract, ok := module.Types["gslang.test.HttpREST"]
+
+	if !ok {
+		t.Fatal("expect contract gslang.test.HttpREST")
+	}
+
+	method, _ := contract.(*ast.Contract).Method("Get")
+
+	if method.Exceptions[0].Type.(*ast.TypeRef).Ref != module.Types["gslang.test.RemoteException"] {
+		t.Fatal("expect exception type reference resolved")
+	}
+
+	buff.Reset()
+
+	if err := descriptor.New(module).Write(&buff); err != nil {
+		t.Fatal(err)
+	}
+
+	if buff.String() != content {
+		t.Fatalf("reconstructed module descriptor mismatch:\n%s", buff.String())
+	}
+}
+
+func TestBinaryDescriptor(t *testing.T) {
+
+	compiler := compile(t, "test.gs")
+
+	data, err := descriptor.Encode(compiler.Module())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := descriptor.Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, ok := module.Types["gslang.test.Duration"]
+
+	if !ok {
+		t.Fatal("expect table gslang.test.Duration")
+	}
+
+	field, _ := table.(*ast.Table).Field("Unit")
+
+	if field.Type.(*ast.TypeRef).Ref != module.Types["gslang.test.TimeUnit"] {
+		t.Fatal("expect field type reference resolved")
+	}
+
+	origin := descriptor.New(compiler.Module())
+
+	decoded := descriptor.New(module)
+
+	for i, script := range origin.Scripts {
+		for j, typeDesc := range script.Types {
+			if decoded.Scripts[i].Types[j].Hash != typeDesc.Hash {
+				t.Fatalf("type(%s) content hash mismatch", typeDesc.FullName)
+			}
+		}
+	}
+
+	// corrupt one type's hash
+	data[len(data)-1] ^= 0xff
+
+	if _, err := descriptor.Decode(data); err == nil {
+		t.Fatal("expect corrupted descriptor decode error")
+	}
+}
+
+func TestDescriptorMalformed(t *testing.T) {
+
+	typeRef := `{"kind":"builtin","name":"int32"}`
+
+	for _, types := range []string{
+		`null`,
+		`{"kind":"table","name":"A","fields":[{"name":"a"}]}`,
+		`{"kind":"contract","name":"A","methods":[{"name":"a"}]}`,
+		`{"kind":"table","name":"A","annotations":[{}]}`,
+		`{"kind":"table","name":"A","annotations":[{"type":` + typeRef + `}]}`,
+		`{"kind":"table","name":"A","annotations":[{"type":{"kind":"ref","name":"A"},"args":{"kind":"string"}}]}`,
+		`{"kind":"table","name":"A","annotations":[{"type":{"kind":"ref","name":"A"},"args":{"kind":"args","args":[{"kind":"newobj","type":` + typeRef + `}]}}]}`,
+		`{"kind":"table","name":"A","annotations":[{"type":{"kind":"ref","name":"A"},"args":{"kind":"args","args":[{"kind":"unary","op":"-"}]}}]}`,
+	} {
+		content := fmt.Sprintf(`{"version":%d,"name":"test","scripts":[{"name":"test.gs","types":[%s]}]}`, descriptor.Version, types)
+
+		if _, err := descriptor.Load(strings.NewReader(content)); err == nil {
+			t.Fatalf("expect malformed descriptor error :%s", types)
+		} else if _, ok := err.(gserrors.GSError); !ok {
+			t.Fatalf("expect gserrors.GSError, got %T", err)
+		}
+	}
+}
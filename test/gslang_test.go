@@ -74,3 +74,23 @@ func TestParser(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// compile compile and link scripts with the bundled prelude scripts
+func compile(t *testing.T, files ...string) *gslang.Compiler {
+
+	compiler := gslang.NewCompiler("test", gslang.HandleError(func(err *gslang.Error) {
+		gserrors.Panicf(err.Orignal, "parse %s error\n\t%s", err.Start, err.Text)
+	}))
+
+	for _, file := range append([]string{"../gslang.gs", "../annotations.gs"}, files...) {
+		if err := compiler.Compile(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := compiler.Link(); err != nil {
+		t.Fatal(err)
+	}
+
+	return compiler
+}
@@ -0,0 +1,109 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+)
+
+func fieldRef(t *testing.T, compiler *gslang.Compiler, name string, field string) ast.Type {
+
+	table, ok := compiler.Module().Types[name].(*ast.Table)
+
+	if !ok {
+		t.Fatalf("table %s not found", name)
+	}
+
+	f, ok := table.Field(field)
+
+	if !ok {
+		t.Fatalf("table %s field %s not found", name, field)
+	}
+
+	return f.Type.(*ast.TypeRef).Ref
+}
+
+func TestIncremental(t *testing.T) {
+
+	compiler := gslang.NewCompiler("test", gslang.HandleError(func(err *gslang.Error) {
+		// keep linking after semantic errors, the errors are checked by diagnostics
+		if err.Stage != gslang.StageSemParing {
+			gserrors.Panicf(err.Orignal, "parse %s error\n\t%s", err.Start, err.Text)
+		}
+	}))
+
+	sources := map[string]string{
+		"a.gs": "package inc;\ntable A { int32 X; }\n",
+		"b.gs": "package inc;\nusing inc.A;\ntable B { A A; }\n",
+		"c.gs": "package inc;\ntable C { int32 Y; }\n",
+	}
+
+	for _, name := range []string{"a.gs", "b.gs", "c.gs"} {
+		if err := compiler.CompileSource(name, []byte(sources[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := compiler.Link(); err != nil {
+		t.Fatal(err)
+	}
+
+	if dependents := compiler.Dependents("a.gs"); len(dependents) != 1 || dependents[0] != "b.gs" {
+		t.Fatalf("unexpect dependents %v", dependents)
+	}
+
+	// replace script, dependents are relinked to the new types
+	if err := compiler.Update("a.gs", []byte("package inc;\ntable A { int64 X; }\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if fieldRef(t, compiler, "inc.B", "A") != compiler.Module().Types["inc.A"] {
+		t.Fatal("inc.B.A not relinked to the new inc.A")
+	}
+
+	// remove script, dependents report unknown type
+	if err := compiler.Remove("a.gs"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := compiler.Module().Types["inc.A"]; ok {
+		t.Fatal("inc.A not removed")
+	}
+
+	// unknown using and unknown field type
+	if diagnostics := compiler.Diagnostics("b.gs"); len(diagnostics) != 2 {
+		t.Fatalf("expect two diagnostics for b.gs, got %v", diagnostics)
+	}
+
+	if diagnostics := compiler.Diagnostics("c.gs"); len(diagnostics) != 0 {
+		t.Fatalf("unexpect diagnostics for c.gs %v", diagnostics)
+	}
+
+	// add script back, the diagnostics are invalidated
+	if err := compiler.Update("a.gs", []byte(sources["a.gs"])); err != nil {
+		t.Fatal(err)
+	}
+
+	if diagnostics := compiler.Diagnostics("b.gs"); len(diagnostics) != 0 {
+		t.Fatalf("unexpect diagnostics for b.gs %v", diagnostics)
+	}
+
+	if fieldRef(t, compiler, "inc.B", "A") != compiler.Module().Types["inc.A"] {
+		t.Fatal("inc.B.A not relinked to the new inc.A")
+	}
+
+	// syntax errors are reported to the updated script only
+	if err := compiler.Update("a.gs", []byte("package inc;\ntable A { int32 }\n")); err == nil {
+		t.Fatal("expect syntax error")
+	}
+
+	if diagnostics := compiler.Diagnostics("a.gs"); len(diagnostics) != 1 || diagnostics[0].Start.Lines != 2 {
+		t.Fatalf("unexpect diagnostics for a.gs %v", diagnostics)
+	}
+
+	if diagnostics := compiler.Diagnostics("b.gs"); len(diagnostics) != 0 {
+		t.Fatalf("unexpect diagnostics for b.gs %v", diagnostics)
+	}
+}
@@ -0,0 +1,326 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gsrpc/gslang/lsp"
+)
+
+var lspScript = `package demo;
+
+using gslang.annotations.Usage;
+using gslang.annotations.Target;
+
+// Color palette
+enum Color {
+    Red,
+    Green(2)
+}
+
+@Usage(Target.Table)
+table Tag {
+    string Name;
+    Color Color;
+}
+
+// Point in the plane
+@Tag(Name:"point", Color:Color.Red)
+table Point {
+    int32 X;
+    Color Fill;
+}
+`
+
+// lspClient scripted in-memory language client
+type lspClient struct {
+	t           *testing.T
+	conn        *lsp.Conn
+	messages    chan *lsp.Message
+	id          int
+	diagnostics map[string][]*lsp.Diagnostic
+}
+
+func newLSPClient(t *testing.T) (*lspClient, func()) {
+
+	serverReader, clientWriter := io.Pipe()
+	clientReader, serverWriter := io.Pipe()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- lsp.NewServer().Serve(serverReader, serverWriter)
+		serverWriter.Close()
+	}()
+
+	client := &lspClient{
+		t:           t,
+		conn:        lsp.NewConn(clientReader, clientWriter),
+		messages:    make(chan *lsp.Message, 1024),
+		diagnostics: make(map[string][]*lsp.Diagnostic),
+	}
+
+	// the pipes are synchronous, keep reading so the server never blocks on writing
+	go func() {
+		defer close(client.messages)
+
+		for {
+			message, err := client.conn.Read()
+
+			if err != nil {
+				return
+			}
+
+			client.messages <- message
+		}
+	}()
+
+	return client, func() {
+		client.call("shutdown", nil, nil)
+		client.notify("exit", nil)
+
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func (client *lspClient) notify(method string, params interface{}) {
+	if err := client.conn.Notify(method, params); err != nil {
+		client.t.Fatal(err)
+	}
+}
+
+// sync send a request and wait the response, so all notifications of previous messages are received
+func (client *lspClient) sync() {
+	client.call("$/sync", nil, nil)
+}
+
+func (client *lspClient) call(method string, params interface{}, result interface{}) *lsp.ResponseError {
+
+	client.id++
+
+	if err := client.conn.Call(client.id, method, params); err != nil {
+		client.t.Fatal(err)
+	}
+
+	for message := range client.messages {
+
+		if message.ID == nil {
+
+			if message.Method == "textDocument/publishDiagnostics" {
+
+				var diagnostics lsp.PublishDiagnosticsParams
+
+				if err := json.Unmarshal(message.Params, &diagnostics); err != nil {
+					client.t.Fatal(err)
+				}
+
+				client.diagnostics[diagnostics.URI] = diagnostics.Diagnostics
+			}
+
+			continue
+		}
+
+		if message.Error != nil {
+			return message.Error
+		}
+
+		if result != nil {
+			if err := json.Unmarshal(message.Result, result); err != nil {
+				client.t.Fatal(err)
+			}
+		}
+
+		return nil
+	}
+
+	client.t.Fatal("connection closed")
+
+	return nil
+}
+
+func position(uri string, line int, character int) *lsp.TextDocumentPositionParams {
+	return &lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: uri},
+		Position:     lsp.Position{Line: line, Character: character},
+	}
+}
+
+func labels(list *lsp.CompletionList) map[string]bool {
+
+	labels := make(map[string]bool)
+
+	for _, item := range list.Items {
+		labels[item.Label] = true
+	}
+
+	return labels
+}
+
+func TestLSP(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gslang-lsp")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "demo.gs")
+
+	if err := ioutil.WriteFile(file, []byte(lspScript), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gslangFile, _ := filepath.Abs("../gslang.gs")
+
+	annotationsFile, _ := filepath.Abs("../annotations.gs")
+
+	client, shutdown := newLSPClient(t)
+
+	defer shutdown()
+
+	var initResult struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+
+	client.call("initialize", map[string]interface{}{
+		"rootUri": lsp.PathToURI(dir),
+		"initializationOptions": map[string]interface{}{
+			"includes": []string{gslangFile, annotationsFile},
+		},
+	}, &initResult)
+
+	if initResult.Capabilities["definitionProvider"] != true {
+		t.Fatalf("unexpect capabilities %v", initResult.Capabilities)
+	}
+
+	client.notify("initialized", struct{}{})
+
+	uri := lsp.PathToURI(file)
+
+	client.notify("textDocument/didOpen", &lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: uri, Version: 1, Text: lspScript},
+	})
+
+	client.sync()
+
+	if diagnostics, ok := client.diagnostics[uri]; !ok || len(diagnostics) != 0 {
+		t.Fatalf("expect empty diagnostics, got %v", diagnostics)
+	}
+
+	// go to definition of field type Color
+	var location lsp.Location
+
+	client.call("textDocument/definition", position(uri, 21, 5), &location)
+
+	if location.URI != uri || location.Range.Start != (lsp.Position{Line: 6, Character: 5}) {
+		t.Fatalf("unexpect definition %v", location)
+	}
+
+	// go to definition of enum constant Color.Red
+	client.call("textDocument/definition", position(uri, 18, 32), &location)
+
+	if location.Range.Start != (lsp.Position{Line: 7, Character: 4}) {
+		t.Fatalf("unexpect definition %v", location)
+	}
+
+	// go to definition of annotation Usage in the include script
+	client.call("textDocument/definition", position(uri, 11, 2), &location)
+
+	if location.URI != lsp.PathToURI(annotationsFile) {
+		t.Fatalf("unexpect definition %v", location)
+	}
+
+	var hover lsp.Hover
+
+	client.call("textDocument/hover", position(uri, 6, 6), &hover)
+
+	if !strings.Contains(hover.Contents.Value, "enum demo.Color") || !strings.Contains(hover.Contents.Value, "Color palette") {
+		t.Fatalf("unexpect hover %s", hover.Contents.Value)
+	}
+
+	var references []*lsp.Location
+
+	client.call("textDocument/references", &lsp.ReferenceParams{
+		TextDocumentPositionParams: *position(uri, 6, 6),
+	}, &references)
+
+	if len(references) != 2 {
+		t.Fatalf("expect 2 references of Color, got %v", references)
+	}
+
+	var completion lsp.CompletionList
+
+	// enum constants
+	client.call("textDocument/completion", position(uri, 18, 31), &completion)
+
+	if found := labels(&completion); !found["Red"] || !found["Green"] || len(found) != 2 {
+		t.Fatalf("unexpect enum constant completion %v", found)
+	}
+
+	// annotation arg labels
+	client.call("textDocument/completion", position(uri, 18, 5), &completion)
+
+	if found := labels(&completion); !found["Name:"] || !found["Color:"] {
+		t.Fatalf("unexpect label completion %v", found)
+	}
+
+	// type names with typed prefix
+	client.call("textDocument/completion", position(uri, 21, 6), &completion)
+
+	if found := labels(&completion); !found["Color"] || found["Point"] {
+		t.Fatalf("unexpect type completion %v", found)
+	}
+
+	// annotation types
+	client.call("textDocument/completion", position(uri, 18, 1), &completion)
+
+	if found := labels(&completion); !found["Tag"] || !found["Usage"] || found["Point"] {
+		t.Fatalf("unexpect annotation completion %v", found)
+	}
+
+	// link error after change
+	client.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []map[string]string{{"text": strings.Replace(lspScript, "Color Fill", "Colour Fill", 1)}},
+	})
+
+	client.sync()
+
+	diagnostics := client.diagnostics[uri]
+
+	if len(diagnostics) != 1 || diagnostics[0].Range.Start != (lsp.Position{Line: 21, Character: 4}) {
+		t.Fatalf("unexpect diagnostics %v", diagnostics)
+	}
+
+	// syntax error after change
+	client.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 3},
+		"contentChanges": []map[string]string{{"text": strings.Replace(lspScript, "int32 X;", "int32 X", 1)}},
+	})
+
+	client.sync()
+
+	if diagnostics := client.diagnostics[uri]; len(diagnostics) != 1 || diagnostics[0].Range.Start.Line != 21 {
+		t.Fatalf("unexpect diagnostics %v", diagnostics)
+	}
+
+	client.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 4},
+		"contentChanges": []map[string]string{{"text": lspScript}},
+	})
+
+	client.sync()
+
+	if diagnostics := client.diagnostics[uri]; len(diagnostics) != 0 {
+		t.Fatalf("expect empty diagnostics, got %v", diagnostics)
+	}
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gsrpc/gslang/openapi"
+)
+
+func TestOpenAPI(t *testing.T) {
+
+	document, err := openapi.Generate(compile(t, "test.gs"), "test", "1.0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duration, ok := document.Components.Schemas["gslang.test.Duration"]
+
+	if !ok {
+		t.Fatal("expect table gslang.test.Duration schema")
+	}
+
+	if duration.Properties["Unit"].Ref != "#/components/schemas/gslang.test.TimeUnit" {
+		t.Fatalf("expect enum ref, got %s", duration.Properties["Unit"].Ref)
+	}
+
+	if _, ok := document.Components.Schemas["gslang.test.Timeout"]; ok {
+		t.Fatal("annotation table should not generate schema")
+	}
+
+	get, ok := document.Paths["/gslang.test.HttpREST/Get"]
+
+	if !ok {
+		t.Fatal("expect HttpREST.Get operation")
+	}
+
+	if get.Post.Summary != "get invoke http get method" {
+		t.Fatalf("expect doc comment summary, got %s", get.Post.Summary)
+	}
+
+	if _, ok := get.Post.Responses["500"]; !ok {
+		t.Fatal("expect exception response")
+	}
+
+	if _, ok := document.Paths["/gslang.test.HttpREST/Post"].Post.Responses["204"]; !ok {
+		t.Fatal("expect void method no content response")
+	}
+
+	var buff bytes.Buffer
+
+	if err := document.Write(&buff); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var commentsScript = `package comments;
+
+contract Store {
+    // lookup values
+    (
+        // status code
+        int32 code,
+        string[] values
+    ) Lookup(
+        // the lookup key
+        string key,
+        // max values
+        // returned
+        uint16 limit);
+}
+`
+
+func TestOpenAPIComments(t *testing.T) {
+
+	compiler, errs := compileModule(t, "comments.gs", commentsScript)
+
+	if len(errs) != 0 {
+		t.Fatalf("unexpect errors %v", errs)
+	}
+
+	document, err := openapi.Generate(compiler, "comments", "1.0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := document.Paths["/comments.Store/Lookup"].Post
+
+	if lookup.Summary != "lookup values" {
+		t.Fatalf("expect method summary, got %s", lookup.Summary)
+	}
+
+	params := lookup.RequestBody.Content["application/json"].Schema
+
+	if params.Properties["key"].Description != "the lookup key" || params.Properties["limit"].Description != "max values returned" {
+		t.Fatalf("expect param descriptions, got %q and %q", params.Properties["key"].Description, params.Properties["limit"].Description)
+	}
+
+	results := lookup.Responses["200"].Content["application/json"].Schema
+
+	if results.Properties["code"].Description != "status code" || results.Properties["values"].Description != "" {
+		t.Fatalf("expect result descriptions, got %q and %q", results.Properties["code"].Description, results.Properties["values"].Description)
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/descriptor"
+)
+
+func TestParent(t *testing.T) {
+
+	compiler := compile(t, "test.gs")
+
+	checkParent(t, compiler.Module())
+
+	// the decoded module keeps the same parent invariant
+	data, err := descriptor.Encode(compiler.Module())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := descriptor.Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkParent(t, module)
+
+	contract := compiler.Module().Types["gslang.test.HttpREST"].(*ast.Contract)
+
+	post, _ := contract.Method("Post")
+
+	content, _ := post.Param("content")
+
+	if ast.EnclosingType(content) != contract || ast.EnclosingType(content.Type.(*ast.Seq).Component) != contract {
+		t.Fatalf("param %s enclosing type expect %s", content, contract)
+	}
+
+	if script := ast.EnclosingScript(content); script == nil || script.Name() != "test.gs" {
+		t.Fatalf("param %s enclosing script expect test.gs but got %v", content, script)
+	}
+
+	if ast.EnclosingType(contract) != nil {
+		t.Fatalf("type %s isn't enclosed by type", contract)
+	}
+}
+
+// checkParent check every node parent is the node inspecting it
+func checkParent(t *testing.T, module *ast.Module) {
+
+	var stack []ast.Node
+
+	ast.Inspect(module, func(node ast.Node) bool {
+
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+
+		if len(stack) != 0 && node.Parent() != stack[len(stack)-1] {
+			t.Fatalf("node %s(%T) parent expect %s but got %v", node, node, stack[len(stack)-1], node.Parent())
+		}
+
+		stack = append(stack, node)
+
+		return true
+	})
+}
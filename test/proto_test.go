@@ -0,0 +1,176 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+)
+
+func TestImportProto(t *testing.T) {
+
+	compiler := gslang.NewCompiler("test", gslang.HandleError(func(err *gslang.Error) {
+		gserrors.Panicf(err.Orignal, "parse %s error\n\t%s", err.Start, err.Text)
+	}))
+
+	for _, file := range []string{"../gslang.gs", "../annotations.gs", "../proto.gs"} {
+		if err := compiler.Compile(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := compiler.ImportProto("test.proto"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := compiler.Link(); err != nil {
+		t.Fatal(err)
+	}
+
+	user, ok := compiler.Eval().GetType("gslang.test.proto.User")
+
+	if !ok {
+		t.Fatal("expect imported message gslang.test.proto.User")
+	}
+
+	addresses, ok := user.(*ast.Table).Field("addresses")
+
+	if !ok {
+		t.Fatal("expect field User.addresses")
+	}
+
+	seq, ok := addresses.Type.(*ast.Seq)
+
+	if !ok || seq.Component.(*ast.TypeRef).Ref.FullName() != "gslang.test.proto.User_Address" {
+		t.Fatalf("expect nested message seq field, got %s", addresses.Type.FullName())
+	}
+
+	home, _ := user.(*ast.Table).Field("home")
+
+	if optional, ok := home.Type.(*ast.Optional); !ok || optional.Component.(*ast.TypeRef).Ref.FullName() != "gslang.test.proto.User_Address" {
+		t.Fatalf("expect optional message field, got %s", home.Type.FullName())
+	}
+
+	name, _ := user.(*ast.Table).Field("name")
+
+	if _, ok := gslang.FindAnnotation(name, gslang.ProtoTag); !ok {
+		t.Fatal("expect field number annotation")
+	}
+
+	if _, ok := gslang.FindAnnotation(name, gslang.ProtoOption); !ok {
+		t.Fatal("expect field option annotation")
+	}
+
+	response, _ := compiler.Eval().GetType("gslang.test.proto.ListUsersResponse")
+
+	next, _ := response.(*ast.Table).Field("next")
+
+	if next.Type.(*ast.TypeRef).Ref.FullName() != "gslang.test.common.Cursor" {
+		t.Fatalf("expect imported proto type reference, got %s", next.Type.FullName())
+	}
+
+	service, ok := compiler.Eval().GetType("gslang.test.proto.UserService")
+
+	if !ok {
+		t.Fatal("expect imported service gslang.test.proto.UserService")
+	}
+
+	method, ok := service.(*ast.Contract).Method("ListUsers")
+
+	if !ok || method.Return.(*ast.TypeRef).Ref != response {
+		t.Fatal("expect rpc ListUsers return ListUsersResponse")
+	}
+
+	watch, ok := service.(*ast.Contract).Method("WatchUsers")
+
+	if !ok || watch.Stream != ast.StreamBidi || !watch.Params[0].Stream {
+		t.Fatal("expect bidi streaming rpc WatchUsers")
+	}
+
+	tail, ok := service.(*ast.Contract).Method("TailUsers")
+
+	if !ok || tail.Stream != ast.StreamServer || tail.Params[0].Stream || tail.Params[0].Type.(*ast.TypeRef).Ref.Name() != "stream" {
+		t.Fatal("expect server streaming rpc TailUsers with message stream")
+	}
+
+	status, _ := compiler.Eval().GetType("gslang.test.proto.Status")
+
+	if constant, _ := status.(*ast.Enum).Constant("BANNED"); constant.Value != -1 {
+		t.Fatalf("expect enum constant BANNED(-1), got %d", constant.Value)
+	}
+
+	role, _ := compiler.Eval().GetType("gslang.test.proto.Role")
+
+	if user, _ := role.(*ast.Enum).Constant("USER"); !gslang.ProtoAllowAlias(role.(*ast.Enum)) || user.Value != 1 {
+		t.Fatal("expect aliased enum constant USER(1)")
+	}
+
+	// enum value options
+	banned, _ := status.(*ast.Enum).Constant("BANNED")
+
+	if option, ok := gslang.FindAnnotation(banned, gslang.ProtoOption); !ok || option.Type.Ref == nil {
+		t.Fatal("expect enum constant BANNED option")
+	}
+}
+
+func TestImportProtoPanic(t *testing.T) {
+
+	compiler := gslang.NewCompiler("test", gslang.HandleError(func(err *gslang.Error) {
+		panic(err.Text)
+	}))
+
+	err := compiler.ImportProto("broken.proto")
+
+	if err == nil {
+		t.Fatal("expect import error")
+	}
+
+	if _, ok := err.(gserrors.GSError); !ok {
+		t.Fatalf("expect gserrors.GSError, got %T", err)
+	}
+}
+
+func TestImportProtoNoPackage(t *testing.T) {
+
+	compiler := gslang.NewCompiler("test", gslang.HandleError(func(err *gslang.Error) {
+		gserrors.Panicf(err.Orignal, "parse %s error\n\t%s", err.Start, err.Text)
+	}))
+
+	for _, file := range []string{"../gslang.gs", "../annotations.gs", "../proto.gs"} {
+		if err := compiler.Compile(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := compiler.ImportProto("nopkg.proto"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the types are declared in the package named by the proto file name
+	script := "package gslang.test.nopkg;\nusing nopkg.Outer;\ntable Wrapper { Outer Value; nopkg.Kind Kind; }\n"
+
+	if err := compiler.CompileSource("wrapper.gs", []byte(script)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := compiler.Link(); err != nil {
+		t.Fatal(err)
+	}
+
+	outer, ok := compiler.Eval().GetType("nopkg.Outer")
+
+	if !ok {
+		t.Fatal("expect imported message nopkg.Outer")
+	}
+
+	if inner, _ := outer.(*ast.Table).Field("inner"); inner.Type.(*ast.TypeRef).Ref.FullName() != "nopkg.Outer_Inner" {
+		t.Fatalf("expect nested message field, got %s", inner.Type)
+	}
+
+	wrapper, _ := compiler.Eval().GetType("gslang.test.nopkg.Wrapper")
+
+	if value, _ := wrapper.(*ast.Table).Field("Value"); value.Type.(*ast.TypeRef).Ref != outer {
+		t.Fatalf("expect field referencing nopkg.Outer, got %s", value.Type)
+	}
+}
@@ -0,0 +1,348 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/gsrpc/gslang/gogen"
+	"github.com/gsrpc/gslang/rpc"
+	"github.com/gsrpc/gslang/test/stub"
+)
+
+func TestGoStub(t *testing.T) {
+
+	compiler := compile(t, "stub/stub.gs")
+
+	content, err := gogen.Generate(compiler, "gslang.test.stub")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect, err := ioutil.ReadFile("stub/stub.go")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(content, expect) {
+		t.Fatalf("generated stub differs from stub/stub.go, regenerate it by gslangc go\n%s", content)
+	}
+}
+
+type _Logs struct {
+	entries []*stub.Entry
+}
+
+func (logs *_Logs) Write(source string, entries <-chan *stub.Entry) (int32, error) {
+
+	for entry := range entries {
+		entry.Source = &source
+		logs.entries = append(logs.entries, entry)
+	}
+
+	return int32(len(logs.entries)), nil
+}
+
+func (logs *_Logs) Tail(filters <-chan *stub.Filter, out chan<- *stub.Event) error {
+
+	for filter := range filters {
+
+		if filter.Min > stub.LevelError {
+			return &stub.Stopped{Reason: "no such level"}
+		}
+
+		for _, entry := range logs.entries {
+			if entry.Level >= filter.Min {
+				out <- &stub.Event{Entry: entry}
+			}
+		}
+
+		note := fmt.Sprintf("end of %d", filter.Min)
+
+		out <- &stub.Event{Note: &note}
+	}
+
+	return nil
+}
+
+func (logs *_Logs) Lines(max int32, out chan<- *stub.LogsLinesResult) error {
+
+	for i, entry := range logs.entries {
+
+		if int32(i) == max {
+			return errors.New("too many lines")
+		}
+
+		out <- &stub.LogsLinesResult{Seq: int32(i), Text: entry.Text}
+	}
+
+	return nil
+}
+
+func (logs *_Logs) Stat(source string) (int32, stub.Level, error) {
+
+	var count int32
+
+	last := stub.LevelDebug
+
+	if len(logs.sources(source)) == 0 {
+		return 0, last, &stub.NotFound{Source: source}
+	}
+
+	for _, entry := range logs.entries {
+		if entry.Source != nil && *entry.Source == source {
+			count++
+			last = entry.Level
+		}
+	}
+
+	return count, last, nil
+}
+
+func (logs *_Logs) Clear() error {
+	logs.entries = nil
+	return nil
+}
+
+func (logs *_Logs) Query(offset int32, limit int32) (*stub.PageEntry, error) {
+
+	if offset > int32(len(logs.entries)) {
+		return nil, &stub.NotFound{Source: fmt.Sprintf("offset %d", offset)}
+	}
+
+	page := &stub.PageEntry{Items: logs.entries[offset:], Next: -1}
+
+	if int32(len(page.Items)) > limit {
+		page.Items = page.Items[:limit]
+		page.Next = offset + limit
+	}
+
+	return page, nil
+}
+
+func (logs *_Logs) Snapshot() (*stub.Snapshot, error) {
+
+	snapshot := &stub.Snapshot{Entries: &stub.PageEntry{Items: logs.entries, Next: -1}}
+
+	if sources := logs.sources(""); len(sources) > 0 {
+		snapshot.Sources = &stub.PageString{Items: sources, Next: -1}
+	}
+
+	return snapshot, nil
+}
+
+// sources get the entry sources, filtered by source if it isn't empty
+func (logs *_Logs) sources(source string) (sources []string) {
+
+	for _, entry := range logs.entries {
+		if entry.Source != nil && (source == "" || *entry.Source == source) {
+			sources = append(sources, *entry.Source)
+		}
+	}
+
+	return
+}
+
+func isNotFound(err error, source string) bool {
+	exception, ok := err.(*stub.NotFound)
+
+	return ok && exception.Source == source
+}
+
+func TestGoStubPipe(t *testing.T) {
+
+	server := rpc.NewServer()
+
+	stub.RegisterLogs(server, &_Logs{})
+
+	serverPipe, clientPipe := net.Pipe()
+
+	rpc.NewConn(serverPipe, server)
+
+	conn := rpc.NewConn(clientPipe, nil)
+
+	defer conn.Close()
+
+	client := stub.NewLogsClient(conn)
+
+	// client stream
+	entries := make(chan *stub.Entry)
+
+	go func() {
+		entries <- &stub.Entry{Level: stub.LevelInfo, Text: "started"}
+		entries <- &stub.Entry{Level: stub.LevelWarn, Text: "slow"}
+		entries <- &stub.Entry{Level: stub.LevelError, Text: "failed"}
+		close(entries)
+	}()
+
+	count, err := client.Write("main", entries)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 3 {
+		t.Fatalf("expect 3 written entries, got %d", count)
+	}
+
+	// unary
+	count, last, err := client.Stat("main")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 3 || last != stub.LevelError {
+		t.Fatalf("expect stat (3, %d), got (%d, %d)", stub.LevelError, count, last)
+	}
+
+	// generic table instances
+	page, err := client.Query(1, 1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Items) != 1 || page.Items[0].Text != "slow" || page.Next != 2 {
+		t.Fatalf("unexpected page %v", page)
+	}
+
+	snapshot, err := client.Snapshot()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshot.Entries.Items) != 3 || snapshot.Sources == nil || len(snapshot.Sources.Items) != 3 {
+		t.Fatalf("unexpected snapshot %v", snapshot)
+	}
+
+	// typed exceptions
+	if _, err := client.Query(10, 1); !isNotFound(err, "offset 10") {
+		t.Fatalf("expect NotFound exception, got %v", err)
+	}
+
+	if _, _, err := client.Stat("other"); !isNotFound(err, "other") {
+		t.Fatalf("expect NotFound exception, got %v", err)
+	}
+
+	// bidi stream
+	filters := make(chan *stub.Filter, 2)
+
+	filters <- &stub.Filter{Min: stub.LevelWarn}
+	filters <- &stub.Filter{Min: stub.LevelError}
+
+	close(filters)
+
+	tail, err := client.Tail(filters)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+
+	for tail.Next() {
+		if event := tail.Value(); event.Entry != nil {
+			events = append(events, event.Entry.Text)
+		} else {
+			events = append(events, *event.Note)
+		}
+	}
+
+	if err := tail.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(events) != fmt.Sprint([]string{"slow", "failed", "end of 5", "failed", "end of 6"}) {
+		t.Fatalf("unexpected tail events %v", events)
+	}
+
+	// server stream
+	lines, err := client.Lines(10)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var texts []string
+
+	for lines.Next() {
+		if lines.Value().Seq != int32(len(texts)) {
+			t.Fatalf("expect line seq %d, got %d", len(texts), lines.Value().Seq)
+		}
+
+		texts = append(texts, lines.Value().Text)
+	}
+
+	if err := lines.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(texts) != fmt.Sprint([]string{"started", "slow", "failed"}) {
+		t.Fatalf("unexpected lines %v", texts)
+	}
+
+	// remote error after stream values
+	lines, err = client.Lines(1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received int
+
+	for lines.Next() {
+		received++
+	}
+
+	if received != 1 {
+		t.Fatalf("expect 1 line before error, got %d", received)
+	}
+
+	if remote, ok := lines.Err().(*rpc.Error); !ok || remote.Text != "too many lines" {
+		t.Fatalf("expect remote error, got %v", lines.Err())
+	}
+
+	// exception thrown by stream
+	stopped := make(chan *stub.Filter, 1)
+
+	stopped <- &stub.Filter{Min: stub.LevelError + 1}
+
+	close(stopped)
+
+	tail, err = client.Tail(stopped)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for tail.Next() {
+		t.Fatalf("unexpected tail event %v", tail.Value())
+	}
+
+	if exception, ok := tail.Err().(*stub.Stopped); !ok || exception.Reason != "no such level" {
+		t.Fatalf("expect Stopped exception, got %v", tail.Err())
+	}
+
+	// void
+	if err := client.Clear(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := client.Stat("main"); !isNotFound(err, "main") {
+		t.Fatal("expect NotFound exception after cleared")
+	}
+
+	// unknown method
+	if err := conn.Call("logs/v2/3", nil); err == nil {
+		t.Fatal("expect unknown method error")
+	} else if _, ok := err.(*rpc.Error); !ok {
+		t.Fatalf("expect remote error, got %v", err)
+	}
+}
@@ -0,0 +1,440 @@
+// Code generated by gslangc go. DO NOT EDIT.
+
+package stub
+
+import "github.com/gsrpc/gslang/rpc"
+
+// Entry log entry
+type Entry struct {
+	Level  Level   `json:"Level"`
+	Text   string  `json:"Text"`
+	Source *string `json:"Source,omitempty"`
+}
+
+// Event tail event
+type Event struct {
+	Entry *Entry  `json:"Entry,omitempty"`
+	Note  *string `json:"Note,omitempty"`
+}
+
+// Filter table gslang.test.stub.Filter
+type Filter struct {
+	Min Level `json:"Min"`
+}
+
+// Level log level
+type Level byte
+
+// Level constants
+const (
+	LevelDebug Level = 0
+	LevelInfo  Level = 1
+	LevelWarn  Level = 5
+	LevelError Level = 6
+)
+
+// Logs contract gslang.test.stub.Logs
+type Logs interface {
+	Write(source string, entries <-chan *Entry) (int32, error)
+	Tail(filters <-chan *Filter, rpcOut chan<- *Event) error
+	Lines(max int32, rpcOut chan<- *LogsLinesResult) error
+	Stat(source string) (int32, Level, error)
+	Clear() error
+	Query(offset int32, limit int32) (*PageEntry, error)
+	Snapshot() (*Snapshot, error)
+}
+
+// RegisterLogs register Logs implementation as the method handlers of service logs
+func RegisterLogs(rpcServer *rpc.Server, rpcImpl Logs) {
+	rpcServer.Handle("logs/v2/0", func(rpcStream *rpc.Stream) error {
+		var source string
+
+		if rpcErr := rpcStream.RecvArgs(&source); rpcErr != nil {
+			return rpcErr
+		}
+
+		entries := make(chan *Entry)
+
+		go func() {
+			defer close(entries)
+
+			for {
+				var rpcVal *Entry
+
+				if rpcStream.Recv(&rpcVal) != nil {
+					return
+				}
+
+				select {
+				case entries <- rpcVal:
+				case <-rpcStream.Done():
+					return
+				}
+			}
+		}()
+
+		rpcRet0, rpcErr := rpcImpl.Write(source, entries)
+
+		if rpcErr != nil {
+			return rpcErr
+		}
+
+		return rpcStream.SendArgs(rpcRet0)
+	})
+
+	rpcServer.Handle("logs/v2/1", func(rpcStream *rpc.Stream) error {
+
+		if rpcErr := rpcStream.RecvArgs(); rpcErr != nil {
+			return rpcErr
+		}
+
+		filters := make(chan *Filter)
+
+		go func() {
+			defer close(filters)
+
+			for {
+				var rpcVal *Filter
+
+				if rpcStream.Recv(&rpcVal) != nil {
+					return
+				}
+
+				select {
+				case filters <- rpcVal:
+				case <-rpcStream.Done():
+					return
+				}
+			}
+		}()
+
+		rpcOut := make(chan *Event)
+
+		rpcResult := make(chan error, 1)
+
+		go func() {
+			rpcResult <- rpcImpl.Tail(filters, rpcOut)
+			close(rpcOut)
+		}()
+
+		var rpcErr error
+
+		for rpcVal := range rpcOut {
+			if rpcErr == nil {
+				rpcErr = rpcStream.Send(rpcVal)
+			}
+		}
+
+		if rpcImplErr := <-rpcResult; rpcImplErr != nil {
+			return logsTailExceptions.Throw(rpcImplErr)
+		}
+
+		return rpcErr
+	})
+
+	rpcServer.Handle("logs/v2/2", func(rpcStream *rpc.Stream) error {
+		var max int32
+
+		if rpcErr := rpcStream.RecvArgs(&max); rpcErr != nil {
+			return rpcErr
+		}
+
+		rpcOut := make(chan *LogsLinesResult)
+
+		rpcResult := make(chan error, 1)
+
+		go func() {
+			rpcResult <- rpcImpl.Lines(max, rpcOut)
+			close(rpcOut)
+		}()
+
+		var rpcErr error
+
+		for rpcVal := range rpcOut {
+			if rpcErr == nil {
+				rpcErr = rpcStream.Send(rpcVal)
+			}
+		}
+
+		if rpcImplErr := <-rpcResult; rpcImplErr != nil {
+			return rpcImplErr
+		}
+
+		return rpcErr
+	})
+
+	rpcServer.Handle("logs/v2/10", func(rpcStream *rpc.Stream) error {
+		var source string
+
+		if rpcErr := rpcStream.RecvArgs(&source); rpcErr != nil {
+			return rpcErr
+		}
+
+		rpcRet0, rpcRet1, rpcErr := rpcImpl.Stat(source)
+
+		if rpcErr != nil {
+			return logsStatExceptions.Throw(rpcErr)
+		}
+
+		return rpcStream.SendArgs(rpcRet0, rpcRet1)
+	})
+
+	rpcServer.Handle("logs/v2/4", func(rpcStream *rpc.Stream) error {
+
+		if rpcErr := rpcStream.RecvArgs(); rpcErr != nil {
+			return rpcErr
+		}
+
+		if rpcErr := rpcImpl.Clear(); rpcErr != nil {
+			return rpcErr
+		}
+
+		return rpcStream.SendArgs()
+	})
+
+	rpcServer.Handle("logs/v2/5", func(rpcStream *rpc.Stream) error {
+		var offset int32
+		var limit int32
+
+		if rpcErr := rpcStream.RecvArgs(&offset, &limit); rpcErr != nil {
+			return rpcErr
+		}
+
+		rpcRet0, rpcErr := rpcImpl.Query(offset, limit)
+
+		if rpcErr != nil {
+			return logsQueryExceptions.Throw(rpcErr)
+		}
+
+		return rpcStream.SendArgs(rpcRet0)
+	})
+
+	rpcServer.Handle("logs/v2/6", func(rpcStream *rpc.Stream) error {
+
+		if rpcErr := rpcStream.RecvArgs(); rpcErr != nil {
+			return rpcErr
+		}
+
+		rpcRet0, rpcErr := rpcImpl.Snapshot()
+
+		if rpcErr != nil {
+			return rpcErr
+		}
+
+		return rpcStream.SendArgs(rpcRet0)
+	})
+
+}
+
+// LogsClient client stub of contract gslang.test.stub.Logs
+type LogsClient struct {
+	conn *rpc.Conn
+}
+
+// NewLogsClient create client stub over rpc connection
+func NewLogsClient(conn *rpc.Conn) *LogsClient {
+	return &LogsClient{conn: conn}
+}
+
+// Write call method Write
+func (rpcClient *LogsClient) Write(source string, entries <-chan *Entry) (rpcRet0 int32, rpcErr error) {
+	rpcStream, rpcErr := rpcClient.conn.Open("logs/v2/0", source)
+
+	if rpcErr != nil {
+		return
+	}
+
+	go func() {
+		for rpcVal := range entries {
+			if rpcStream.Send(rpcVal) != nil {
+				return
+			}
+		}
+
+		rpcStream.CloseSend()
+	}()
+
+	rpcErr = rpcStream.RecvArgs(&rpcRet0)
+
+	return
+}
+
+// Tail open stream of method Tail, the values are got by the returned iterator
+func (rpcClient *LogsClient) Tail(filters <-chan *Filter) (rpcIter *LogsTailIterator, rpcErr error) {
+	rpcStream, rpcErr := rpcClient.conn.Open("logs/v2/1")
+
+	if rpcErr != nil {
+		return
+	}
+
+	go func() {
+		for rpcVal := range filters {
+			if rpcStream.Send(rpcVal) != nil {
+				return
+			}
+		}
+
+		rpcStream.CloseSend()
+	}()
+
+	rpcIter = &LogsTailIterator{Iterator: rpc.NewIterator(rpcStream)}
+
+	return
+}
+
+// Lines open stream of method Lines, the values are got by the returned iterator
+func (rpcClient *LogsClient) Lines(max int32) (rpcIter *LogsLinesIterator, rpcErr error) {
+	rpcStream, rpcErr := rpcClient.conn.Open("logs/v2/2", max)
+
+	if rpcErr != nil {
+		return
+	}
+
+	if rpcErr = rpcStream.CloseSend(); rpcErr != nil {
+		return
+	}
+
+	rpcIter = &LogsLinesIterator{Iterator: rpc.NewIterator(rpcStream)}
+
+	return
+}
+
+// Stat call method Stat
+func (rpcClient *LogsClient) Stat(source string) (rpcRet0 int32, rpcRet1 Level, rpcErr error) {
+	rpcErr = logsStatExceptions.Catch(rpcClient.conn.Call("logs/v2/10", []interface{}{source}, &rpcRet0, &rpcRet1))
+
+	return
+}
+
+// Clear call method Clear
+func (rpcClient *LogsClient) Clear() (rpcErr error) {
+	rpcErr = rpcClient.conn.Call("logs/v2/4", []interface{}{})
+
+	return
+}
+
+// Query call method Query
+func (rpcClient *LogsClient) Query(offset int32, limit int32) (rpcRet0 *PageEntry, rpcErr error) {
+	rpcErr = logsQueryExceptions.Catch(rpcClient.conn.Call("logs/v2/5", []interface{}{offset, limit}, &rpcRet0))
+
+	return
+}
+
+// Snapshot call method Snapshot
+func (rpcClient *LogsClient) Snapshot() (rpcRet0 *Snapshot, rpcErr error) {
+	rpcErr = rpcClient.conn.Call("logs/v2/6", []interface{}{}, &rpcRet0)
+
+	return
+}
+
+// logsTailExceptions exceptions thrown by contract gslang.test.stub.Logs method Tail
+var logsTailExceptions = rpc.Exceptions{
+	0: func() error { return &Stopped{} },
+}
+
+// LogsTailIterator iterator of contract gslang.test.stub.Logs method Tail stream values
+type LogsTailIterator struct {
+	*rpc.Iterator
+	value *Event
+}
+
+// Next receive next value, returns false if the stream is closed or failed, see Err
+func (iter *LogsTailIterator) Next() bool {
+	var value *Event
+
+	if !iter.Iterator.Next(&value) {
+		return false
+	}
+
+	iter.value = value
+
+	return true
+}
+
+// Value get current value
+func (iter *LogsTailIterator) Value() *Event {
+	return iter.value
+}
+
+// Err get iteration error, the thrown exceptions are got as typed errors
+func (iter *LogsTailIterator) Err() error {
+	return logsTailExceptions.Catch(iter.Iterator.Err())
+}
+
+// LogsLinesResult stream value of contract gslang.test.stub.Logs method Lines results
+type LogsLinesResult struct {
+	Seq  int32  `json:"seq"`
+	Text string `json:"text"`
+}
+
+// LogsLinesIterator iterator of contract gslang.test.stub.Logs method Lines stream values
+type LogsLinesIterator struct {
+	*rpc.Iterator
+	value *LogsLinesResult
+}
+
+// Next receive next value, returns false if the stream is closed or failed, see Err
+func (iter *LogsLinesIterator) Next() bool {
+	var value *LogsLinesResult
+
+	if !iter.Iterator.Next(&value) {
+		return false
+	}
+
+	iter.value = value
+
+	return true
+}
+
+// Value get current value
+func (iter *LogsLinesIterator) Value() *LogsLinesResult {
+	return iter.value
+}
+
+// logsStatExceptions exceptions thrown by contract gslang.test.stub.Logs method Stat
+var logsStatExceptions = rpc.Exceptions{
+	0: func() error { return &NotFound{} },
+}
+
+// logsQueryExceptions exceptions thrown by contract gslang.test.stub.Logs method Query
+var logsQueryExceptions = rpc.Exceptions{
+	0: func() error { return &NotFound{} },
+}
+
+// NotFound table gslang.test.stub.NotFound
+type NotFound struct {
+	Source string `json:"Source"`
+}
+
+// Error implement error interface
+func (err *NotFound) Error() string {
+	return "gslang.test.stub.NotFound"
+}
+
+// PageEntry instance Page<gslang.test.stub.Entry> of generic table gslang.test.stub.Page
+type PageEntry struct {
+	Items []*Entry `json:"Items"`
+	Next  int32    `json:"Next"`
+}
+
+// PageString instance Page<string> of generic table gslang.test.stub.Page
+type PageString struct {
+	Items []string `json:"Items"`
+	Next  int32    `json:"Next"`
+}
+
+// Snapshot entries snapshot
+type Snapshot struct {
+	Entries *PageEntry  `json:"Entries"`
+	Sources *PageString `json:"Sources,omitempty"`
+}
+
+// Stopped table gslang.test.stub.Stopped
+type Stopped struct {
+	Reason string `json:"Reason"`
+}
+
+// Error implement error interface
+func (err *Stopped) Error() string {
+	return "gslang.test.stub.Stopped"
+}
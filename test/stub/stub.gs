@@ -0,0 +1,66 @@
+package gslang.test.stub;
+
+using gslang.Exception;
+using gslang.MethodID;
+using gslang.Service;
+
+// log level
+enum Level {
+    Debug, Info, Warn(5), Error
+}
+
+// log entry
+table Entry {
+    Level Level;
+    string Text;
+    string? Source;
+}
+
+table Filter {
+    Level Min;
+}
+
+// tail event
+union Event {
+    Entry Entry;
+    string Note(3);
+}
+
+// page of query results
+table Page<T> {
+    T[] Items;
+    int32 Next;
+}
+
+// entries snapshot
+table Snapshot {
+    Page<Entry> Entries;
+    Page<string>? Sources;
+}
+
+// unknown log source
+@Exception
+table NotFound {
+    string Source;
+}
+
+// tail stopped by server
+@Exception
+table Stopped {
+    string Reason;
+}
+
+// log service
+@Service(Name:"logs", Version:2)
+contract Logs {
+    // write entries
+    int32 Write(string source, stream Entry entries);
+    // tail entries matched by filters
+    stream Event Tail(stream Filter filters) throws (Stopped);
+    stream (int32 seq, string text) Lines(int32 max);
+    @MethodID(10)
+    (int32 count, Level last) Stat(string source) throws (NotFound);
+    void Clear();
+    Page<Entry> Query(int32 offset, int32 limit) throws (NotFound);
+    Snapshot Snapshot();
+}
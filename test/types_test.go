@@ -0,0 +1,804 @@
+package test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/descriptor"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+var optionalScript = `package optional;
+
+using gslang.POD;
+
+table Point {
+    int32 X;
+    int32 Y;
+}
+
+table Shape {
+    Point? Center;
+    Point?[] Points;
+    Point[]? Path;
+    Point?[4] Corners;
+}
+
+@POD
+table Compact {
+    int32? X;
+}
+
+contract Service {
+    Point? Find(string? name);
+    void? Call();
+}
+`
+
+func TestOptional(t *testing.T) {
+
+	compiler, errs := compileModule(t, "optional.gs", optionalScript)
+
+	expect := []string{
+		"fixed-size seq(Point?[4]) can't contain optional type",
+		"POD table(Compact) can't contain optional type(int32?)",
+		"void can't be optional",
+	}
+
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors, got %v", len(expect), errs)
+	}
+
+	// types are linked in map order
+	for _, text := range expect {
+		if !containsError(errs, text) {
+			t.Fatalf("expect error %s, got %v", text, errs)
+		}
+	}
+
+	shape := compiler.Module().Types["optional.Shape"].(*ast.Table)
+
+	center, _ := shape.Field("Center")
+
+	optional, ok := center.Type.(*ast.Optional)
+
+	if !ok || optional.Component.(*ast.TypeRef).Ref != compiler.Module().Types["optional.Point"] {
+		t.Fatalf("expect linked optional type, got %s", center.Type)
+	}
+
+	path, _ := shape.Field("Path")
+
+	if optional, ok := path.Type.(*ast.Optional); !ok || !isSeq(optional.Component) {
+		t.Fatalf("expect optional seq, got %s", path.Type)
+	}
+}
+
+func containsError(errs []*gslang.Error, text string) bool {
+	for _, err := range errs {
+		if strings.Contains(err.Text, text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSeq(typeDecl ast.Type) bool {
+	_, ok := typeDecl.(*ast.Seq)
+	return ok
+}
+
+var unionScript = `package unions;
+
+table Created {
+    string Name;
+}
+
+table Deleted {
+    string Name;
+}
+
+union Event {
+    Created Created;
+    Deleted Deleted(5);
+    string Note;
+}
+
+union Broken {
+    Created A;
+    Created B;
+    int32 C(1);
+    void D;
+}
+
+union Empty {}
+
+contract Service {
+    Event Next();
+}
+`
+
+func TestUnion(t *testing.T) {
+
+	compiler, errs := compileModule(t, "union.gs", unionScript)
+
+	expect := []string{
+		"duplicate union(Broken) case type(unions.Created) : A and B",
+		"duplicate union(Broken) case tag(1) : B and C",
+		"union(Broken) case(D) can't be void",
+		"union(Empty) must declare at least one case",
+	}
+
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors, got %v", len(expect), errs)
+	}
+
+	for i, err := range errs {
+		if !strings.Contains(err.Text, expect[i]) {
+			t.Fatalf("expect error %s, got %s", expect[i], err)
+		}
+	}
+
+	event := compiler.Module().Types["unions.Event"].(*ast.Union)
+
+	for name, tag := range map[string]int{"Created": 0, "Deleted": 5, "Note": 6} {
+		if unionCase, ok := event.Case(name); !ok || unionCase.Tag != tag {
+			t.Fatalf("expect union case %s tag %d", name, tag)
+		}
+	}
+
+	created, _ := event.Case("Created")
+
+	if created.Type.(*ast.TypeRef).Ref != compiler.Module().Types["unions.Created"] {
+		t.Fatalf("expect linked union case type, got %s", created.Type)
+	}
+
+	data, err := descriptor.Encode(compiler.Module())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := descriptor.Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, ok := module.Types["unions.Event"].(*ast.Union)
+
+	if !ok || len(decoded.Cases) != 3 || decoded.Cases[1].Tag != 5 {
+		t.Fatalf("expect decoded union Event, got %v", module.Types["unions.Event"])
+	}
+}
+
+var keywordScript = `package keywords;
+
+table union {
+    int32 Tag;
+}
+
+table stream {
+    int32 Seq;
+}
+
+union Value {
+    union union;
+}
+
+table Holder {
+    union union;
+    stream stream;
+}
+
+contract Service {
+    void Set(union union, stream stream);
+    stream Get(stream stream);
+    stream stream Watch(stream stream filters);
+}
+`
+
+// TestContextualKeywords check contextual keywords are legal names out of the keyword positions
+func TestContextualKeywords(t *testing.T) {
+
+	compiler, errs := compileModule(t, "keywords.gs", keywordScript)
+
+	if len(errs) != 0 {
+		t.Fatalf("unexpect errors %v", errs)
+	}
+
+	module := compiler.Module()
+
+	if _, ok := module.Types["keywords.Value"].(*ast.Union); !ok {
+		t.Fatalf("expect union Value, got %v", module.Types["keywords.Value"])
+	}
+
+	field, ok := module.Types["keywords.Holder"].(*ast.Table).Field("union")
+
+	if !ok || field.Type.(*ast.TypeRef).Ref != module.Types["keywords.union"] {
+		t.Fatalf("expect field union with type union, got %v", field)
+	}
+
+	method, _ := module.Types["keywords.Service"].(*ast.Contract).Method("Set")
+
+	if len(method.Params) != 2 || method.Params[0].Name() != "union" || method.Params[1].Name() != "stream" {
+		t.Fatalf("expect params union and stream, got %v", method.Params)
+	}
+
+	streamType := module.Types["keywords.stream"]
+
+	get, _ := module.Types["keywords.Service"].(*ast.Contract).Method("Get")
+
+	if get.Stream != 0 || get.Return.(*ast.TypeRef).Ref != streamType || get.Params[0].Stream || get.Params[0].Name() != "stream" {
+		t.Fatalf("expect method Get without stream modifiers")
+	}
+
+	watch, _ := module.Types["keywords.Service"].(*ast.Contract).Method("Watch")
+
+	if watch.Stream != ast.StreamBidi || watch.Return.(*ast.TypeRef).Ref != streamType || !watch.Params[0].Stream {
+		t.Fatalf("expect bidi streaming method Watch, got %s", watch.Stream)
+	}
+
+	if watch.Params[0].Name() != "filters" || watch.Params[0].Type.(*ast.TypeRef).Ref != streamType {
+		t.Fatalf("expect stream param filters of type stream, got %v", watch.Params[0])
+	}
+}
+
+var genericScript = `package generic;
+
+table User {
+    string Name;
+}
+
+table Page<T> {
+    T[] Items;
+    string Cursor;
+}
+
+table Pair<K, V> {
+    K Key;
+    V Value;
+}
+
+table Result<T> {
+    Page<T> Page;
+    T? Last;
+}
+
+contract Service {
+    Result<User> List();
+    Pair<string, User> Get(Page page);
+    void Set(User<int32> user);
+}
+`
+
+func TestGeneric(t *testing.T) {
+
+	compiler, errs := compileModule(t, "generic.gs", genericScript)
+
+	expect := []string{
+		"generic table(Page) expect 1 type args but got 0",
+		"type(User) is not generic table",
+	}
+
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors, got %v", len(expect), errs)
+	}
+
+	for i, err := range errs {
+		if !strings.Contains(err.Text, expect[i]) {
+			t.Fatalf("expect error %s, got %s", expect[i], err)
+		}
+	}
+
+	user := compiler.Module().Types["generic.User"]
+
+	service := compiler.Module().Types["generic.Service"].(*ast.Contract)
+
+	list, _ := service.Method("List")
+
+	result, ok := gslang.Instantiate(list.Return.(*ast.TypeRef))
+
+	if !ok || result.FullName() != "generic.Result<generic.User>" || result.Generic != compiler.Module().Types["generic.Result"] {
+		t.Fatalf("expect instance generic.Result<generic.User>, got %v", result)
+	}
+
+	if instance, _ := gslang.Instantiate(list.Return.(*ast.TypeRef)); instance != result {
+		t.Fatal("expect cached generic table instance")
+	}
+
+	last, _ := result.Field("Last")
+
+	if optional, ok := last.Type.(*ast.Optional); !ok || optional.Component.(*ast.TypeRef).Ref != user {
+		t.Fatalf("expect type param bound to User, got %s", last.Type)
+	}
+
+	field, _ := result.Field("Page")
+
+	page, ok := gslang.Instantiate(field.Type.(*ast.TypeRef))
+
+	if !ok {
+		t.Fatalf("expect nested generic table instance, got %s", field.Type)
+	}
+
+	items, _ := page.Field("Items")
+
+	if items.Type.(*ast.Seq).Component.(*ast.TypeRef).Ref != user {
+		t.Fatalf("expect Page<User> items of User, got %s", items.Type)
+	}
+
+	data, err := descriptor.Encode(compiler.Module())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := descriptor.Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := module.Types["generic.Page"].(*ast.Table)
+
+	items, _ = decoded.Field("Items")
+
+	if param, ok := items.Type.(*ast.Seq).Component.(*ast.TypeRef).Ref.(*ast.TypeParam); !ok || param != decoded.TypeParams[0] {
+		t.Fatalf("expect decoded type param reference, got %s", items.Type)
+	}
+}
+
+var resultsScript = `package results;
+
+using gslang.Async;
+using gslang.Deprecated;
+using gslang.MethodID;
+
+table Status {
+    int32 Code;
+}
+
+@Deprecated (Reason:"spaced args")
+table Old {}
+
+contract Service {
+    @Async
+    (int32 code) Notify();
+    @MethodID (5) (int32 code) Spaced(@Deprecated (Reason:"spaced param args") string id);
+    (int32 code, @Deprecated string msg, Status? status) Get(string id);
+    (void nothing) Bad(string nothing);
+}
+`
+
+func TestResults(t *testing.T) {
+
+	compiler, errs := compileModule(t, "results.gs", resultsScript)
+
+	expect := []string{
+		"gslang.Async can't mark those method which has results",
+		"method(Bad) result(nothing) can't be void",
+		"method(Bad) result(nothing) conflicts with param",
+	}
+
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors, got %v", len(expect), errs)
+	}
+
+	for i, err := range errs {
+		if !strings.Contains(err.Text, expect[i]) {
+			t.Fatalf("expect error %s, got %s", expect[i], err)
+		}
+	}
+
+	service := compiler.Module().Types["results.Service"].(*ast.Contract)
+
+	get, _ := service.Method("Get")
+
+	if !gslang.IsVoid(get.Return) || len(get.Results) != 3 || len(get.Params) != 1 {
+		t.Fatalf("expect method Get with 3 results and void return, got %d results", len(get.Results))
+	}
+
+	// the annotation args table is separated by whitespace
+	spaced, _ := service.Method("Spaced")
+
+	if gslang.MethodID(spaced) != 5 || len(spaced.Results) != 1 || !gslang.IsDeprecated(spaced.Params[0]) {
+		t.Fatal("expect annotation args table followed by results list")
+	}
+
+	if !gslang.IsDeprecated(compiler.Module().Types["results.Old"]) {
+		t.Fatal("expect annotation args table after whitespace")
+	}
+
+	msg, ok := get.Result("msg")
+
+	if !ok || msg.ID != 1 || !gslang.IsDeprecated(msg) {
+		t.Fatal("expect annotated result msg with id 1")
+	}
+
+	status, _ := get.Result("status")
+
+	if optional, ok := status.Type.(*ast.Optional); !ok || optional.Component.(*ast.TypeRef).Ref != compiler.Module().Types["results.Status"] {
+		t.Fatalf("expect linked optional result type, got %s", status.Type)
+	}
+
+	data, err := descriptor.Encode(compiler.Module())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := descriptor.Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get, _ = module.Types["results.Service"].(*ast.Contract).Method("Get")
+
+	if len(get.Results) != 3 || get.Results[2].Name() != "status" {
+		t.Fatal("expect decoded method results")
+	}
+}
+
+var streamScript = `package streams;
+
+using gslang.Async;
+
+table Event {
+    int32 ID;
+}
+
+contract Service {
+    stream Event Watch(string topic);
+    Event Upload(string name, stream Event events);
+    stream (int32 code, Event event) Chat(stream Event events);
+    @Async
+    void Fire(stream Event events);
+    stream void Bad(stream Event events, string name);
+}
+`
+
+func TestStream(t *testing.T) {
+
+	compiler, errs := compileModule(t, "stream.gs", streamScript)
+
+	expect := []string{
+		"gslang.Async can't mark those streaming method",
+		"method(Bad) stream return can't be void",
+		"method(Bad) stream param(events) must be the last param",
+	}
+
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors, got %v", len(expect), errs)
+	}
+
+	for i, err := range errs {
+		if !strings.Contains(err.Text, expect[i]) {
+			t.Fatalf("expect error %s, got %s", expect[i], err)
+		}
+	}
+
+	service := compiler.Module().Types["streams.Service"].(*ast.Contract)
+
+	for name, mode := range map[string]ast.StreamMode{
+		"Watch":  ast.StreamServer,
+		"Upload": ast.StreamClient,
+		"Chat":   ast.StreamBidi,
+	} {
+		method, _ := service.Method(name)
+
+		if method.Stream != mode {
+			t.Fatalf("expect method(%s) stream mode %s, got %s", name, mode, method.Stream)
+		}
+	}
+
+	upload, _ := service.Method("Upload")
+
+	if upload.Params[0].Stream || !upload.Params[1].Stream {
+		t.Fatal("expect stream param events")
+	}
+
+	data, err := descriptor.Encode(compiler.Module())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := descriptor.Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chat, _ := module.Types["streams.Service"].(*ast.Contract).Method("Chat")
+
+	if chat.Stream != ast.StreamBidi || !chat.Params[0].Stream {
+		t.Fatal("expect decoded bidi streaming method")
+	}
+}
+
+var boundScript = `package bounds;
+
+table User {
+    string<=64 Name;
+    string<=32[<=10] Tags;
+    byte[16] ID;
+    string<=128? Bio;
+    int32<=5 Age;
+    byte[0] Empty;
+}
+`
+
+func TestBound(t *testing.T) {
+
+	compiler, errs := compileModule(t, "bound.gs", boundScript)
+
+	expect := []string{
+		"bound can't be applied to builtin type(int32)",
+		"seq(byte) size(0) out of range",
+	}
+
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors, got %v", len(expect), errs)
+	}
+
+	for i, err := range errs {
+		if !strings.Contains(err.Text, expect[i]) {
+			t.Fatalf("expect error %s, got %s", expect[i], err)
+		}
+	}
+
+	user := compiler.Module().Types["bounds.User"].(*ast.Table)
+
+	for name, max := range map[string]int{"Name": 64, "Tags": 10, "ID": 16, "Bio": 128} {
+
+		field, _ := user.Field(name)
+
+		if length, ok := gslang.MaxLength(field.Type); !ok || length != max {
+			t.Fatalf("expect field(%s) max length %d, got %d", name, max, length)
+		}
+	}
+
+	tags, _ := user.Field("Tags")
+
+	if tags.Type.FullName() != "string<=32[<=10]" {
+		t.Fatalf("expect bounded seq name, got %s", tags.Type.FullName())
+	}
+
+	data, err := descriptor.Encode(compiler.Module())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := descriptor.Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, _ = module.Types["bounds.User"].(*ast.Table).Field("Tags")
+
+	if tags.Type.FullName() != "string<=32[<=10]" {
+		t.Fatalf("expect decoded bounded seq, got %s", tags.Type.FullName())
+	}
+}
+
+var enumScript = `package enums;
+
+using gslang.Flag;
+
+enum Code : uint16 {
+    OK(0), NotFound(404), Teapot(418)
+}
+
+enum Small {
+    A(1), B(300), C(1)
+}
+
+@Flag
+enum Mode {
+    None(0), Read(1), Write(2), ReadWrite(3), Bad(12)
+}
+
+@Flag
+enum Signed : int32 {
+    X(1)
+}
+
+enum Wrong : string {
+    Y
+}
+`
+
+func TestEnumUnderlying(t *testing.T) {
+
+	compiler, errs := compileModule(t, "enums.gs", enumScript)
+
+	for _, expect := range []string{
+		"enum(Small) constant(B) value(300) out of underlying type(byte) range [0,255]",
+		"enum(Small) constant(C) duplicate value(1) with A",
+		"flag enum(Mode) constant(Bad) value(12) must be power of two or combination of the other flags",
+		"flag enum(Signed) underlying type(int32) must be unsigned",
+		"enum(Wrong) underlying type(string) must be integer builtin type",
+	} {
+		if !containsError(errs, expect) {
+			t.Fatalf("expect error %s, got %v", expect, errs)
+		}
+	}
+
+	if len(errs) != 5 {
+		t.Fatalf("expect 5 errors, got %v", errs)
+	}
+
+	code := compiler.Module().Types["enums.Code"]
+
+	if gslang.EnumType(code) != lexer.KeyUInt16 || gslang.EnumSize(code) != 2 {
+		t.Fatalf("expect uint16 enum, got %s", gslang.EnumType(code))
+	}
+
+	if mode := compiler.Module().Types["enums.Mode"]; gslang.EnumType(mode) != lexer.KeyUInt32 || gslang.EnumSize(mode) != 4 {
+		t.Fatal("expect flag enum default underlying type uint32")
+	}
+
+	data, err := descriptor.Encode(compiler.Module())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := descriptor.Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gslang.EnumType(module.Types["enums.Code"]) != lexer.KeyUInt16 {
+		t.Fatal("expect decoded enum underlying type")
+	}
+}
+
+var enumExprScript = `package exprs;
+
+using gslang.Flag;
+using gslang.annotations.Usage;
+using gslang.annotations.Target;
+
+@Usage(Target.EnumConstant)
+table Alias {
+    int64 Value;
+}
+
+@Flag
+enum Mode {
+    Read(1), Write(2), ReadWrite(Read | Write), All(Mode.ReadWrite | Exec), Exec(4)
+}
+
+enum Status : int16 {
+    Unknown(-1), OK, Failed(Code.Max)
+}
+
+enum Code : int16 {
+    Min(-5), Max(Mode.Exec)
+}
+
+enum Cycle {
+    A(B), B(A), Zero(0)
+}
+
+enum Bad {
+    X(Nope)
+}
+
+enum Scoped {
+    A(1), @Alias(A) B(2)
+}
+`
+
+func TestEnumExpr(t *testing.T) {
+
+	compiler, errs := compileModule(t, "exprs.gs", enumExprScript)
+
+	for _, expect := range []string{
+		// the failed constants aren't checked as duplicate values
+		"value circular reference",
+		"unknown constant val (Nope)",
+		// the bare names resolve to the same enum constants only in the constant value exprs
+		"unknown constant val (A)",
+	} {
+		if !containsError(errs, expect) {
+			t.Fatalf("expect error %s, got %v", expect, errs)
+		}
+	}
+
+	if len(errs) != 3 {
+		t.Fatalf("expect 3 errors, got %v", errs)
+	}
+
+	for name, values := range map[string]map[string]int64{
+		"exprs.Mode":   {"Read": 1, "Write": 2, "ReadWrite": 3, "All": 7, "Exec": 4},
+		"exprs.Status": {"Unknown": -1, "OK": 0, "Failed": 4},
+		"exprs.Code":   {"Min": -5, "Max": 4},
+	} {
+
+		enum := compiler.Module().Types[name].(*ast.Enum)
+
+		for constantName, value := range values {
+			if constant, _ := enum.Constant(constantName); constant.Value != value {
+				t.Fatalf("expect %s.%s value %d, got %d", name, constantName, value, constant.Value)
+			}
+		}
+	}
+}
+
+var enumWideScript = `package wide;
+
+using gslang.Flag;
+
+enum Big : int64 {
+    Min(-9223372036854775807), Zero(0), Odd(9007199254740993), Max(9223372036854775807)
+}
+
+@Flag
+enum Bits : uint64 {
+    Low(1), High(4611686018427387904), Both(Low | High)
+}
+
+enum Word : uint32 {
+    Top(4294967295), Over(4294967296)
+}
+
+enum Unsigned : uint64 {
+    Negative(-1)
+}
+`
+
+func TestEnumWideRange(t *testing.T) {
+
+	compiler, errs := compileModule(t, "wide.gs", enumWideScript)
+
+	for _, expect := range []string{
+		"enum(Word) constant(Over) value(4294967296) out of underlying type(uint32) range [0,4294967295]",
+		"enum(Unsigned) constant(Negative) value(-1) out of underlying type(uint64) range [0,9223372036854775807]",
+	} {
+		if !containsError(errs, expect) {
+			t.Fatalf("expect error %s, got %v", expect, errs)
+		}
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("expect 2 errors, got %v", errs)
+	}
+
+	data, err := descriptor.Encode(compiler.Module())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, err := descriptor.Decode(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, values := range map[string]map[string]int64{
+		"wide.Big":  {"Min": -math.MaxInt64, "Zero": 0, "Odd": 1<<53 + 1, "Max": math.MaxInt64},
+		"wide.Bits": {"Low": 1, "High": 1 << 62, "Both": 1<<62 | 1},
+		"wide.Word": {"Top": 1<<32 - 1},
+	} {
+		for _, types := range []map[string]ast.Type{compiler.Module().Types, module.Types} {
+
+			enum := types[name].(*ast.Enum)
+
+			for constantName, value := range values {
+				if constant, _ := enum.Constant(constantName); constant.Value != value {
+					t.Fatalf("expect %s.%s value %d, got %d", name, constantName, value, constant.Value)
+				}
+			}
+		}
+	}
+}
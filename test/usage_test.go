@@ -0,0 +1,253 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsrpc/gslang"
+)
+
+var usageScript = `package usage;
+
+using gslang.Async;
+using gslang.Exception;
+using gslang.annotations.Usage;
+using gslang.annotations.Target;
+
+@Usage(Target.Field|Target.Param)
+table Rename {
+    string Name;
+}
+
+@Usage(Target.Exception)
+table Retry {}
+
+@Exception
+table Failure {}
+
+@Async
+table Wrong {
+    @Rename("x") int32 X;
+    @Async int32 Y;
+}
+
+contract Service {
+    void Call(@Rename("p") int32 P) throws (@Retry Failure);
+    void Post(@Retry int32 P);
+}
+`
+
+// compileErrors compile and link source with the bundled prelude scripts, returns the semantic errors
+func compileErrors(t *testing.T, name string, source string) []*gslang.Error {
+
+	_, errs := compileModule(t, name, source)
+
+	return errs
+}
+
+// compileModule compile and link source with the bundled prelude scripts, returns the compiler and the semantic errors
+func compileModule(t *testing.T, name string, source string) (compiler *gslang.Compiler, errs []*gslang.Error) {
+
+	compiler = gslang.NewCompiler("test", gslang.HandleError(func(err *gslang.Error) {
+		if err.Stage != gslang.StageSemParing {
+			gserrors.Panicf(err.Orignal, "parse %s error\n\t%s", err.Start, err.Text)
+		}
+
+		errs = append(errs, err)
+	}))
+
+	for _, file := range []string{"../gslang.gs", "../annotations.gs"} {
+		if err := compiler.Compile(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := compiler.CompileSource(name, []byte(source)); err != nil {
+		t.Fatal(err)
+	}
+
+	compiler.Link()
+
+	return
+}
+
+func TestUsageTarget(t *testing.T) {
+
+	errs := compileErrors(t, "usage.gs", usageScript)
+
+	expect := []struct {
+		line    int
+		allowed string
+	}{
+		{19, "allowed targets : Method"},
+		{22, "allowed targets : Method"},
+		{27, "allowed targets : Exception"},
+	}
+
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors, got %v", len(expect), errs)
+	}
+
+	for _, err := range errs {
+
+		found := false
+
+		for _, e := range expect {
+			if err.Start.Lines == e.line && strings.HasSuffix(err.Text, e.allowed) {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Fatalf("unexpect error %s", err)
+		}
+	}
+}
+
+var rulesScript = `package rules;
+
+using gslang.annotations.Usage;
+using gslang.annotations.Target;
+using gslang.annotations.Repeatable;
+using gslang.annotations.Required;
+
+@Usage(Target.Method)
+table Timeout {
+    @Required int32 Value;
+    string Unit;
+}
+
+@Usage(Target.Method)
+@Repeatable
+table Tag {
+    string Name;
+}
+
+contract Service {
+    @Timeout(Value:1)
+    @Timeout(Value:2)
+    void Call();
+    @Tag("a")
+    @Tag("b")
+    @Timeout(Unit:"s")
+    void Post();
+    @Timeout(10, "s")
+    void Get();
+}
+`
+
+func TestAnnotationRules(t *testing.T) {
+
+	errs := compileErrors(t, "rules.gs", rulesScript)
+
+	if len(errs) != 2 {
+		t.Fatalf("expect 2 errors, got %v", errs)
+	}
+
+	if errs[0].Start.Lines != 22 || !strings.Contains(errs[0].Text, "isn't repeatable") {
+		t.Fatalf("unexpect error %s", errs[0])
+	}
+
+	if errs[1].Start.Lines != 26 || !strings.Contains(errs[1].Text, "required field(Value)") {
+		t.Fatalf("unexpect error %s", errs[1])
+	}
+}
+
+var constantScript = `package constants;
+
+using gslang.annotations.Usage;
+@Alias("target")
+using gslang.annotations.Target;
+
+@Usage(Target.EnumConstant|Target.Using)
+table Alias {
+    string Name;
+}
+
+enum TimeUnit {
+    @Alias("SEC")
+    Second,
+    // minute
+    @Usage(Target.Table)
+    Minute
+}
+`
+
+func TestEnumConstantAnnotation(t *testing.T) {
+
+	errs := compileErrors(t, "constants.gs", constantScript)
+
+	if len(errs) != 1 || errs[0].Start.Lines != 16 || !strings.HasSuffix(errs[0].Text, "allowed targets : Table") {
+		t.Fatalf("unexpect errors %v", errs)
+	}
+}
+
+var deprecatedScript = `package deprecated;
+
+using gslang.Deprecated;
+using gslang.annotations.Usage;
+using gslang.annotations.Target;
+
+@Deprecated(Reason:"use Duration", Since:"1.2")
+table Timeout {
+    int32 Value;
+}
+
+enum Unit {
+    Second,
+    @Deprecated
+    Minute
+}
+
+@Usage(Target.Method)
+table Options {
+    @Deprecated
+    Unit Unit;
+}
+
+table Request {
+    Timeout Timeout;
+    Options Options;
+}
+
+@Deprecated
+table Legacy {
+    Timeout Timeout;
+}
+
+contract Service {
+    @Options(Unit:Unit.Minute)
+    void Call(Timeout[] timeouts);
+}
+`
+
+func TestDeprecated(t *testing.T) {
+
+	errs := compileErrors(t, "deprecated.gs", deprecatedScript)
+
+	expect := map[int]string{
+		25: "type(Timeout) is deprecated since 1.2 : use Duration",
+		35: "field(Unit) is deprecated",
+		36: "type(Timeout) is deprecated since 1.2 : use Duration",
+	}
+
+	if len(errs) != len(expect)+1 {
+		t.Fatalf("expect %d warnings, got %v", len(expect)+1, errs)
+	}
+
+	for _, err := range errs {
+
+		if !err.Warning {
+			t.Fatalf("expect warning, got %s", err)
+		}
+
+		if err.Start.Lines == 35 && strings.Contains(err.Text, "enum constant(Minute)") {
+			continue
+		}
+
+		if text, ok := expect[err.Start.Lines]; !ok || err.Text != text {
+			t.Fatalf("unexpect warning %s", err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+)
+
+// depthVisitor check Visit(nil) is called once for each visited node
+type depthVisitor struct {
+	depth *int
+}
+
+func (visitor depthVisitor) Visit(node ast.Node) ast.Visitor {
+
+	if node == nil {
+		*visitor.depth--
+		return nil
+	}
+
+	*visitor.depth++
+
+	return visitor
+}
+
+func TestWalk(t *testing.T) {
+
+	compiler := compile(t, "test.gs")
+
+	kinds := make(map[string]int)
+
+	ast.Inspect(compiler.Module(), func(node ast.Node) bool {
+
+		if node != nil {
+			kinds[reflect.TypeOf(node).Elem().Name()]++
+		}
+
+		return true
+	})
+
+	for _, kind := range []string{
+		"Module", "Script", "Using", "Table", "Field", "Enum", "EnumConstant", "Contract", "Method",
+		"Param", "Exception", "Seq", "TypeRef", "BuiltinType", "Annotation", "ArgsTable", "NewObj",
+		"BinaryOp", "ConstantRef", "Numeric",
+	} {
+		if kinds[kind] == 0 {
+			t.Fatalf("node kind %s not visited", kind)
+		}
+	}
+
+	depth := 0
+
+	ast.Walk(compiler.Module(), depthVisitor{&depth})
+
+	if depth != 0 {
+		t.Fatalf("unbalanced Visit(nil) calls %d", depth)
+	}
+
+	ast.Apply(compiler.Module(), func(cursor *ast.Cursor) bool {
+
+		if cursor.Parent() != nil && cursor.Node().Parent() != cursor.Parent() {
+			t.Fatalf("parent of %s not set", cursor.Node())
+		}
+
+		return true
+	}, nil)
+}
+
+func TestApply(t *testing.T) {
+
+	compiler := compile(t, "test.gs")
+
+	module := compiler.Module()
+
+	ast.Apply(module, func(cursor *ast.Cursor) bool {
+
+		switch node := cursor.Node().(type) {
+		case *ast.Field:
+			if node.Name() == "LongText" {
+				cursor.Delete()
+			}
+		case *ast.Annotation:
+			if node.Type.Name() == "Async" {
+				cursor.Delete()
+			}
+		case *ast.Numeric:
+			cursor.Replace(ast.NewNumeric(100))
+		}
+
+		return true
+	}, nil)
+
+	description := module.Types["gslang.test.Description"].(*ast.Table)
+
+	if len(description.Fields) != 1 || description.Fields[0].Name() != "Text" {
+		t.Fatalf("field LongText not deleted %v", description.Fields)
+	}
+
+	post, _ := module.Types["gslang.test.HttpREST"].(*ast.Contract).Method("Post")
+
+	anns := gslang.FindAnnotations(post, "gslang.test.Async")
+
+	if len(anns) != 0 {
+		t.Fatalf("annotation Async not deleted")
+	}
+
+	timeout := gslang.FindAnnotations(post, "gslang.test.Timeout")
+
+	if len(timeout) != 1 {
+		t.Fatalf("annotation Timeout not found")
+	}
+
+	duration := timeout[0].Args.Arg(0).(*ast.NewObj)
+
+	if numeric, ok := duration.Args.Arg(0).(*ast.Numeric); !ok || numeric.Val != 100 {
+		t.Fatalf("numeric not replaced %v", duration.Args.Arg(0))
+	}
+
+	// post returns false to stop the traversal
+	count := 0
+
+	ast.Apply(module, nil, func(cursor *ast.Cursor) bool {
+		count++
+		return count < 3
+	})
+
+	if count != 3 {
+		t.Fatalf("apply not stopped, %d nodes visited", count)
+	}
+}
+
+func TestApplyReplaceSlot(t *testing.T) {
+
+	compiler := compile(t, "test.gs")
+
+	module := compiler.Module()
+
+	post, _ := module.Types["gslang.test.HttpREST"].(*ast.Contract).Method("Post")
+
+	timeout := gslang.FindAnnotations(post, "gslang.test.Timeout")[0]
+
+	replacePanic := func(node ast.Node, name string, target ast.Node) (msg interface{}) {
+
+		defer func() {
+			msg = recover()
+		}()
+
+		ast.Apply(node, func(cursor *ast.Cursor) bool {
+			if cursor.Name() == name {
+				cursor.Replace(target)
+			}
+
+			return true
+		}, nil)
+
+		return nil
+	}
+
+	if msg := replacePanic(timeout, "Args", ast.NewNumeric(1)); msg == nil || !strings.Contains(fmt.Sprint(msg), "expect *ast.ArgsTable") {
+		t.Fatalf("expect args type mismatch panic, got %v", msg)
+	}
+
+	if msg := replacePanic(post, "Return", nil); msg == nil || !strings.Contains(fmt.Sprint(msg), "required field Return") {
+		t.Fatalf("expect required field panic, got %v", msg)
+	}
+
+	if msg := replacePanic(timeout, "Args", nil); msg != nil {
+		t.Fatalf("unexpect panic %v", msg)
+	}
+
+	if timeout.Args != nil {
+		t.Fatal("expect annotation args deleted")
+	}
+}
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/watch"
+)
+
+// fakeClock hand over each wait to the test
+type fakeClock struct {
+	waits chan chan time.Time
+}
+
+func (clock *fakeClock) After(d time.Duration) <-chan time.Time {
+	tick := make(chan time.Time, 1)
+	clock.waits <- tick
+	return tick
+}
+
+func TestWatch(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "gslang-watch")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	write := func(name string, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a.gs", "package watch;\ntable A { int32 X; }\n")
+	write("b.gs", "package watch;\nenum B { First, Second }\n")
+
+	clock := &fakeClock{waits: make(chan chan time.Time)}
+
+	var output bytes.Buffer
+
+	watcher := watch.New("watch", watch.NewDirPoller(dir), clock, time.Second, &output)
+
+	var runs []string
+
+	generator := func(name string, filter func(ast.Type) bool) *watch.Generator {
+		return &watch.Generator{
+			Name:   name,
+			Filter: filter,
+			Run: func(compiler *gslang.Compiler) error {
+				runs = append(runs, name)
+				return nil
+			},
+		}
+	}
+
+	watcher.Add(generator("tables", func(typeDecl ast.Type) bool {
+		_, ok := typeDecl.(*ast.Table)
+		return ok
+	}))
+
+	watcher.Add(generator("all", nil))
+
+	stop := make(chan struct{})
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- watcher.Run(stop)
+	}()
+
+	// step run one poll loop, then check the rerun generators
+	step := func(expect ...string) {
+
+		tick := <-clock.waits
+
+		if !reflect.DeepEqual(runs, expect) {
+			t.Fatalf("expect generators %v, got %v\n%s", expect, runs, output.String())
+		}
+
+		runs = nil
+
+		output.Reset()
+
+		tick <- time.Now()
+	}
+
+	step("tables", "all")
+
+	// comments and whitespaces don't change the types
+	write("a.gs", "package watch;\n\n// table A\ntable A {\n    int32 X;\n}\n")
+
+	step()
+
+	write("b.gs", "package watch;\nenum B { First, Second, Third }\n")
+
+	step("all")
+
+	write("a.gs", "package watch;\ntable A { int32 X; int32 Y; }\n")
+
+	step("tables", "all")
+
+	write("a.gs", "package watch;\ntable A { int32 X; int32 }\n")
+
+	tick := <-clock.waits
+
+	if runs != nil || !strings.Contains(output.String(), "1 errors") {
+		t.Fatalf("expect syntax error without generating, got %v\n%s", runs, output.String())
+	}
+
+	output.Reset()
+
+	tick <- time.Now()
+
+	// removed table reruns the table generator
+	if err := os.Remove(filepath.Join(dir, "a.gs")); err != nil {
+		t.Fatal(err)
+	}
+
+	step("tables", "all")
+
+	<-clock.waits
+
+	close(stop)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
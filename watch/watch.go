@@ -0,0 +1,338 @@
+// Package watch recompile gslang scripts on file changes and rerun the affected generators
+package watch
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/descriptor"
+)
+
+// Stamp file change stamp
+type Stamp struct {
+	ModTime time.Time // last modify time
+	Size    int64     // file size
+}
+
+// Poller poll script files' stamps
+type Poller interface {
+	Poll() (map[string]Stamp, error)
+}
+
+// Clock watch loop clock
+type Clock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+// Generator code generator rerun when its input types changed
+type Generator struct {
+	Name   string                                // generator name
+	Filter func(typeDecl ast.Type) bool          // input types filter, nil accepts all types
+	Run    func(compiler *gslang.Compiler) error // generate codes, e.g. call compiler.Visit
+}
+
+type _DirPoller struct {
+	roots []string // watched files or directories
+}
+
+// NewDirPoller create poller walking .gs scripts of files or directories
+func NewDirPoller(roots ...string) Poller {
+	return &_DirPoller{roots: roots}
+}
+
+func (poller *_DirPoller) Poll() (map[string]Stamp, error) {
+
+	stamps := make(map[string]Stamp)
+
+	for _, root := range poller.roots {
+
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() && filepath.Ext(path) == ".gs" {
+				stamps[path] = Stamp{ModTime: info.ModTime(), Size: info.Size()}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stamps, nil
+}
+
+type _SystemClock struct{}
+
+func (clock _SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SystemClock the real time clock
+var SystemClock Clock = _SystemClock{}
+
+// Watcher recompile changed scripts with the incremental compiler API
+type Watcher struct {
+	gslogger.Log                     // Mixin log
+	compiler     *gslang.Compiler    // incremental compiler
+	poller       Poller              // script poller
+	clock        Clock               // loop clock
+	interval     time.Duration       // poll interval
+	output       io.Writer           // diagnostics output
+	generators   []*Generator        // registered generators
+	stamps       map[string]Stamp    // last polled stamps
+	hashes       map[string]string   // last compiled types' hash indexed by full name
+	types        map[string]ast.Type // last compiled types, used to filter removed types
+	linked       bool                // first build flag
+}
+
+// New create new watcher, diagnostics are printed to output
+func New(name string, poller Poller, clock Clock, interval time.Duration, output io.Writer) *Watcher {
+
+	return &Watcher{
+		Log: gslogger.Get("watch"),
+		compiler: gslang.NewCompiler(name, gslang.HandleError(func(err *gslang.Error) {
+			// the parser can't recover from syntax errors, semantic errors are collected as diagnostics
+			if err.Stage != gslang.StageSemParing {
+				gserrors.Panicf(err.Orignal, "%s: %s", err.Start, err.Text)
+			}
+		})),
+		poller:   poller,
+		clock:    clock,
+		interval: interval,
+		output:   output,
+		stamps:   make(map[string]Stamp),
+		hashes:   make(map[string]string),
+		types:    make(map[string]ast.Type),
+	}
+}
+
+// Compiler get the watched compiler
+func (watcher *Watcher) Compiler() *gslang.Compiler {
+	return watcher.compiler
+}
+
+// Add register generator
+func (watcher *Watcher) Add(generator *Generator) {
+	watcher.generators = append(watcher.generators, generator)
+}
+
+// Run poll and rebuild until stop closed
+func (watcher *Watcher) Run(stop <-chan struct{}) error {
+
+	for {
+		if _, err := watcher.Step(); err != nil {
+			return err
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-watcher.clock.After(watcher.interval):
+		}
+	}
+}
+
+// Step poll once, recompile the changed scripts, print diagnostics and rerun the generators
+// whose input types changed. Returns the names of rerun generators
+func (watcher *Watcher) Step() ([]string, error) {
+
+	stamps, err := watcher.poller.Poll()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var changed, removed []string
+
+	for path, stamp := range stamps {
+		if old, ok := watcher.stamps[path]; !ok || old != stamp {
+			changed = append(changed, path)
+		}
+	}
+
+	for path := range watcher.stamps {
+		if _, ok := stamps[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+
+	watcher.stamps = stamps
+
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(changed)
+
+	sort.Strings(removed)
+
+	for _, path := range removed {
+		watcher.I("removed %s", path)
+
+		if err := watcher.compiler.Remove(path); err != nil {
+			watcher.D("remove %s error :%s", path, err)
+		}
+	}
+
+	for _, path := range changed {
+		watcher.I("changed %s", path)
+
+		content, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			// removed after polled, picked up by the next poll
+			delete(watcher.stamps, path)
+			continue
+		}
+
+		if err := watcher.compiler.Update(path, content); err != nil {
+			watcher.D("update %s error :%s", path, err)
+		}
+	}
+
+	if !watcher.linked {
+
+		watcher.linked = true
+
+		if err := watcher.compiler.Link(); err != nil {
+			watcher.D("link error :%s", err)
+		}
+	}
+
+	if watcher.diagnostics(stamps) {
+		// keep the last generated codes until the scripts are fixed
+		return nil, nil
+	}
+
+	return watcher.generate(), nil
+}
+
+// diagnostics print all scripts' diagnostics, returns true if there are errors, warnings don't stop generating
+func (watcher *Watcher) diagnostics(stamps map[string]Stamp) bool {
+
+	var paths []string
+
+	for path := range stamps {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	count := 0
+
+	for _, path := range paths {
+		for _, err := range watcher.compiler.Diagnostics(path) {
+
+			if err.Warning {
+				fmt.Fprintf(watcher.output, "%s: warning: %s\n", err.Start, err.Text)
+				continue
+			}
+
+			fmt.Fprintf(watcher.output, "%s: %s\n", err.Start, err.Text)
+			count++
+		}
+	}
+
+	if count == 0 {
+		fmt.Fprintf(watcher.output, "compile success\n")
+		return false
+	}
+
+	fmt.Fprintf(watcher.output, "%d errors\n", count)
+
+	return true
+}
+
+// generate rerun generators whose input types changed
+func (watcher *Watcher) generate() (names []string) {
+
+	hashes := make(map[string]string)
+
+	for _, script := range descriptor.New(watcher.compiler.Module()).Scripts {
+		for _, typeDesc := range script.Types {
+			hashes[typeDesc.FullName] = typeDesc.Hash
+		}
+	}
+
+	// changed includes added and removed types
+	changed := make(map[string]bool)
+
+	for name, hash := range hashes {
+		if watcher.hashes[name] != hash {
+			changed[name] = true
+		}
+	}
+
+	for name := range watcher.hashes {
+		if _, ok := hashes[name]; !ok {
+			changed[name] = true
+		}
+	}
+
+	watcher.hashes = hashes
+
+	types := watcher.types
+
+	watcher.types = make(map[string]ast.Type)
+
+	for name, typeDecl := range watcher.compiler.Module().Types {
+		watcher.types[name] = typeDecl
+	}
+
+	for _, generator := range watcher.generators {
+
+		if !watcher.accept(generator, changed, types) {
+			continue
+		}
+
+		names = append(names, generator.Name)
+
+		if err := generator.Run(watcher.compiler); err != nil {
+			fmt.Fprintf(watcher.output, "generator %s error :%s\n", generator.Name, err)
+			continue
+		}
+
+		fmt.Fprintf(watcher.output, "generator %s done\n", generator.Name)
+	}
+
+	return
+}
+
+// accept check if the changed types contain generator input types, removed types are checked with
+// the previous compiled types
+func (watcher *Watcher) accept(generator *Generator, changed map[string]bool, types map[string]ast.Type) bool {
+
+	if generator.Filter == nil {
+		return len(changed) != 0
+	}
+
+	for name := range changed {
+
+		typeDecl, ok := watcher.types[name]
+
+		if !ok {
+			typeDecl, ok = types[name]
+		}
+
+		if ok && generator.Filter(typeDecl) {
+			return true
+		}
+	}
+
+	return false
+}